@@ -13,16 +13,15 @@ package md
 
 import (
 	api "github.com/documize/community/core/convapi"
-
-	"github.com/documize/blackfriday"
+	"github.com/documize/community/core/mdutil"
 
 	"golang.org/x/net/context"
 )
 
 // Convert provides the standard interface for conversion of a Markdown document.
 // All the function does is return a pointer to api.DocumentConversionResponse with
-// PagesHTML set to the given (*api.DocumentConversionRequest).Filedata converted by the blackfriday lib.
+// PagesHTML set to the given (*api.DocumentConversionRequest).Filedata converted to sanitized HTML.
 func Convert(ctx context.Context, in interface{}) (interface{}, error) {
 	return &api.DocumentConversionResponse{
-		PagesHTML: blackfriday.MarkdownCommon(in.(*api.DocumentConversionRequest).Filedata)}, nil
+		PagesHTML: mdutil.Render(in.(*api.DocumentConversionRequest).Filedata)}, nil
 }
@@ -0,0 +1,20 @@
+/* community edition */
+DROP TABLE IF EXISTS `datasource`;
+
+CREATE TABLE IF NOT EXISTS `datasource` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`refid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`userid` CHAR(16) DEFAULT '' COLLATE utf8_bin,
+	`name` VARCHAR(200) NOT NULL,
+	`driver` VARCHAR(20) NOT NULL DEFAULT 'mysql',
+	`dsn` TEXT NOT NULL,
+	`maxrows` INT UNSIGNED NOT NULL DEFAULT 100,
+	`timeout` INT UNSIGNED NOT NULL DEFAULT 10,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	INDEX `idx_datasource_refid` (`refid` ASC),
+	INDEX `idx_datasource_orgid` (`orgid` ASC))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
@@ -0,0 +1,2 @@
+/* community edition */
+ALTER TABLE page ADD COLUMN `version` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `revisions`;
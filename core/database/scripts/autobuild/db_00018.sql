@@ -0,0 +1,16 @@
+/* community edition */
+DROP TABLE IF EXISTS `pagelock`;
+
+CREATE TABLE IF NOT EXISTS `pagelock` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`pageid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`userid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`expires` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_pagelock_pageid` (`orgid`, `pageid`),
+	INDEX `idx_pagelock_documentid` (`documentid` ASC))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
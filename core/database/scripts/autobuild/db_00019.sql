@@ -0,0 +1,19 @@
+/* community edition */
+DROP TABLE IF EXISTS `snapshot`;
+
+CREATE TABLE IF NOT EXISTS `snapshot` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`refid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`userid` CHAR(16) DEFAULT '' COLLATE utf8_bin,
+	`name` VARCHAR(200) NOT NULL,
+	`note` TEXT,
+	`data` LONGBLOB,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	INDEX `idx_snapshot_refid` (`refid` ASC),
+	INDEX `idx_snapshot_documentid` (`orgid`, `documentid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
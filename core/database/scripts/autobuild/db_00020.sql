@@ -0,0 +1,21 @@
+/* community edition */
+DROP TABLE IF EXISTS `trash`;
+
+CREATE TABLE IF NOT EXISTS `trash` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`refid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`labelid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`pageid` CHAR(16) DEFAULT '' COLLATE utf8_bin,
+	`userid` CHAR(16) DEFAULT '' COLLATE utf8_bin,
+	`title` VARCHAR(2000) NOT NULL,
+	`data` LONGBLOB,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	INDEX `idx_trash_refid` (`refid` ASC),
+	INDEX `idx_trash_labelid` (`orgid`, `labelid`),
+	INDEX `idx_trash_created` (`created`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
@@ -0,0 +1,3 @@
+/* community edition */
+ALTER TABLE document ADD COLUMN `lifecycle` INT NOT NULL DEFAULT 1 AFTER `layout`;
+ALTER TABLE document ADD INDEX `idx_document_lifecycle` (`orgid`, `lifecycle`);
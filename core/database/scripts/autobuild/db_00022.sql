@@ -0,0 +1,5 @@
+/* community edition */
+ALTER TABLE document ADD COLUMN `approvals` INT NOT NULL DEFAULT 0 AFTER `lifecycle`;
+ALTER TABLE useraction ADD COLUMN `outcome` INT NOT NULL DEFAULT 0 AFTER `iscomplete`;
+ALTER TABLE useraction ADD COLUMN `reply` NVARCHAR(2000) NOT NULL DEFAULT '' AFTER `outcome`;
+ALTER TABLE useraction ADD INDEX `idx_useraction_pending` (`orgid`, `userid`, `iscomplete`);
@@ -0,0 +1,4 @@
+/* community edition */
+ALTER TABLE feedback ADD COLUMN `pageid` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin AFTER `documentid`;
+ALTER TABLE feedback ADD COLUMN `helpful` BOOL NOT NULL DEFAULT 0 AFTER `email`;
+ALTER TABLE feedback ADD INDEX `idx_feedback_documentid` (`orgid`, `documentid`);
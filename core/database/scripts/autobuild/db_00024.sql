@@ -0,0 +1,42 @@
+/* community edition */
+DROP TABLE IF EXISTS `comment`;
+
+CREATE TABLE IF NOT EXISTS `comment` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`refid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`pageid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`threadid` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin,
+	`userid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`body` TEXT,
+	`mentions` TEXT,
+	`anchor` TEXT,
+	`anchorstart` INT NOT NULL DEFAULT -1,
+	`resolved` BOOL NOT NULL DEFAULT 0,
+	`resolvedby` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin,
+	`outdated` BOOL NOT NULL DEFAULT 0,
+	`edited` BOOL NOT NULL DEFAULT 0,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	INDEX `idx_comment_refid` (`refid` ASC),
+	INDEX `idx_comment_documentid` (`orgid`, `documentid`),
+	INDEX `idx_comment_pageid` (`pageid` ASC),
+	INDEX `idx_comment_threadid` (`threadid` ASC))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
+
+DROP TABLE IF EXISTS `commentrevision`;
+
+CREATE TABLE IF NOT EXISTS `commentrevision` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`commentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`userid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`body` TEXT,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	INDEX `idx_commentrevision_commentid` (`orgid`, `commentid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
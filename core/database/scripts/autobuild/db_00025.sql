@@ -0,0 +1,6 @@
+/* community edition */
+ALTER TABLE participant ADD COLUMN `labelid` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin AFTER `orgid`;
+ALTER TABLE participant ADD COLUMN `lastnotified` TIMESTAMP NULL AFTER `lastviewed`;
+ALTER TABLE participant ADD COLUMN `appurl` VARCHAR(250) NOT NULL DEFAULT '' AFTER `lastnotified`;
+ALTER TABLE participant ADD INDEX `idx_participant_userid` (`orgid`, `userid`);
+ALTER TABLE participant ADD INDEX `idx_participant_labelid` (`labelid` ASC);
@@ -0,0 +1,5 @@
+/* community edition */
+ALTER TABLE share ADD COLUMN `refid` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin AFTER `id`;
+ALTER TABLE share ADD COLUMN `views` INT NOT NULL DEFAULT 0 AFTER `viewed`;
+ALTER TABLE share ADD INDEX `idx_share_secret` (`secret` ASC);
+ALTER TABLE share ADD INDEX `idx_share_documentid` (`orgid`, `documentid`);
@@ -0,0 +1,29 @@
+/* community edition */
+DROP TABLE IF EXISTS `template`;
+
+CREATE TABLE IF NOT EXISTS `template` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`templateid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`variables` TEXT,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_template_templateid` (`orgid`, `templateid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
+
+DROP TABLE IF EXISTS `documenttemplate`;
+
+CREATE TABLE IF NOT EXISTS `documenttemplate` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`templateid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`variablevalues` TEXT,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_documenttemplate_documentid` (`orgid`, `documentid`),
+	INDEX `idx_documenttemplate_templateid` (`orgid`, `templateid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
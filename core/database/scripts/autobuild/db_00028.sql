@@ -0,0 +1,36 @@
+/* community edition */
+ALTER TABLE `template` ADD COLUMN `version` INT NOT NULL DEFAULT 1 AFTER `templateid`;
+
+ALTER TABLE `documenttemplate` ADD COLUMN `version` INT NOT NULL DEFAULT 1 AFTER `templateid`;
+ALTER TABLE `documenttemplate` ADD COLUMN `synced` TIMESTAMP NULL AFTER `variablevalues`;
+UPDATE `documenttemplate` SET `synced`=`created`;
+
+DROP TABLE IF EXISTS `templateversion`;
+
+CREATE TABLE IF NOT EXISTS `templateversion` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`templateid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`version` INT NOT NULL,
+	`userid` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin,
+	`note` VARCHAR(500) NOT NULL DEFAULT '',
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_templateversion_version` (`orgid`, `templateid`, `version`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
+
+DROP TABLE IF EXISTS `templatesection`;
+
+CREATE TABLE IF NOT EXISTS `templatesection` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`pageid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`templatepageid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_templatesection_pageid` (`orgid`, `pageid`),
+	INDEX `idx_templatesection_documentid` (`orgid`, `documentid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
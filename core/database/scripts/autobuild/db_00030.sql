@@ -0,0 +1,36 @@
+/* community edition */
+DROP TABLE IF EXISTS `spacefield`;
+
+CREATE TABLE IF NOT EXISTS `spacefield` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`refid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`labelid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`name` VARCHAR(50) NOT NULL,
+	`label` VARCHAR(100) NOT NULL DEFAULT '',
+	`type` VARCHAR(10) NOT NULL,
+	`required` BOOL NOT NULL DEFAULT 0,
+	`choices` TEXT,
+	`sequence` INT NOT NULL DEFAULT 0,
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	`revised` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_spacefield_refid` (`refid`),
+	UNIQUE INDEX `idx_spacefield_name` (`orgid`, `labelid`, `name`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
+
+DROP TABLE IF EXISTS `documentfield`;
+
+CREATE TABLE IF NOT EXISTS `documentfield` (
+	`id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+	`orgid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`documentid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`fieldid` CHAR(16) NOT NULL COLLATE utf8_bin,
+	`value` VARCHAR(2000) NOT NULL DEFAULT '',
+	`created` TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	CONSTRAINT pk_id PRIMARY KEY (id),
+	UNIQUE INDEX `idx_documentfield_field` (`orgid`, `documentid`, `fieldid`),
+	INDEX `idx_documentfield_fieldid` (`fieldid`))
+DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_bin
+ENGINE = InnoDB;
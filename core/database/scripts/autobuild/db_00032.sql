@@ -0,0 +1,3 @@
+/* community edition */
+ALTER TABLE page ADD COLUMN `revisedby` CHAR(16) NOT NULL DEFAULT '' COLLATE utf8_bin AFTER `userid`;
+UPDATE page SET revisedby=userid;
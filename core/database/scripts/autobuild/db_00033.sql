@@ -0,0 +1,2 @@
+/* community edition */
+ALTER TABLE `templateversion` ADD COLUMN `data` LONGBLOB AFTER `note`;
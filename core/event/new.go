@@ -25,6 +25,10 @@ const (
 	TypeRemoveUser Type = "USER_DELETE"
 	// TypeAddDocument for when document created
 	TypeAddDocument Type = "DOCUMENT_ADD"
+	// TypeAddComment for when a comment or reply is added
+	TypeAddComment Type = "COMMENT_ADD"
+	// TypeMentionUser for when a user is mentioned in a comment
+	TypeMentionUser Type = "COMMENT_MENTION"
 	// TypeSystemLicenseChange for when adin updates license
 	TypeSystemLicenseChange Type = "LICENSE_CHANGE"
 )
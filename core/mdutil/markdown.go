@@ -0,0 +1,161 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package mdutil converts GitHub Flavored Markdown into sanitized HTML.
+package mdutil
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// tocDirective is replaced with a table of contents built from document headings.
+var tocDirective = regexp.MustCompile(`(?i)<p>\[\[toc\]\]</p>`)
+
+var converter = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+		extension.Footnote),
+	goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+	goldmark.WithRendererOptions(html.WithUnsafe()), // sanitized below
+)
+
+var policy = newPolicy()
+
+// newPolicy extends the user generated content policy with what
+// headings, footnotes and task lists need.
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+	p.AllowAttrs("id").OnElements("h1", "h2", "h3", "h4", "h5", "h6", "li", "sup")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^(footnotes|footnote-ref|footnote-backref|documize-toc|language-[\w-]+)$`)).Globally()
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	p.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+
+	return p
+}
+
+// heading captures what the table of contents needs.
+type heading struct {
+	Level int
+	ID    string
+	Title string
+}
+
+// Render converts markdown into sanitized HTML. Headings receive IDs
+// derived from their text so links to them remain stable across edits
+// elsewhere in the document. A paragraph holding only [[toc]] becomes
+// a table of contents.
+func Render(source []byte) []byte {
+	doc := converter.Parser().Parse(text.NewReader(source))
+
+	var buffer bytes.Buffer
+	err := converter.Renderer().Render(&buffer, source, doc)
+	if err != nil {
+		return []byte{}
+	}
+
+	output := buffer.Bytes()
+	if tocDirective.Match(output) {
+		toc := tableOfContents(headings(doc, source))
+		output = tocDirective.ReplaceAllLiteral(output, toc)
+	}
+
+	return policy.SanitizeBytes(output)
+}
+
+// headings returns all headings in document order.
+func headings(doc ast.Node, source []byte) (h []heading) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		if node, ok := n.(*ast.Heading); ok {
+			id, _ := node.AttributeString("id")
+			idValue, _ := id.([]byte)
+			h = append(h, heading{Level: node.Level, ID: string(idValue), Title: string(node.Text(source))})
+			return ast.WalkSkipChildren, nil
+		}
+
+		return ast.WalkContinue, nil
+	})
+
+	return
+}
+
+// tableOfContents renders headings as nested lists, each sub-list inside
+// the item it belongs to. Skipped heading levels get an empty item.
+func tableOfContents(h []heading) []byte {
+	var b bytes.Buffer
+
+	if len(h) == 0 {
+		return b.Bytes()
+	}
+
+	b.WriteString(`<div class="documize-toc">`)
+
+	base := h[0].Level
+	for _, x := range h {
+		if x.Level < base {
+			base = x.Level
+		}
+	}
+
+	// every list down to depth has an open item
+	depth := 0
+	for _, x := range h {
+		level := x.Level - base + 1
+
+		for depth > level {
+			b.WriteString("</li></ul>")
+			depth--
+		}
+
+		if depth == level {
+			b.WriteString("</li>")
+		}
+
+		for depth < level {
+			b.WriteString("<ul>")
+			depth++
+			if depth < level {
+				b.WriteString("<li>")
+			}
+		}
+
+		fmt.Fprintf(&b, `<li><a href="#%s">%s</a>`, template.HTMLEscapeString(x.ID), template.HTMLEscapeString(x.Title))
+	}
+
+	for depth > 0 {
+		b.WriteString("</li></ul>")
+		depth--
+	}
+
+	b.WriteString("</div>")
+
+	return b.Bytes()
+}
@@ -0,0 +1,77 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mdutil
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	type testRender struct {
+		md       string
+		contains []string
+		excludes []string
+	}
+	tests := []testRender{
+		{
+			"# Hello World\n\n# Hello World",
+			[]string{`<h1 id="hello-world">`, `<h1 id="hello-world-1">`},
+			nil,
+		},
+		{
+			"[[toc]]\n\n# One\n\n## Two",
+			[]string{`<div class="documize-toc"><ul><li><a href="#one">One</a><ul><li><a href="#two">Two</a></li></ul></li></ul></div>`},
+			[]string{"[[toc]]"},
+		},
+		{
+			"[[toc]]\n\n## One\n\n### Two\n\n## Three\n\n# Four\n\n### Five",
+			[]string{`<div class="documize-toc"><ul><li><ul><li><a href="#one">One</a><ul><li><a href="#two">Two</a></li></ul></li><li><a href="#three">Three</a></li></ul></li><li><a href="#four">Four</a><ul><li><ul><li><a href="#five">Five</a></li></ul></li></ul></li></ul></div>`},
+			nil,
+		},
+		{
+			"- [x] done\n- [ ] todo",
+			[]string{`<input checked="" disabled="" type="checkbox">`},
+			nil,
+		},
+		{
+			"note[^1]\n\n[^1]: footnote",
+			[]string{`<sup id="fnref:1">`, `<li id="fn:1">`},
+			nil,
+		},
+		{
+			"| a | b |\n|:--|--:|\n| 1 | 2 |",
+			[]string{`<th align="left">a</th>`, `<td align="right">2</td>`},
+			nil,
+		},
+		{
+			"visit https://documize.com\n\n<script>alert(1)</script><a href=\"javascript:alert(1)\">x</a>",
+			[]string{`<a href="https://documize.com" rel="nofollow">`},
+			[]string{"<script", "javascript:"},
+		},
+	}
+
+	for _, tst := range tests {
+		got := string(Render([]byte(tst.md)))
+
+		for _, c := range tst.contains {
+			if !strings.Contains(got, c) {
+				t.Errorf("Render for `%s` expected to contain `%s` got `%s`", tst.md, c, got)
+			}
+		}
+		for _, e := range tst.excludes {
+			if strings.Contains(got, e) {
+				t.Errorf("Render for `%s` expected not to contain `%s` got `%s`", tst.md, e, got)
+			}
+		}
+	}
+}
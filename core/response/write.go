@@ -103,3 +103,19 @@ func WriteJSON(w http.ResponseWriter, v interface{}) {
 	j, _ := json.Marshal(v)
 	w.Write(j)
 }
+
+// WriteConflictError notifies HTTP client that the request was based on out-of-date data,
+// sending back v so the client can reconcile.
+func WriteConflictError(w http.ResponseWriter, method string, v interface{}) {
+	writeStatus(w, http.StatusConflict)
+	j, _ := json.Marshal(v)
+	w.Write(j)
+}
+
+// WriteLockedError notifies HTTP client that the resource is locked by someone else,
+// sending back v to describe the lock.
+func WriteLockedError(w http.ResponseWriter, method string, v interface{}) {
+	writeStatus(w, http.StatusLocked)
+	j, _ := json.Marshal(v)
+	w.Write(j)
+}
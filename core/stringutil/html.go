@@ -76,6 +76,13 @@ func writeText(n *html.Node, b io.Writer, isTest bool) {
 }
 
 func excluded(n *html.Node) bool {
+	if n.DataAtom == atom.Pre {
+		for _, a := range n.Attr {
+			if a.Key == "class" && a.Val == "documize-code-lines" {
+				return true
+			}
+		}
+	}
 	if n.DataAtom == atom.Div {
 		for _, a := range n.Attr {
 			if a.Key == "class" {
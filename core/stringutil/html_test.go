@@ -27,6 +27,10 @@ func TestHTML(t *testing.T) {
 			`<p>This is:</p><ul><li><documize type="field-start"></documize> <documize type="field-end"></documize><documize type="unknown"></documize><li><a href="/bar/baz">HTML text.</a></ul>`,
 			"This is: [ ] [ ] HTML text. ", true,
 		},
+		{
+			`<div class="documize-code"><table><tr><td><pre class="documize-code-lines"><span>1</span><span>2</span></pre></td><td><pre><span>x := 1</span></pre></td></tr></table></div>`,
+			" x := 1 ", false,
+		},
 	}
 	for _, tst := range convTest {
 		var ch HTML
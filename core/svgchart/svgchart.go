@@ -0,0 +1,398 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package svgchart draws static, accessible SVG charts so that charts
+// display without client-side script (exports, email, crawlers).
+package svgchart
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"html/template"
+	"math"
+)
+
+// palette provides series colors in order.
+var palette = []string{"#4c9be8", "#f4a33a", "#5cb85c", "#d9534f", "#8e6cc1", "#1fb5ad", "#e67ab8", "#8a8a8a"}
+
+// Series is a named set of values plotted against chart labels.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+// Chart describes the data to draw.
+type Chart struct {
+	Title  string
+	Labels []string
+	Series []Series
+	Width  int
+	Height int
+}
+
+// plot area margins
+const (
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 50
+	gridLines    = 5
+)
+
+func (c *Chart) setDefaults() {
+	if c.Width <= 0 {
+		c.Width = 640
+	}
+	if c.Height <= 0 {
+		c.Height = 360
+	}
+}
+
+// color returns the palette color for series i.
+func color(i int) string {
+	return palette[i%len(palette)]
+}
+
+// value returns series value for label index, zero if missing.
+func (s Series) value(i int) float64 {
+	if i < len(s.Values) {
+		return s.Values[i]
+	}
+	return 0
+}
+
+// Bar draws a grouped bar chart, one bar per series for each label.
+func Bar(c Chart) string {
+	c.setDefaults()
+	min, max := c.bounds()
+
+	b := new(bytes.Buffer)
+	c.open(b)
+	c.axes(b, min, max)
+
+	plotW := float64(c.Width - marginLeft - marginRight)
+	groupW := plotW / float64(max1(len(c.Labels)))
+	barW := groupW * 0.8 / float64(max1(len(c.Series)))
+
+	for i := range c.Labels {
+		for j, s := range c.Series {
+			v := s.value(i)
+			x := float64(marginLeft) + groupW*float64(i) + groupW*0.1 + barW*float64(j)
+			y0 := c.y(0, min, max)
+			y1 := c.y(v, min, max)
+			top, height := math.Min(y0, y1), math.Abs(y0-y1)
+
+			fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`,
+				x, top, barW, height, color(j), esc(c.Labels[i]+" "+s.Name), format(v))
+		}
+	}
+
+	c.xLabels(b, groupW)
+	c.legend(b)
+	c.close(b)
+
+	return b.String()
+}
+
+// Line draws one polyline per series with a point marker at each label.
+func Line(c Chart) string {
+	c.setDefaults()
+	min, max := c.bounds()
+
+	b := new(bytes.Buffer)
+	c.open(b)
+	c.axes(b, min, max)
+
+	plotW := float64(c.Width - marginLeft - marginRight)
+	step := plotW / float64(max1(len(c.Labels)))
+
+	for j, s := range c.Series {
+		points := new(bytes.Buffer)
+		markers := new(bytes.Buffer)
+
+		for i := range c.Labels {
+			v := s.value(i)
+			x := float64(marginLeft) + step*float64(i) + step/2
+			y := c.y(v, min, max)
+
+			fmt.Fprintf(points, "%.1f,%.1f ", x, y)
+			fmt.Fprintf(markers, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s: %s</title></circle>`,
+				x, y, color(j), esc(c.Labels[i]+" "+s.Name), format(v))
+		}
+
+		fmt.Fprintf(b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, points.String(), color(j))
+		b.Write(markers.Bytes())
+	}
+
+	c.xLabels(b, step)
+	c.legend(b)
+	c.close(b)
+
+	return b.String()
+}
+
+// Stacked draws one bar per label with series values stacked on top of
+// each other. Negative values stack downwards from zero.
+func Stacked(c Chart) string {
+	c.setDefaults()
+	min, max := c.stackedBounds()
+
+	b := new(bytes.Buffer)
+	c.open(b)
+	c.axes(b, min, max)
+
+	plotW := float64(c.Width - marginLeft - marginRight)
+	slot := plotW / float64(max1(len(c.Labels)))
+	barW := slot * 0.6
+
+	for i := range c.Labels {
+		x := float64(marginLeft) + slot*float64(i) + slot*0.2
+		up, down := 0.0, 0.0
+
+		for j, s := range c.Series {
+			v := s.value(i)
+			from := &up
+			if v < 0 {
+				from = &down
+			}
+
+			y0 := c.y(*from, min, max)
+			y1 := c.y(*from+v, min, max)
+			*from += v
+
+			fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`,
+				x, math.Min(y0, y1), barW, math.Abs(y0-y1), color(j), esc(c.Labels[i]+" "+s.Name), format(v))
+		}
+	}
+
+	c.xLabels(b, slot)
+	c.legend(b)
+	c.close(b)
+
+	return b.String()
+}
+
+// Pie draws the first series as slices of a circle, one per label.
+// Negative values cannot be shown and are skipped.
+func Pie(c Chart) string {
+	c.setDefaults()
+
+	var values []float64
+	if len(c.Series) > 0 {
+		values = c.Series[0].Values
+	}
+
+	total := 0.0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+
+	b := new(bytes.Buffer)
+	c.open(b)
+
+	radius := math.Min(float64(c.Width-marginLeft-marginRight), float64(c.Height-marginTop-marginBottom)) / 2
+	cx := float64(marginLeft) + radius
+	cy := float64(marginTop) + radius
+
+	angle := -math.Pi / 2 // start at twelve o'clock
+	for i, l := range c.Labels {
+		if i >= len(values) || values[i] <= 0 || total == 0 {
+			continue
+		}
+
+		title := fmt.Sprintf("<title>%s: %s (%.1f%%)</title>", esc(l), format(values[i]), values[i]/total*100)
+		sweep := values[i] / total * 2 * math.Pi
+
+		if sweep >= 2*math.Pi-1e-9 {
+			fmt.Fprintf(b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s">%s</circle>`, cx, cy, radius, color(i), title)
+			continue
+		}
+
+		x0, y0 := cx+radius*math.Cos(angle), cy+radius*math.Sin(angle)
+		angle += sweep
+		x1, y1 := cx+radius*math.Cos(angle), cy+radius*math.Sin(angle)
+
+		large := 0
+		if sweep > math.Pi {
+			large = 1
+		}
+
+		fmt.Fprintf(b, `<path d="M%.1f,%.1f L%.1f,%.1f A%.1f,%.1f 0 %d 1 %.1f,%.1f Z" fill="%s" stroke="#fff">%s</path>`,
+			cx, cy, x0, y0, radius, radius, large, x1, y1, color(i), title)
+	}
+
+	// legend lists labels beside the pie
+	x := cx + radius + 30
+	for i, l := range c.Labels {
+		y := float64(marginTop) + 10 + float64(i)*18
+		if y > float64(c.Height-10) {
+			break
+		}
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`, x, y-9, color(i))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, x+14, y, esc(truncate(l, 30)))
+	}
+
+	c.close(b)
+
+	return b.String()
+}
+
+// open writes the SVG element with title and description for screen readers.
+// Element IDs derive from chart content so output is repeatable yet
+// distinct for multiple charts on one page.
+func (c *Chart) open(b *bytes.Buffer) {
+	desc := c.describe()
+
+	h := fnv.New32a()
+	h.Write([]byte(c.Title + desc))
+	id := fmt.Sprintf("chart-%x", h.Sum32())
+
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" class="documize-chart" width="%d" height="%d" viewBox="0 0 %d %d" role="img" aria-labelledby="%s-title %s-desc">`,
+		c.Width, c.Height, c.Width, c.Height, id, id)
+	fmt.Fprintf(b, `<title id="%s-title">%s</title>`, id, esc(c.Title))
+	fmt.Fprintf(b, `<desc id="%s-desc">%s</desc>`, id, esc(desc))
+	b.WriteString(`<style>text{font-family:sans-serif;font-size:11px;fill:#555}</style>`)
+
+	if len(c.Title) > 0 {
+		fmt.Fprintf(b, `<text x="%d" y="18" text-anchor="middle" style="font-size:14px;fill:#333">%s</text>`, c.Width/2, esc(c.Title))
+	}
+}
+
+func (c *Chart) close(b *bytes.Buffer) {
+	b.WriteString("</svg>")
+}
+
+// describe summarizes the data for assistive technology.
+func (c *Chart) describe() string {
+	d := new(bytes.Buffer)
+	for _, s := range c.Series {
+		fmt.Fprintf(d, "%s: ", s.Name)
+		for i, l := range c.Labels {
+			if i > 0 {
+				d.WriteString(", ")
+			}
+			fmt.Fprintf(d, "%s %s", l, format(s.value(i)))
+		}
+		d.WriteString(". ")
+	}
+	return d.String()
+}
+
+// axes draws horizontal grid lines with value labels.
+func (c *Chart) axes(b *bytes.Buffer, min, max float64) {
+	left := float64(marginLeft)
+	right := float64(c.Width - marginRight)
+
+	for i := 0; i <= gridLines; i++ {
+		v := min + (max-min)*float64(i)/gridLines
+		y := c.y(v, min, max)
+
+		fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#e5e5e5"/>`, left, y, right, y)
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="end">%s</text>`, left-6, y+4, format(v))
+	}
+
+	y0 := c.y(0, min, max)
+	fmt.Fprintf(b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#999"/>`, left, y0, right, y0)
+}
+
+// xLabels writes category labels centered beneath each slot.
+func (c *Chart) xLabels(b *bytes.Buffer, slot float64) {
+	y := float64(c.Height-marginBottom) + 16
+	for i, l := range c.Labels {
+		x := float64(marginLeft) + slot*float64(i) + slot/2
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`, x, y, esc(truncate(l, 14)))
+	}
+}
+
+// legend lists series names along the bottom edge.
+func (c *Chart) legend(b *bytes.Buffer) {
+	if len(c.Series) < 2 {
+		return
+	}
+
+	x := float64(marginLeft)
+	y := float64(c.Height - 12)
+	for i, s := range c.Series {
+		fmt.Fprintf(b, `<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`, x, y-9, color(i))
+		fmt.Fprintf(b, `<text x="%.1f" y="%.1f">%s</text>`, x+14, y, esc(truncate(s.Name, 20)))
+		x += 24 + float64(len(truncate(s.Name, 20)))*6
+	}
+}
+
+// bounds returns the value range, always including zero.
+func (c *Chart) bounds() (min, max float64) {
+	for _, s := range c.Series {
+		for _, v := range s.Values {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+		}
+	}
+	if max == min {
+		max = min + 1
+	}
+	return
+}
+
+// stackedBounds returns the range of stacked totals, always including zero.
+func (c *Chart) stackedBounds() (min, max float64) {
+	for i := range c.Labels {
+		up, down := 0.0, 0.0
+		for _, s := range c.Series {
+			if v := s.value(i); v < 0 {
+				down += v
+			} else {
+				up += v
+			}
+		}
+		min = math.Min(min, down)
+		max = math.Max(max, up)
+	}
+	if max == min {
+		max = min + 1
+	}
+	return
+}
+
+// y maps value onto the vertical pixel position.
+func (c *Chart) y(v, min, max float64) float64 {
+	plotH := float64(c.Height - marginTop - marginBottom)
+	return float64(marginTop) + plotH - (v-min)/(max-min)*plotH
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+func esc(s string) string {
+	return template.HTMLEscapeString(s)
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// format prints numbers without needless decimals.
+func format(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
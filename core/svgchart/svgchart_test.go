@@ -0,0 +1,42 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package svgchart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCharts(t *testing.T) {
+	c := Chart{
+		Title:  "Orders <by> month",
+		Labels: []string{"Jan", "Feb", "Mar"},
+		Series: []Series{{Name: "EU", Values: []float64{3, 5, 2}}, {Name: "US", Values: []float64{4, -1}}},
+	}
+
+	for name, draw := range map[string]func(Chart) string{"bar": Bar, "line": Line, "stacked": Stacked, "pie": Pie} {
+		got := draw(c)
+
+		if !strings.HasPrefix(got, "<svg") || !strings.HasSuffix(got, "</svg>") {
+			t.Errorf("%s chart is not an svg element: %s", name, got)
+		}
+		if !strings.Contains(got, "Orders &lt;by&gt; month") || strings.Contains(got, "<by>") {
+			t.Errorf("%s chart title not escaped: %s", name, got)
+		}
+		if !strings.Contains(got, "US: Jan 4, Feb -1, Mar 0.") {
+			t.Errorf("%s chart missing description: %s", name, got)
+		}
+		if got != draw(c) {
+			t.Errorf("%s chart output is not repeatable", name)
+		}
+	}
+}
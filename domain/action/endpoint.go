@@ -0,0 +1,498 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package action
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/stringutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/domain/mail"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/action"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/user"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// RequestApproval asks one or more users to approve a document.
+// Space managers can also change how many approvals the document
+// needs before going live.
+func (h *Handler) RequestApproval(w http.ResponseWriter, r *http.Request) {
+	method := "action.requestApproval"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := action.ApprovalRequest{}
+	err = json.Unmarshal(body, &model)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model.Note = strings.TrimSpace(model.Note)
+	if len(model.Users) == 0 && model.Required == nil {
+		response.WriteMissingDataError(w, method, "users")
+		return
+	}
+	if model.Required != nil && *model.Required < 0 {
+		response.WriteBadRequestError(w, method, "required approvals cannot be negative")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if model.Required != nil && *model.Required != d.Approvals && !space.CanManageSpace(ctx, *h.Store, d.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	approvers, ok := h.approvers(w, ctx, method, d, model.Users)
+	if !ok {
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if model.Required != nil {
+		err = h.Store.Document.UpdateApprovals(ctx, documentID, *model.Required)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	for _, u := range approvers {
+		a := action.UserAction{}
+		a.RefID = uniqueid.Generate()
+		a.UserID = u.RefID
+		a.DocumentID = documentID
+		a.ActionType = action.TypeApprove
+		a.Note = model.Note
+		a.Due = model.Due
+
+		err = h.Store.Action.Add(ctx, a)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	if len(approvers) > 0 {
+		h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+			LabelID:      d.LabelID,
+			SourceID:     documentID,
+			SourceType:   activity.SourceTypeDocument,
+			ActivityType: activity.TypeApprovalRequest})
+	}
+
+	ctx.Transaction.Commit()
+
+	if len(approvers) > 0 {
+		h.Store.Audit.Record(ctx, audit.EventTypeApprovalRequest)
+
+		requestor, err := h.Store.User.Get(ctx, ctx.UserID)
+		if err != nil {
+			h.Runtime.Log.Error(method, err)
+		}
+
+		due := ""
+		if model.Due != nil {
+			due = model.Due.Format("2 January 2006")
+		}
+
+		url := h.documentURL(ctx, d)
+		mailer := mail.Mailer{Runtime: h.Runtime, Store: h.Store, Context: ctx}
+		for _, u := range approvers {
+			go mailer.ApprovalRequest(u.Email, requestor.Fullname(), url, d.Title, model.Note, due)
+		}
+	}
+
+	a, err := h.Store.Action.GetByDocument(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, a)
+}
+
+// GetByDocument returns approval requests and responses for a document.
+func (h *Handler) GetByDocument(w http.ResponseWriter, r *http.Request) {
+	method := "action.getByDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	a, err := h.Store.Action.GetByDocument(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(a) == 0 {
+		a = []action.UserAction{}
+	}
+
+	response.WriteJSON(w, a)
+}
+
+// GetPending returns actions the current user has yet to complete.
+func (h *Handler) GetPending(w http.ResponseWriter, r *http.Request) {
+	method := "action.getPending"
+	ctx := domain.GetRequestContext(r)
+
+	a, err := h.Store.Action.GetPending(ctx, ctx.UserID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(a) == 0 {
+		a = []action.UserAction{}
+	}
+
+	response.WriteJSON(w, a)
+}
+
+// Respond approves or rejects a document on behalf of the user asked.
+// A draft document goes live once it has the approvals it needs.
+func (h *Handler) Respond(w http.ResponseWriter, r *http.Request) {
+	method := "action.respond"
+	ctx := domain.GetRequestContext(r)
+
+	actionID := request.Param(r, "actionID")
+	if len(actionID) == 0 {
+		response.WriteMissingDataError(w, method, "actionID")
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := action.Response{}
+	err = json.Unmarshal(body, &model)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model.Note = strings.TrimSpace(model.Note)
+
+	a, ok := h.get(w, ctx, method, actionID)
+	if !ok {
+		return
+	}
+
+	if a.UserID != ctx.UserID || !document.CanViewDocument(ctx, *h.Store, a.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	if a.IsComplete {
+		response.WriteConflictError(w, method, a)
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, a.DocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	outcome := action.OutcomeRejected
+	activityType := activity.TypeRejected
+	eventType := audit.EventTypeDocumentReject
+	if model.Approved {
+		outcome = action.OutcomeApproved
+		activityType = activity.TypeApproved
+		eventType = audit.EventTypeDocumentApprove
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Action.Complete(ctx, actionID, outcome, model.Note)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      d.LabelID,
+		SourceID:     d.RefID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activityType})
+
+	if model.Approved && d.Lifecycle == doc.LifecycleDraft && d.Approvals > 0 {
+		err = h.publish(ctx, d)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, eventType)
+
+	approver, err := h.Store.User.Get(ctx, ctx.UserID)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+	}
+	requestor, err := h.Store.User.Get(ctx, a.RequestorID)
+	if err == nil {
+		mailer := mail.Mailer{Runtime: h.Runtime, Store: h.Store, Context: ctx}
+		go mailer.ApprovalResult(requestor.Email, approver.Fullname(), h.documentURL(ctx, d), d.Title, model.Approved, model.Note)
+	} else if errors.Cause(err) != sql.ErrNoRows {
+		h.Runtime.Log.Error(method, err)
+	}
+
+	a, err = h.Store.Action.Get(ctx, actionID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, a)
+}
+
+// Cancel withdraws a request that has not been responded to.
+// Only the requestor or a space editor can do this.
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	method := "action.cancel"
+	ctx := domain.GetRequestContext(r)
+
+	actionID := request.Param(r, "actionID")
+	if len(actionID) == 0 {
+		response.WriteMissingDataError(w, method, "actionID")
+		return
+	}
+
+	a, ok := h.get(w, ctx, method, actionID)
+	if !ok {
+		return
+	}
+
+	if a.RequestorID != ctx.UserID && !space.CanChangeSpaceDocuments(ctx, *h.Store, a.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	if a.IsComplete {
+		response.WriteConflictError(w, method, a)
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Action.Delete(ctx, actionID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeApprovalCancel)
+
+	response.WriteEmpty(w)
+}
+
+// get fetches an action, writing the error response on failure.
+func (h *Handler) get(w http.ResponseWriter, ctx domain.RequestContext, method, actionID string) (a action.UserAction, ok bool) {
+	a, err := h.Store.Action.Get(ctx, actionID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, actionID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	return a, true
+}
+
+// approvers loads the users asked to approve a document, skipping those with
+// a request still outstanding. Everyone must be able to view the document
+// and nobody can approve their own request.
+func (h *Handler) approvers(w http.ResponseWriter, ctx domain.RequestContext, method string, d doc.Document, userIDs []string) (approvers []user.User, ok bool) {
+	existing, err := h.Store.Action.GetByDocument(ctx, d.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	pending := make(map[string]bool)
+	for _, a := range existing {
+		if a.ActionType == action.TypeApprove && !a.IsComplete {
+			pending[a.UserID] = true
+		}
+	}
+
+	roles, err := h.Store.Space.GetRoles(ctx, d.LabelID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	viewers := make(map[string]bool)
+	for _, role := range roles {
+		if role.CanView || role.CanEdit {
+			viewers[role.UserID] = true
+		}
+	}
+
+	for _, id := range userIDs {
+		if id == ctx.UserID {
+			response.WriteBadRequestError(w, method, "cannot request your own approval")
+			return
+		}
+		if pending[id] {
+			continue
+		}
+
+		acc, err := h.Store.Account.GetUserAccount(ctx, id)
+		if err != nil || !acc.Active {
+			response.WriteBadRequestError(w, method, fmt.Sprintf("unknown user %s", id))
+			return
+		}
+
+		u, err := h.Store.User.Get(ctx, id)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		// blank user means everyone
+		if !viewers[id] && !viewers[""] {
+			response.WriteBadRequestError(w, method, fmt.Sprintf("%s cannot view this document", u.Fullname()))
+			return
+		}
+
+		pending[id] = true
+		approvers = append(approvers, u)
+	}
+
+	return approvers, true
+}
+
+// publish makes a draft document live within ctx.Transaction when it
+// has enough approvals, counting the response being recorded.
+func (h *Handler) publish(ctx domain.RequestContext, d doc.Document) (err error) {
+	approved, err := h.Store.Action.CountApprovals(ctx, d.RefID)
+	if err != nil || approved < d.Approvals {
+		return
+	}
+
+	return document.ChangeLifecycle(ctx, *h.Store, d, doc.LifecycleLive)
+}
+
+// documentURL links to the document in the application.
+func (h *Handler) documentURL(ctx domain.RequestContext, d doc.Document) string {
+	sp, err := h.Store.Space.Get(ctx, d.LabelID)
+	if err != nil {
+		return ctx.GetAppURL("")
+	}
+
+	return ctx.GetAppURL(fmt.Sprintf("s/%s/%s/d/%s/%s",
+		sp.RefID, stringutil.MakeSlug(sp.Name), d.RefID, stringutil.MakeSlug(d.Title)))
+}
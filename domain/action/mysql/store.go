@@ -0,0 +1,154 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/action"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// selectAction includes document and user names for display.
+const selectAction = `SELECT a.id, a.refid, a.orgid, a.userid, a.documentid, a.requestorid, a.actiontype, a.note, a.requested, a.due, a.completed, a.iscomplete, a.outcome, a.reply, a.created, a.revised,
+	coalesce(d.title,'') as documenttitle, coalesce(d.labelid,'') as labelid,
+	coalesce(u.firstname,'') as firstname, coalesce(u.lastname,'') as lastname,
+	coalesce(CONCAT(r.firstname, ' ', r.lastname),'') as requestedby
+	FROM useraction a
+	LEFT JOIN document d ON a.documentid=d.refid AND a.orgid=d.orgid
+	LEFT JOIN user u ON a.userid=u.refid
+	LEFT JOIN user r ON a.requestorid=r.refid`
+
+// Add records a new action for a user.
+func (s Scope) Add(ctx domain.RequestContext, a action.UserAction) (err error) {
+	a.OrgID = ctx.OrgID
+	a.RequestorID = ctx.UserID
+	a.Requested = time.Now().UTC()
+	a.Created = time.Now().UTC()
+	a.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO useraction (refid, orgid, userid, documentid, requestorid, actiontype, note, requested, due, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert user action")
+		return
+	}
+
+	_, err = stmt.Exec(a.RefID, a.OrgID, a.UserID, a.DocumentID, a.RequestorID, a.ActionType, a.Note, a.Requested, a.Due, a.Created, a.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert user action")
+		return
+	}
+
+	return
+}
+
+// Get returns requested user action.
+func (s Scope) Get(ctx domain.RequestContext, id string) (a action.UserAction, err error) {
+	stmt, err := s.Runtime.Db.Preparex(selectAction + " WHERE a.orgid=? AND a.refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare select user action %s", id))
+		return
+	}
+
+	err = stmt.Get(&a, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute select user action %s", id))
+		return
+	}
+
+	return
+}
+
+// GetByDocument returns every action requested for a document, newest first.
+func (s Scope) GetByDocument(ctx domain.RequestContext, documentID string) (a []action.UserAction, err error) {
+	err = s.Runtime.Db.Select(&a, selectAction+" WHERE a.orgid=? AND a.documentid=? ORDER BY a.id DESC", ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select user actions for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// GetPending returns outstanding actions for a user, soonest due first.
+// Actions for documents that no longer exist are left out.
+func (s Scope) GetPending(ctx domain.RequestContext, userID string) (a []action.UserAction, err error) {
+	err = s.Runtime.Db.Select(&a, selectAction+" WHERE a.orgid=? AND a.userid=? AND a.iscomplete=0 AND d.refid IS NOT NULL ORDER BY a.due IS NULL, a.due, a.id", ctx.OrgID, userID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select pending user actions for %s", userID))
+		return
+	}
+
+	return
+}
+
+// Complete records the user's response to an action.
+func (s Scope) Complete(ctx domain.RequestContext, id string, outcome action.Outcome, reply string) (err error) {
+	completed := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE useraction SET iscomplete=1, completed=?, outcome=?, reply=?, revised=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare complete user action %s", id))
+		return
+	}
+
+	_, err = stmt.Exec(completed, outcome, reply, completed, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute complete user action %s", id))
+		return
+	}
+
+	return
+}
+
+// CountApprovals returns how many users currently approve the document,
+// going by each approver's most recent response. It reads within
+// ctx.Transaction so responses being recorded are counted.
+func (s Scope) CountApprovals(ctx domain.RequestContext, documentID string) (count int, err error) {
+	row := ctx.Transaction.QueryRow(`SELECT COUNT(DISTINCT a.userid) FROM useraction a
+		WHERE a.orgid=? AND a.documentid=? AND a.actiontype=? AND a.iscomplete=1 AND a.outcome=?
+		AND a.completed=(SELECT MAX(b.completed) FROM useraction b
+			WHERE b.orgid=a.orgid AND b.documentid=a.documentid AND b.userid=a.userid AND b.actiontype=a.actiontype AND b.iscomplete=1)`,
+		ctx.OrgID, documentID, action.TypeApprove, action.OutcomeApproved)
+
+	err = row.Scan(&count)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("count approvals for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// Delete removes a user action.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "useraction", ctx.OrgID, id)
+}
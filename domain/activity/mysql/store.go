@@ -74,3 +74,16 @@ func (s Scope) GetDocumentActivity(ctx domain.RequestContext, id string) (a []ac
 
 	return
 }
+
+// GetLastRead returns when the current user last read a document before the given time.
+func (s Scope) GetLastRead(ctx domain.RequestContext, documentID string, before time.Time) (read time.Time, err error) {
+	err = s.Runtime.Db.Get(&read, "SELECT created FROM useractivity WHERE orgid=? AND userid=? AND sourceid=? AND sourcetype=? AND activitytype=? AND created<? ORDER BY created DESC LIMIT 1",
+		ctx.OrgID, ctx.UserID, documentID, activity.SourceTypeDocument, activity.TypeRead, before.UTC())
+
+	if err != nil {
+		err = errors.Wrap(err, "select last document read")
+		return
+	}
+
+	return
+}
@@ -12,6 +12,7 @@
 package block
 
 import (
+	"database/sql"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
@@ -23,14 +24,19 @@ import (
 	"github.com/documize/community/core/uniqueid"
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/document"
+	indexer "github.com/documize/community/domain/search"
 	"github.com/documize/community/model/audit"
 	"github.com/documize/community/model/block"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+	"github.com/pkg/errors"
 )
 
 // Handler contains the runtime information such as logging and database.
 type Handler struct {
 	Runtime *env.Runtime
 	Store   *domain.Store
+	Indexer indexer.Indexer
 }
 
 // Add inserts new reusable content block into database.
@@ -163,13 +169,27 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b.RefID = blockID
+	// The stored block decides where it lives, not the payload.
+	current, err := h.Store.Block.Get(ctx, blockID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, blockID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
 
-	if !document.CanUploadDocument(ctx, *h.Store, b.LabelID) {
+	if !document.CanUploadDocument(ctx, *h.Store, current.LabelID) {
 		response.WriteForbiddenError(w)
 		return
 	}
 
+	b.RefID = blockID
+	b.OrgID = ctx.OrgID
+	b.LabelID = current.LabelID
+
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -183,11 +203,88 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	linked, result, err := h.propagate(ctx, b)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	h.Store.Audit.Record(ctx, audit.EventTypeBlockUpdate)
 
 	ctx.Transaction.Commit()
 
-	response.WriteEmpty(w)
+	for _, p := range linked {
+		go h.Indexer.IndexContent(ctx, p)
+	}
+
+	response.WriteJSON(w, result)
+}
+
+// propagate copies block content into every section linked to the block,
+// keeping each section's previous content as a revision. Sections the user
+// cannot change, in archived documents or locked by someone else are
+// left as they are, reported as skipped and marked stale so they catch
+// up once they can be changed (see Resync).
+func (h *Handler) propagate(ctx domain.RequestContext, b block.Block) (updated []page.Page, result block.Propagation, err error) {
+	result.Skipped = []block.Skipped{}
+
+	linked, err := h.Store.Block.GetLinkedPages(ctx, b.RefID)
+	if err != nil {
+		return
+	}
+
+	reasons := make(map[string]string) // by document
+	for i := range linked {
+		p := linked[i]
+
+		reason, checked := reasons[p.DocumentID]
+		if !checked {
+			reason = h.documentSkipReason(ctx, p.DocumentID)
+			reasons[p.DocumentID] = reason
+		}
+		if len(reason) == 0 && lockedByOther(ctx, *h.Store, p.RefID) {
+			reason = "locked"
+		}
+		if len(reason) > 0 {
+			result.Skipped = append(result.Skipped, block.Skipped{DocumentID: p.DocumentID, PageID: p.RefID, Reason: reason})
+
+			err = h.Store.Block.SetStale(ctx, p.RefID, true)
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		p, err = follow(ctx, h.Runtime, *h.Store, p, b)
+		if err != nil {
+			return
+		}
+
+		updated = append(updated, p)
+	}
+
+	result.Updated = len(updated)
+
+	return
+}
+
+// documentSkipReason says why block content cannot be copied into a
+// document's sections, or returns empty when it can.
+func (h *Handler) documentSkipReason(ctx domain.RequestContext, documentID string) string {
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		return "forbidden"
+	}
+	if d.Lifecycle == doc.LifecycleArchived {
+		return "archived"
+	}
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		return "forbidden"
+	}
+
+	return ""
 }
 
 // Delete removes requested reusable content block.
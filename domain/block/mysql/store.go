@@ -13,6 +13,7 @@ package mysql
 
 import (
 	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/documize/community/core/env"
@@ -20,6 +21,7 @@ import (
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/store/mysql"
 	"github.com/documize/community/model/block"
+	"github.com/documize/community/model/page"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
@@ -123,8 +125,9 @@ func (s Scope) DecrementUsage(ctx domain.RequestContext, id string) (err error)
 }
 
 // RemoveReference clears page.blockid for given blockID.
+// Linked pages keep the block's last content as their own.
 func (s Scope) RemoveReference(ctx domain.RequestContext, id string) (err error) {
-	stmt, err := ctx.Transaction.Preparex("UPDATE page SET blockid='', revised=? WHERE orgid=? AND blockid=?")
+	stmt, err := ctx.Transaction.Preparex("UPDATE page SET blockid='', blocklinked=0, blockstale=0, revised=? WHERE orgid=? AND blockid=?")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -146,6 +149,58 @@ func (s Scope) RemoveReference(ctx domain.RequestContext, id string) (err error)
 	return
 }
 
+// GetLinkedPages returns pages whose content follows the given block.
+func (s Scope) GetLinkedPages(ctx domain.RequestContext, id string) (p []page.Page, err error) {
+	err = s.Runtime.Db.Select(&p, "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.blockid, a.blocklinked, a.revisions, a.version, a.created, a.revised FROM page a WHERE a.orgid=? AND a.blockid=? AND a.blocklinked=1", ctx.OrgID, id)
+
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select pages linked to block %s", id))
+		return
+	}
+
+	return
+}
+
+// Unlink makes a linked page an independent copy of its block.
+func (s Scope) Unlink(ctx domain.RequestContext, pageID string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE page SET blocklinked=0, blockstale=0, revised=? WHERE orgid=? AND refid=?", time.Now().UTC(), ctx.OrgID, pageID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("unlink page %s from block", pageID))
+		return
+	}
+
+	return
+}
+
+// SetStale records whether a linked page missed block updates it could not take.
+func (s Scope) SetStale(ctx domain.RequestContext, pageID string, stale bool) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE page SET blockstale=? WHERE orgid=? AND refid=?", stale, ctx.OrgID, pageID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("set block stale for page %s", pageID))
+		return
+	}
+
+	return
+}
+
+// GetStalePages returns pages of a document that follow a block but missed its updates.
+func (s Scope) GetStalePages(ctx domain.RequestContext, documentID string) (p []page.Page, err error) {
+	err = s.Runtime.Db.Select(&p, "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.blockid, a.blocklinked, a.revisions, a.version, a.created, a.revised FROM page a WHERE a.orgid=? AND a.documentid=? AND a.blocklinked=1 AND a.blockstale=1", ctx.OrgID, documentID)
+
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select stale block pages for document %s", documentID))
+		return
+	}
+
+	return
+}
+
 // Update updates existing reusable content block item.
 func (s Scope) Update(ctx domain.RequestContext, b block.Block) (err error) {
 	b.Revised = time.Now().UTC()
@@ -0,0 +1,117 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package block
+
+import (
+	"database/sql"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/comment"
+	"github.com/documize/community/model/block"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+	"github.com/pkg/errors"
+)
+
+// Resync brings a document's linked sections that missed block updates
+// up to date within ctx.Transaction. Callers check the user can change
+// the document. Nothing happens while the document is archived, and
+// sections locked by someone else stay stale until they are unlocked.
+// Updated sections are returned for indexing.
+func Resync(ctx domain.RequestContext, rt *env.Runtime, s domain.Store, documentID string) (updated []page.Page, err error) {
+	stale, err := s.Block.GetStalePages(ctx, documentID)
+	if err != nil || len(stale) == 0 {
+		return
+	}
+
+	d, err := s.Document.Get(ctx, documentID)
+	if err != nil {
+		return
+	}
+	if d.Lifecycle == doc.LifecycleArchived {
+		return
+	}
+
+	blocks := make(map[string]block.Block)
+	for i := range stale {
+		p := stale[i]
+		if lockedByOther(ctx, s, p.RefID) {
+			continue
+		}
+
+		b, ok := blocks[p.BlockID]
+		if !ok {
+			b, err = s.Block.Get(ctx, p.BlockID)
+			if errors.Cause(err) == sql.ErrNoRows {
+				err = nil
+				continue
+			}
+			if err != nil {
+				return
+			}
+			blocks[p.BlockID] = b
+		}
+
+		p, err = follow(ctx, rt, s, p, b)
+		if err != nil {
+			return
+		}
+
+		updated = append(updated, p)
+	}
+
+	return
+}
+
+// follow copies block content into a linked section, keeping the
+// section's previous content as a revision.
+func follow(ctx domain.RequestContext, rt *env.Runtime, s domain.Store, p page.Page, b block.Block) (page.Page, error) {
+	p.Body = b.Body
+
+	err := s.Page.Update(ctx, p, uniqueid.Generate(), ctx.UserID, false)
+	if err != nil {
+		return p, err
+	}
+
+	meta, err := s.Page.GetPageMeta(ctx, p.RefID)
+	if err != nil {
+		return p, err
+	}
+
+	meta.RawBody = b.RawBody
+	meta.Config = b.Config
+
+	err = s.Page.UpdateMeta(ctx, meta, true)
+	if err != nil {
+		return p, err
+	}
+
+	err = s.Block.SetStale(ctx, p.RefID, false)
+	if err != nil {
+		return p, err
+	}
+
+	// Comments that can no longer be placed are flagged, not fatal.
+	if err2 := comment.Reanchor(ctx, s, p); err2 != nil {
+		rt.Log.Error("block.follow", err2)
+	}
+
+	return p, nil
+}
+
+// lockedByOther says whether someone other than the current user holds the section lock.
+func lockedByOther(ctx domain.RequestContext, s domain.Store, pageID string) bool {
+	l, err := s.Page.GetLock(ctx, pageID)
+	return err == nil && l.UserID != ctx.UserID
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package comment
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/documize/community/core/stringutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/page"
+)
+
+// Reanchor moves comments anchored to text in a section to wherever that
+// text now sits, so they survive edits elsewhere in the section. Comments
+// whose text has gone are marked outdated. Runs within ctx.Transaction.
+func Reanchor(ctx domain.RequestContext, s domain.Store, p page.Page) (err error) {
+	comments, err := s.Comment.GetAnchored(ctx, p.RefID)
+	if err != nil || len(comments) == 0 {
+		return
+	}
+
+	text, err := stringutil.HTML(p.Body).Text(false)
+	if err != nil {
+		return
+	}
+
+	for _, c := range comments {
+		start, found := locate(text, c.Anchor, c.AnchorStart)
+		if !found {
+			start = c.AnchorStart
+		}
+		if start == c.AnchorStart && found == !c.Outdated {
+			continue
+		}
+
+		err = s.Comment.UpdateAnchor(ctx, c.RefID, start, !found)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// locate finds anchor in text, preferring the occurrence closest to previous.
+// Offsets count characters in the text with whitespace runs collapsed to a
+// single space, so reflowed content does not move anchors.
+func locate(text, anchor string, previous int) (start int, found bool) {
+	text = collapse(text)
+	anchor = collapse(anchor)
+	if len(anchor) == 0 {
+		return
+	}
+
+	best := -1
+	for i := 0; i <= len(text)-len(anchor); {
+		pos := strings.Index(text[i:], anchor)
+		if pos == -1 {
+			break
+		}
+
+		offset := utf8.RuneCountInString(text[:i+pos])
+		if best == -1 || distance(offset, previous) < distance(best, previous) {
+			best = offset
+		}
+
+		_, size := utf8.DecodeRuneInString(text[i+pos:])
+		i += pos + size
+	}
+
+	if best == -1 {
+		return
+	}
+
+	return best, true
+}
+
+// collapse trims and reduces whitespace runs to a single space.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func distance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
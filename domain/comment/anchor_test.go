@@ -0,0 +1,41 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package comment
+
+import "testing"
+
+func TestLocate(t *testing.T) {
+	type testLocate struct {
+		text     string
+		anchor   string
+		previous int
+		start    int
+		found    bool
+	}
+	tests := []testLocate{
+		{"the quick brown fox", "brown", 10, 10, true},
+		{"added text. the quick brown fox", "brown", 10, 22, true},
+		{"the quick\n\n  brown fox", "quick brown", 4, 4, true},
+		{"fox one, fox two, fox three", "fox", 12, 9, true},
+		{"fox one, fox two, fox three", "fox", 20, 18, true},
+		{"naïve café, café", "café", 12, 12, true},
+		{"the quick brown fox", "red", 10, 0, false},
+		{"the quick brown fox", "  ", 0, 0, false},
+	}
+
+	for _, tst := range tests {
+		start, found := locate(tst.text, tst.anchor, tst.previous)
+		if start != tst.start || found != tst.found {
+			t.Errorf("locate(%q, %q, %d) expected %d %v got %d %v", tst.text, tst.anchor, tst.previous, tst.start, tst.found, start, found)
+		}
+	}
+}
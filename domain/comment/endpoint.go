@@ -0,0 +1,524 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package comment
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/event"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/stringutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/comment"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// GetByDocument returns comment threads for a document, optionally for one section (?page=).
+func (h *Handler) GetByDocument(w http.ResponseWriter, r *http.Request) {
+	method := "comment.getByDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	comments, err := h.Store.Comment.GetByDocument(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, threads(comments, request.Query(r, "page")))
+}
+
+// Add starts a comment thread on a section, optionally anchored to some of its text.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	method := "comment.add"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if ctx.Guest || !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	model, ok := h.decode(w, r, method)
+	if !ok {
+		return
+	}
+
+	p, err := h.Store.Page.Get(ctx, model.PageID)
+	if err != nil || p.DocumentID != documentID {
+		response.WriteBadRequestError(w, method, "section not in document")
+		return
+	}
+
+	c := comment.Comment{}
+	c.RefID = uniqueid.Generate()
+	c.DocumentID = documentID
+	c.PageID = p.RefID
+	c.Body = model.Body
+	c.Anchor = strings.TrimSpace(model.Anchor)
+	c.AnchorStart = -1
+
+	if len(c.Anchor) > 0 {
+		text, _ := stringutil.HTML(p.Body).Text(false)
+		start, found := locate(text, c.Anchor, model.AnchorStart)
+		if !found {
+			response.WriteBadRequestError(w, method, "anchor text not in section")
+			return
+		}
+		c.AnchorStart = start
+	}
+
+	h.add(w, ctx, method, c, model.Mentions)
+}
+
+// Reply adds a comment to an existing thread.
+func (h *Handler) Reply(w http.ResponseWriter, r *http.Request) {
+	method := "comment.reply"
+	ctx := domain.GetRequestContext(r)
+
+	commentID := request.Param(r, "commentID")
+	if len(commentID) == 0 {
+		response.WriteMissingDataError(w, method, "commentID")
+		return
+	}
+
+	parent, ok := h.get(w, ctx, method, commentID)
+	if !ok {
+		return
+	}
+
+	if ctx.Guest || !document.CanViewDocument(ctx, *h.Store, parent.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	model, ok := h.decode(w, r, method)
+	if !ok {
+		return
+	}
+
+	c := comment.Comment{}
+	c.RefID = uniqueid.Generate()
+	c.DocumentID = parent.DocumentID
+	c.PageID = parent.PageID
+	c.ThreadID = parent.RefID
+	if len(parent.ThreadID) > 0 {
+		c.ThreadID = parent.ThreadID
+	}
+	c.Body = model.Body
+	c.AnchorStart = -1
+
+	h.add(w, ctx, method, c, model.Mentions)
+}
+
+// Update changes the text of a comment, keeping the previous text as history.
+// Only the author can do this.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	method := "comment.update"
+	ctx := domain.GetRequestContext(r)
+
+	commentID := request.Param(r, "commentID")
+	if len(commentID) == 0 {
+		response.WriteMissingDataError(w, method, "commentID")
+		return
+	}
+
+	c, ok := h.get(w, ctx, method, commentID)
+	if !ok {
+		return
+	}
+
+	if c.UserID != ctx.UserID || !document.CanViewDocument(ctx, *h.Store, c.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	model, ok := h.decode(w, r, method)
+	if !ok {
+		return
+	}
+
+	previous := c.MentionIDs()
+	mentions := h.mentions(ctx, model.Mentions)
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Comment.AddRevision(ctx, comment.Revision{CommentID: c.RefID, UserID: c.UserID, Body: c.Body})
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	c.Body = model.Body
+	c.Mentions = strings.Join(mentions, ",")
+	c.Edited = true
+
+	err = h.Store.Comment.Update(ctx, c)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeCommentUpdate)
+
+	notify(c, without(mentions, previous))
+
+	c, _ = h.Store.Comment.Get(ctx, c.RefID)
+
+	response.WriteJSON(w, c)
+}
+
+// GetRevisions returns the previous text of an edited comment.
+func (h *Handler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	method := "comment.getRevisions"
+	ctx := domain.GetRequestContext(r)
+
+	commentID := request.Param(r, "commentID")
+	if len(commentID) == 0 {
+		response.WriteMissingDataError(w, method, "commentID")
+		return
+	}
+
+	c, ok := h.get(w, ctx, method, commentID)
+	if !ok {
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, c.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	revisions, err := h.Store.Comment.GetRevisions(ctx, commentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		revisions = []comment.Revision{}
+	}
+
+	response.WriteJSON(w, revisions)
+}
+
+// Resolve closes the thread the comment belongs to.
+func (h *Handler) Resolve(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, "comment.resolve", true)
+}
+
+// Reopen opens a resolved thread again.
+func (h *Handler) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.resolve(w, r, "comment.reopen", false)
+}
+
+// Delete removes a comment, and its replies when it starts a thread.
+// Authors can remove their own comments, document editors any comment.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	method := "comment.delete"
+	ctx := domain.GetRequestContext(r)
+
+	commentID := request.Param(r, "commentID")
+	if len(commentID) == 0 {
+		response.WriteMissingDataError(w, method, "commentID")
+		return
+	}
+
+	c, ok := h.get(w, ctx, method, commentID)
+	if !ok {
+		return
+	}
+
+	if c.UserID != ctx.UserID && !document.CanChangeDocument(ctx, *h.Store, c.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Comment.Delete(ctx, commentID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeCommentDelete)
+
+	response.WriteEmpty(w)
+}
+
+// resolve changes thread state. Thread authors and document editors can do this.
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request, method string, resolved bool) {
+	ctx := domain.GetRequestContext(r)
+
+	commentID := request.Param(r, "commentID")
+	if len(commentID) == 0 {
+		response.WriteMissingDataError(w, method, "commentID")
+		return
+	}
+
+	c, ok := h.get(w, ctx, method, commentID)
+	if !ok {
+		return
+	}
+
+	if len(c.ThreadID) > 0 {
+		c, ok = h.get(w, ctx, method, c.ThreadID)
+		if !ok {
+			return
+		}
+	}
+
+	if c.UserID != ctx.UserID && !document.CanChangeDocument(ctx, *h.Store, c.DocumentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Comment.Resolve(ctx, c.RefID, resolved)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	if resolved {
+		h.Store.Audit.Record(ctx, audit.EventTypeCommentResolve)
+	} else {
+		h.Store.Audit.Record(ctx, audit.EventTypeCommentReopen)
+	}
+
+	c, _ = h.Store.Comment.Get(ctx, c.RefID)
+
+	response.WriteJSON(w, c)
+}
+
+// add saves a new comment, records activity and raises events for notification.
+func (h *Handler) add(w http.ResponseWriter, ctx domain.RequestContext, method string, c comment.Comment, mentionIDs []string) {
+	d, err := h.Store.Document.Get(ctx, c.DocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	mentions := h.mentions(ctx, mentionIDs)
+	c.Mentions = strings.Join(mentions, ",")
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Comment.Add(ctx, c)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      d.LabelID,
+		SourceID:     d.RefID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activity.TypeCommented})
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeCommentAdd)
+
+	c, err = h.Store.Comment.Get(ctx, c.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	event.Handler().Publish(string(event.TypeAddComment), c)
+	notify(c, mentions)
+
+	response.WriteJSON(w, c)
+}
+
+// decode reads the comment payload, requiring some text.
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, method string) (model comment.NewComment, ok bool) {
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	err = json.Unmarshal(body, &model)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model.Body = strings.TrimSpace(model.Body)
+	if len(model.Body) == 0 {
+		response.WriteMissingDataError(w, method, "body")
+		return
+	}
+
+	return model, true
+}
+
+// get fetches a comment, writing the error response on failure.
+func (h *Handler) get(w http.ResponseWriter, ctx domain.RequestContext, method, commentID string) (c comment.Comment, ok bool) {
+	c, err := h.Store.Comment.Get(ctx, commentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, commentID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	return c, true
+}
+
+// mentions keeps active users of the organization, dropping duplicates.
+func (h *Handler) mentions(ctx domain.RequestContext, userIDs []string) (valid []string) {
+	seen := make(map[string]bool)
+	for _, id := range userIDs {
+		if seen[id] || id == ctx.UserID {
+			continue
+		}
+		seen[id] = true
+
+		a, err := h.Store.Account.GetUserAccount(ctx, id)
+		if err == nil && a.Active {
+			valid = append(valid, id)
+		}
+	}
+
+	return
+}
+
+// notify raises an event for each mentioned user.
+func notify(c comment.Comment, userIDs []string) {
+	for _, id := range userIDs {
+		event.Handler().Publish(string(event.TypeMentionUser), id, c)
+	}
+}
+
+// without returns ids not in exclude.
+func without(ids, exclude []string) (remaining []string) {
+	skip := make(map[string]bool)
+	for _, id := range exclude {
+		skip[id] = true
+	}
+	for _, id := range ids {
+		if !skip[id] {
+			remaining = append(remaining, id)
+		}
+	}
+
+	return
+}
+
+// threads groups comments under the comment that started their thread.
+// pageID limits threads to one section when given.
+func threads(comments []comment.Comment, pageID string) (t []comment.Thread) {
+	t = []comment.Thread{}
+	index := make(map[string]int)
+
+	for _, c := range comments {
+		if len(pageID) > 0 && c.PageID != pageID {
+			continue
+		}
+
+		if len(c.ThreadID) == 0 {
+			index[c.RefID] = len(t)
+			t = append(t, comment.Thread{Comment: c, Replies: []comment.Comment{}})
+			continue
+		}
+
+		if i, ok := index[c.ThreadID]; ok {
+			t[i].Replies = append(t[i].Replies, c)
+		}
+	}
+
+	return
+}
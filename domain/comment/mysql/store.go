@@ -0,0 +1,258 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/comment"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// selectComment includes author names for display.
+const selectComment = `SELECT a.id, a.refid, a.orgid, a.documentid, a.pageid, a.threadid, a.userid,
+	coalesce(a.body,'') as body, coalesce(a.mentions,'') as mentions, coalesce(a.anchor,'') as anchor, a.anchorstart,
+	a.resolved, a.resolvedby, a.outdated, a.edited, a.created, a.revised,
+	coalesce(u.firstname,'') as firstname, coalesce(u.lastname,'') as lastname
+	FROM comment a LEFT JOIN user u ON a.userid=u.refid`
+
+// Add saves a new comment.
+func (s Scope) Add(ctx domain.RequestContext, c comment.Comment) (err error) {
+	c.OrgID = ctx.OrgID
+	c.UserID = ctx.UserID
+	c.Created = time.Now().UTC()
+	c.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO comment (refid, orgid, documentid, pageid, threadid, userid, body, mentions, anchor, anchorstart, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert comment")
+		return
+	}
+
+	_, err = stmt.Exec(c.RefID, c.OrgID, c.DocumentID, c.PageID, c.ThreadID, c.UserID, c.Body, c.Mentions, c.Anchor, c.AnchorStart, c.Created, c.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert comment")
+		return
+	}
+
+	return
+}
+
+// Get returns requested comment.
+func (s Scope) Get(ctx domain.RequestContext, id string) (c comment.Comment, err error) {
+	stmt, err := s.Runtime.Db.Preparex(selectComment + " WHERE a.orgid=? AND a.refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare select comment %s", id))
+		return
+	}
+
+	err = stmt.Get(&c, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute select comment %s", id))
+		return
+	}
+
+	return
+}
+
+// GetByDocument returns every comment on a document, oldest first.
+func (s Scope) GetByDocument(ctx domain.RequestContext, documentID string) (c []comment.Comment, err error) {
+	err = s.Runtime.Db.Select(&c, selectComment+" WHERE a.orgid=? AND a.documentid=? ORDER BY a.id", ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select comments for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// GetAnchored returns thread starting comments anchored to text in a section.
+func (s Scope) GetAnchored(ctx domain.RequestContext, pageID string) (c []comment.Comment, err error) {
+	err = s.Runtime.Db.Select(&c, selectComment+" WHERE a.orgid=? AND a.pageid=? AND a.threadid='' AND a.anchorstart>=0 ORDER BY a.id", ctx.OrgID, pageID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select anchored comments for page %s", pageID))
+		return
+	}
+
+	return
+}
+
+// Update changes comment body and mentions, flagging it as edited.
+func (s Scope) Update(ctx domain.RequestContext, c comment.Comment) (err error) {
+	c.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE comment SET body=?, mentions=?, edited=1, revised=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare update comment %s", c.RefID))
+		return
+	}
+
+	_, err = stmt.Exec(c.Body, c.Mentions, c.Revised, ctx.OrgID, c.RefID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute update comment %s", c.RefID))
+		return
+	}
+
+	return
+}
+
+// UpdateAnchor moves a comment anchor within its section.
+func (s Scope) UpdateAnchor(ctx domain.RequestContext, id string, start int, outdated bool) (err error) {
+	stmt, err := ctx.Transaction.Preparex("UPDATE comment SET anchorstart=?, outdated=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare update comment anchor %s", id))
+		return
+	}
+
+	_, err = stmt.Exec(start, outdated, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute update comment anchor %s", id))
+		return
+	}
+
+	return
+}
+
+// MarkPageOutdated flags every comment on a section as outdated.
+func (s Scope) MarkPageOutdated(ctx domain.RequestContext, pageID string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE comment SET outdated=1 WHERE orgid=? AND pageid=?", ctx.OrgID, pageID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("mark comments outdated for page %s", pageID))
+	}
+
+	return
+}
+
+// Resolve marks a thread resolved, or reopens it.
+func (s Scope) Resolve(ctx domain.RequestContext, id string, resolved bool) (err error) {
+	resolvedBy := ""
+	if resolved {
+		resolvedBy = ctx.UserID
+	}
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE comment SET resolved=?, resolvedby=?, revised=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare resolve comment %s", id))
+		return
+	}
+
+	_, err = stmt.Exec(resolved, resolvedBy, time.Now().UTC(), ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute resolve comment %s", id))
+		return
+	}
+
+	return
+}
+
+// AddRevision keeps the previous body of an edited comment.
+func (s Scope) AddRevision(ctx domain.RequestContext, r comment.Revision) (err error) {
+	r.OrgID = ctx.OrgID
+	r.Created = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO commentrevision (orgid, commentid, userid, body, created) VALUES (?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert comment revision")
+		return
+	}
+
+	_, err = stmt.Exec(r.OrgID, r.CommentID, r.UserID, r.Body, r.Created)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert comment revision")
+		return
+	}
+
+	return
+}
+
+// GetRevisions returns previous bodies of a comment, newest first.
+func (s Scope) GetRevisions(ctx domain.RequestContext, commentID string) (r []comment.Revision, err error) {
+	err = s.Runtime.Db.Select(&r, `SELECT a.id, a.orgid, a.commentid, a.userid, coalesce(a.body,'') as body, a.created,
+		coalesce(u.firstname,'') as firstname, coalesce(u.lastname,'') as lastname
+		FROM commentrevision a LEFT JOIN user u ON a.userid=u.refid
+		WHERE a.orgid=? AND a.commentid=? ORDER BY a.id DESC`, ctx.OrgID, commentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select revisions for comment %s", commentID))
+		return
+	}
+
+	return
+}
+
+// Delete removes a comment with its revisions, and replies when it starts a thread.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	_, err = ctx.Transaction.Exec("DELETE FROM commentrevision WHERE orgid=? AND commentid IN (SELECT refid FROM comment WHERE orgid=? AND (refid=? OR threadid=?))", ctx.OrgID, ctx.OrgID, id, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete revisions for comment %s", id))
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("DELETE FROM comment WHERE orgid=? AND threadid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete replies to comment %s", id))
+		return
+	}
+
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "comment", ctx.OrgID, id)
+}
+
+// Restore puts back a deleted comment exactly as it was.
+func (s Scope) Restore(ctx domain.RequestContext, c comment.Comment) (err error) {
+	_, err = ctx.Transaction.Exec(`INSERT INTO comment (refid, orgid, documentid, pageid, threadid, userid, body, mentions, anchor, anchorstart, resolved, resolvedby, outdated, edited, created, revised)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.RefID, ctx.OrgID, c.DocumentID, c.PageID, c.ThreadID, c.UserID, c.Body, c.Mentions, c.Anchor, c.AnchorStart,
+		c.Resolved, c.ResolvedBy, c.Outdated, c.Edited, c.Created, c.Revised)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("restore comment %s", c.RefID))
+		return
+	}
+
+	return
+}
+
+// RestoreRevision puts back a deleted comment revision exactly as it was.
+func (s Scope) RestoreRevision(ctx domain.RequestContext, r comment.Revision) (err error) {
+	_, err = ctx.Transaction.Exec("INSERT INTO commentrevision (orgid, commentid, userid, body, created) VALUES (?, ?, ?, ?, ?)",
+		ctx.OrgID, r.CommentID, r.UserID, r.Body, r.Created)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("restore revision for comment %s", r.CommentID))
+		return
+	}
+
+	return
+}
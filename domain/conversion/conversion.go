@@ -29,6 +29,7 @@ import (
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/conversion/store"
 	"github.com/documize/community/domain/document"
+	"github.com/documize/community/domain/watch"
 	"github.com/documize/community/model/activity"
 	"github.com/documize/community/model/attachment"
 	"github.com/documize/community/model/audit"
@@ -163,6 +164,7 @@ func processDocument(ctx domain.RequestContext, r *env.Runtime, store *domain.St
 	document.UserID = ctx.UserID
 	documentID := uniqueid.Generate()
 	document.RefID = documentID
+	document.Lifecycle = doc.LifecycleLive
 
 	err = store.Document.Add(ctx, document)
 	if err != nil {
@@ -171,6 +173,13 @@ func processDocument(ctx domain.RequestContext, r *env.Runtime, store *domain.St
 		return
 	}
 
+	err = watch.Author(ctx, *store, documentID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		err = errors.Wrap(err, "cannot subscribe author to new document")
+		return
+	}
+
 	for k, v := range fileResult.Pages {
 		var p page.Page
 		p.OrgID = ctx.OrgID
@@ -0,0 +1,79 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package datasource manages databases that document sections may query.
+package datasource
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/documize/community/core/secrets"
+	"github.com/documize/community/model/datasource"
+)
+
+// EncryptDSN prepares connection string for storage.
+func EncryptDSN(dsn string) (string, error) {
+	b, err := secrets.MakeAES(dsn)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secrets.EncodeBase64(b)), nil
+}
+
+// DecryptDSN reverses EncryptDSN.
+func DecryptDSN(encrypted string) (string, error) {
+	b, err := secrets.DecodeBase64([]byte(encrypted))
+	if err != nil {
+		return "", err
+	}
+
+	b, err = secrets.DecryptAES(b)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// ValidDriver reports whether a database/sql driver of that name is available.
+func ValidDriver(driver string) bool {
+	for _, d := range sql.Drivers() {
+		if d == driver {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Open returns a database handle for the data source.
+// Callers are responsible for closing it.
+func Open(d datasource.DataSource) (db *sql.DB, err error) {
+	if !ValidDriver(d.Driver) {
+		return nil, errors.New("unsupported driver " + d.Driver)
+	}
+
+	dsn, err := DecryptDSN(d.DSN)
+	if err != nil {
+		return
+	}
+
+	db, err = sql.Open(d.Driver, dsn)
+	if err != nil {
+		return
+	}
+
+	db.SetMaxOpenConns(1)
+
+	return
+}
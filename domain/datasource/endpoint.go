@@ -0,0 +1,286 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package datasource
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/datasource"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// Add registers a new data source. Only administrators can manage data sources.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	method := "datasource.add"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	d, ok := h.read(w, r, method)
+	if !ok {
+		return
+	}
+
+	if len(d.DSN) == 0 {
+		response.WriteMissingDataError(w, method, "dsn")
+		return
+	}
+
+	var err error
+	d.DSN, err = EncryptDSN(d.DSN)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	d.RefID = uniqueid.Generate()
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.DataSource.Add(ctx, d)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeDataSourceAdd)
+
+	ctx.Transaction.Commit()
+
+	d, err = h.Store.DataSource.Get(ctx, d.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	d.DSN = ""
+
+	response.WriteJSON(w, d)
+}
+
+// GetAll returns data sources defined for the organization.
+func (h *Handler) GetAll(w http.ResponseWriter, r *http.Request) {
+	method := "datasource.getAll"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	d, err := h.Store.DataSource.GetAll(ctx)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(d) == 0 {
+		d = []datasource.DataSource{}
+	}
+
+	response.WriteJSON(w, d)
+}
+
+// Get returns requested data source without connection details.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	method := "datasource.get"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	id := request.Param(r, "dataSourceID")
+	if len(id) == 0 {
+		response.WriteMissingDataError(w, method, "dataSourceID")
+		return
+	}
+
+	d, err := h.Store.DataSource.Get(ctx, id)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, id)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	d.DSN = ""
+
+	response.WriteJSON(w, d)
+}
+
+// Update changes data source settings.
+// Connection details are kept unless new ones are sent.
+func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
+	method := "datasource.update"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	id := request.Param(r, "dataSourceID")
+	if len(id) == 0 {
+		response.WriteMissingDataError(w, method, "dataSourceID")
+		return
+	}
+
+	d, ok := h.read(w, r, method)
+	if !ok {
+		return
+	}
+
+	d.RefID = id
+
+	var err error
+	if len(d.DSN) > 0 {
+		d.DSN, err = EncryptDSN(d.DSN)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.DataSource.Update(ctx, d)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeDataSourceUpdate)
+
+	ctx.Transaction.Commit()
+
+	response.WriteEmpty(w)
+}
+
+// Delete removes data source. Sections using it will show an error on next refresh.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	method := "datasource.delete"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	id := request.Param(r, "dataSourceID")
+	if len(id) == 0 {
+		response.WriteMissingDataError(w, method, "dataSourceID")
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.DataSource.Delete(ctx, id)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeDataSourceDelete)
+
+	ctx.Transaction.Commit()
+
+	response.WriteEmpty(w)
+}
+
+// read parses and validates data source sent by client.
+func (h *Handler) read(w http.ResponseWriter, r *http.Request, method string) (d datasource.DataSource, ok bool) {
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	err = json.Unmarshal(body, &d)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	d.Name = strings.TrimSpace(d.Name)
+	d.Driver = strings.TrimSpace(d.Driver)
+	d.DSN = strings.TrimSpace(d.DSN)
+
+	if len(d.Name) == 0 {
+		response.WriteMissingDataError(w, method, "name")
+		return
+	}
+	if !ValidDriver(d.Driver) {
+		response.WriteBadRequestError(w, method, "unsupported driver "+d.Driver)
+		return
+	}
+	if d.MaxRows <= 0 {
+		d.MaxRows = datasource.DefaultMaxRows
+	}
+	if d.Timeout <= 0 {
+		d.Timeout = datasource.DefaultTimeout
+	}
+
+	ok = true
+	return
+}
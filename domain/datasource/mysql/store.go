@@ -0,0 +1,110 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/datasource"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// Add saves data source definition, expecting DSN to be encrypted.
+func (s Scope) Add(ctx domain.RequestContext, d datasource.DataSource) (err error) {
+	d.OrgID = ctx.OrgID
+	d.UserID = ctx.UserID
+	d.Created = time.Now().UTC()
+	d.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO datasource (refid, orgid, userid, name, driver, dsn, maxrows, timeout, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert datasource")
+		return
+	}
+
+	_, err = stmt.Exec(d.RefID, d.OrgID, d.UserID, d.Name, d.Driver, d.DSN, d.MaxRows, d.Timeout, d.Created, d.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert datasource")
+		return
+	}
+
+	return
+}
+
+// Get returns requested data source including encrypted DSN.
+func (s Scope) Get(ctx domain.RequestContext, id string) (d datasource.DataSource, err error) {
+	stmt, err := s.Runtime.Db.Preparex("SELECT id, refid, orgid, userid, name, driver, dsn, maxrows, timeout, created, revised FROM datasource WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare select datasource")
+		return
+	}
+
+	err = stmt.Get(&d, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, "execute select datasource")
+		return
+	}
+
+	return
+}
+
+// GetAll returns data sources for the organization without DSN.
+func (s Scope) GetAll(ctx domain.RequestContext) (d []datasource.DataSource, err error) {
+	err = s.Runtime.Db.Select(&d, "SELECT id, refid, orgid, userid, name, driver, '' AS dsn, maxrows, timeout, created, revised FROM datasource WHERE orgid=? ORDER BY name", ctx.OrgID)
+
+	if err != nil {
+		err = errors.Wrap(err, "select datasources")
+		return
+	}
+
+	return
+}
+
+// Update changes data source settings.
+// DSN is only replaced when a new (encrypted) value is given.
+func (s Scope) Update(ctx domain.RequestContext, d datasource.DataSource) (err error) {
+	d.Revised = time.Now().UTC()
+
+	if len(d.DSN) > 0 {
+		_, err = ctx.Transaction.Exec("UPDATE datasource SET name=?, driver=?, dsn=?, maxrows=?, timeout=?, revised=? WHERE orgid=? AND refid=?",
+			d.Name, d.Driver, d.DSN, d.MaxRows, d.Timeout, d.Revised, ctx.OrgID, d.RefID)
+	} else {
+		_, err = ctx.Transaction.Exec("UPDATE datasource SET name=?, driver=?, maxrows=?, timeout=?, revised=? WHERE orgid=? AND refid=?",
+			d.Name, d.Driver, d.MaxRows, d.Timeout, d.Revised, ctx.OrgID, d.RefID)
+	}
+
+	if err != nil {
+		err = errors.Wrap(err, "execute update datasource")
+		return
+	}
+
+	return
+}
+
+// Delete removes data source definition.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "datasource", ctx.OrgID, id)
+}
@@ -0,0 +1,157 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/documize/community/model/datasource"
+)
+
+// leadingComments matches SQL comments before the first keyword.
+var leadingComments = regexp.MustCompile(`^(\s*(--[^\n]*\n|/\*(.|\n)*?\*/))*\s*`)
+
+// ValidateQuery rejects anything other than a single SELECT statement.
+// It is a first line of defence only: queries also run inside a read-only
+// transaction and data sources should use database accounts without write access.
+func ValidateQuery(query string) (string, error) {
+	q := strings.TrimSpace(query)
+	q = strings.TrimSpace(strings.TrimSuffix(q, ";"))
+
+	if len(q) == 0 {
+		return "", errors.New("query is empty")
+	}
+	if strings.Contains(q, ";") {
+		return "", errors.New("query must be a single statement")
+	}
+
+	body := strings.ToUpper(leadingComments.ReplaceAllString(q, ""))
+	if !strings.HasPrefix(body, "SELECT") && !strings.HasPrefix(body, "WITH") {
+		return "", errors.New("query must start with SELECT or WITH")
+	}
+
+	return q, nil
+}
+
+// Query runs a read-only query against the data source, returning at most
+// maxRows rows. Execution is abandoned once the data source timeout expires.
+func Query(d datasource.DataSource, query string, args []interface{}, maxRows int) (result datasource.Result, err error) {
+	query, err = ValidateQuery(query)
+	if err != nil {
+		return
+	}
+
+	if maxRows <= 0 || maxRows > d.MaxRows {
+		maxRows = d.MaxRows
+	}
+	if maxRows <= 0 {
+		maxRows = datasource.DefaultMaxRows
+	}
+
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = datasource.DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	db, err := Open(d)
+	if err != nil {
+		return
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// MySQL driver cannot start read-only transactions through TxOptions.
+	opts := &sql.TxOptions{ReadOnly: true}
+	if d.Driver == "mysql" {
+		_, err = conn.ExecContext(ctx, "SET SESSION TRANSACTION READ ONLY")
+		if err != nil {
+			return
+		}
+		opts.ReadOnly = false
+	}
+
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		return
+	}
+	defer tx.Rollback() // never commit
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	result.Columns, err = rows.Columns()
+	if err != nil {
+		return
+	}
+
+	result.Rows = [][]string{}
+
+	for rows.Next() {
+		if len(result.Rows) == maxRows {
+			result.Truncated = true
+			break
+		}
+
+		values := make([]interface{}, len(result.Columns))
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return
+		}
+
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = text(v)
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// text converts a scanned column value for display.
+func text(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	case time.Time:
+		return t.UTC().Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
@@ -14,8 +14,10 @@ package document
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/core/request"
@@ -23,12 +25,16 @@ import (
 	"github.com/documize/community/core/streamutil"
 	"github.com/documize/community/core/stringutil"
 	"github.com/documize/community/domain"
+	field "github.com/documize/community/domain/metadata"
 	indexer "github.com/documize/community/domain/search"
 	"github.com/documize/community/domain/space"
+	"github.com/documize/community/domain/tag"
+	"github.com/documize/community/domain/trash"
 	"github.com/documize/community/model/activity"
 	"github.com/documize/community/model/audit"
 	"github.com/documize/community/model/doc"
 	"github.com/documize/community/model/link"
+	"github.com/documize/community/model/metadata"
 	"github.com/documize/community/model/search"
 )
 
@@ -66,6 +72,12 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	values, err := h.Store.Metadata.GetValues(ctx, document.RefID)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+	}
+	document.Fields = field.Map(values)
+
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -83,6 +95,11 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		h.Runtime.Log.Error(method, err)
 	}
 
+	err = h.Store.Watch.UpdateLastViewed(ctx, "", document.RefID)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+	}
+
 	h.Store.Audit.Record(ctx, audit.EventTypeDocumentView)
 
 	ctx.Transaction.Commit()
@@ -153,18 +170,42 @@ func (h *Handler) BySpace(w http.ResponseWriter, r *http.Request) {
 	}
 
 	documents, err := h.Store.Document.GetBySpace(ctx, folderID)
+	documents = withoutArchived(r, documents)
 
-	if len(documents) == 0 {
-		documents = []doc.Document{}
+	if err != nil && err != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
 	}
 
-	if err != nil && err != sql.ErrNoRows {
+	values, err := h.Store.Metadata.GetSpaceValues(ctx, folderID)
+	if err != nil {
 		response.WriteServerError(w, method, err)
 		h.Runtime.Log.Error(method, err)
 		return
 	}
 
-	response.WriteJSON(w, documents)
+	// Filter on metadata fields given as ?field.name=value.
+	filters := make(map[string]string)
+	for key, v := range r.URL.Query() {
+		if strings.HasPrefix(key, "field.") && len(v) > 0 {
+			filters[strings.TrimPrefix(key, "field.")] = v[0]
+		}
+	}
+
+	byDocument := field.ByDocument(values)
+	filtered := []doc.Document{}
+
+	for _, d := range documents {
+		if !field.Matches(byDocument[d.RefID], filters) {
+			continue
+		}
+
+		d.Fields = field.Map(byDocument[d.RefID])
+		filtered = append(filtered, d)
+	}
+
+	response.WriteJSON(w, filtered)
 }
 
 // ByTag is an endpoint that returns the documents with a given tag.
@@ -179,6 +220,7 @@ func (h *Handler) ByTag(w http.ResponseWriter, r *http.Request) {
 	}
 
 	documents, err := h.Store.Document.GetByTag(ctx, tag)
+	documents = withoutArchived(r, documents)
 
 	if len(documents) == 0 {
 		documents = []doc.Document{}
@@ -233,6 +275,62 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 	d.RefID = documentID
 
+	current, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	// Spaces with a tag vocabulary only accept tags from it.
+	spaceID := d.LabelID
+	if len(spaceID) == 0 {
+		spaceID = current.LabelID
+	}
+	if d.Tags != current.Tags || spaceID != current.LabelID {
+		disallowed, err := tag.Disallowed(ctx, *h.Store, spaceID, d.Tags)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+		if len(disallowed) > 0 {
+			response.WriteBadRequestError(w, method, fmt.Sprintf("tag %s is not in the space vocabulary", disallowed[0]))
+			return
+		}
+	}
+
+	// Metadata values are replaced when supplied, and checked against the
+	// destination space's fields when the document changes space.
+	var values []metadata.Value
+	setValues := d.Fields != nil || spaceID != current.LabelID
+	if setValues {
+		fields, err := h.Store.Metadata.GetFields(ctx, spaceID)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		supplied := d.Fields
+		if supplied == nil {
+			existing, err := h.Store.Metadata.GetValues(ctx, documentID)
+			if err != nil {
+				response.WriteServerError(w, method, err)
+				h.Runtime.Log.Error(method, err)
+				return
+			}
+
+			supplied = field.Carry(field.Map(existing), fields)
+		}
+
+		values, err = field.Validate(ctx, *h.Store, fields, supplied)
+		if err != nil {
+			response.WriteBadRequestError(w, method, err.Error())
+			return
+		}
+	}
+
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -248,6 +346,16 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if setValues {
+		err = h.Store.Metadata.SetValues(ctx, documentID, values)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
 	h.Store.Audit.Record(ctx, audit.EventTypeDocumentUpdate)
 
 	ctx.Transaction.Commit()
@@ -288,6 +396,14 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = trash.Document(ctx, *h.Store, doc)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	_, err = h.Store.Document.Delete(ctx, documentID)
 	if err != nil {
 		ctx.Transaction.Rollback()
@@ -348,6 +464,15 @@ func (h *Handler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
 		h.Runtime.Log.Error(method, err)
 	}
 
+	if len(options.Fields) > 0 {
+		results, err = h.matchFields(ctx, results, options.Fields)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
 	// Put in slugs for easy UI display of search URL
 	for key, result := range results {
 		result.DocumentSlug = stringutil.MakeSlug(result.Document)
@@ -363,3 +488,31 @@ func (h *Handler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSON(w, results)
 }
+
+// matchFields keeps search results for documents whose metadata fields match.
+func (h *Handler) matchFields(ctx domain.RequestContext, results []search.QueryResult, filters map[string]string) (matched []search.QueryResult, err error) {
+	ids := []string{}
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if !seen[r.DocumentID] {
+			seen[r.DocumentID] = true
+			ids = append(ids, r.DocumentID)
+		}
+	}
+
+	values, err := h.Store.Metadata.GetDocumentsValues(ctx, ids)
+	if err != nil {
+		return
+	}
+
+	byDocument := field.ByDocument(values)
+	matched = []search.QueryResult{}
+
+	for _, r := range results {
+		if field.Matches(byDocument[r.DocumentID], filters) {
+			matched = append(matched, r)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,234 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package document
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/doc"
+)
+
+// LifecycleRequest asks for documents to be moved to a lifecycle state.
+type LifecycleRequest struct {
+	Lifecycle doc.Lifecycle `json:"lifecycle"`
+	Documents []string      `json:"documents"` // used by bulk archive, empty means every document in the space
+}
+
+// SetLifecycle moves a document between draft, live and archived.
+// Space editors can do this even for archived documents, which are
+// otherwise read-only.
+func (h *Handler) SetLifecycle(w http.ResponseWriter, r *http.Request) {
+	method := "document.lifecycle"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := LifecycleRequest{}
+	err = json.Unmarshal(body, &model)
+	if err != nil || !model.Lifecycle.Valid() {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if model.Lifecycle == doc.LifecycleLive && d.Lifecycle != doc.LifecycleLive && d.Approvals > 0 {
+		var approved int
+		approved, err = h.Store.Action.CountApprovals(ctx, d.RefID)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+		if approved < d.Approvals {
+			ctx.Transaction.Rollback()
+			response.WriteBadRequestError(w, method, fmt.Sprintf("document needs %d approvals, has %d", d.Approvals, approved))
+			return
+		}
+	}
+
+	err = ChangeLifecycle(ctx, *h.Store, d, model.Lifecycle)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	d.Lifecycle = model.Lifecycle
+
+	response.WriteJSON(w, d)
+}
+
+// ArchiveSpace archives documents in a space, either those listed or all of them.
+func (h *Handler) ArchiveSpace(w http.ResponseWriter, r *http.Request) {
+	method := "document.archiveSpace"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, spaceID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := LifecycleRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &model)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "Bad payload")
+			return
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range model.Documents {
+		selected[id] = true
+	}
+
+	documents, err := h.Store.Document.GetBySpace(ctx, spaceID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	archived := []doc.Document{}
+	for _, d := range documents {
+		if d.Lifecycle == doc.LifecycleArchived || (len(selected) > 0 && !selected[d.RefID]) {
+			continue
+		}
+
+		err = ChangeLifecycle(ctx, *h.Store, d, doc.LifecycleArchived)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		d.Lifecycle = doc.LifecycleArchived
+		archived = append(archived, d)
+	}
+
+	ctx.Transaction.Commit()
+
+	response.WriteJSON(w, archived)
+}
+
+// ChangeLifecycle updates the document state within ctx.Transaction, recording who did it.
+func ChangeLifecycle(ctx domain.RequestContext, s domain.Store, d doc.Document, lifecycle doc.Lifecycle) (err error) {
+	if d.Lifecycle == lifecycle {
+		return
+	}
+
+	err = s.Document.UpdateLifecycle(ctx, d.RefID, lifecycle)
+	if err != nil {
+		return
+	}
+
+	activityType := activity.TypeArchived
+	eventType := audit.EventTypeDocumentArchive
+
+	switch lifecycle {
+	case doc.LifecycleDraft:
+		activityType = activity.TypeDraft
+		eventType = audit.EventTypeDocumentDraft
+	case doc.LifecycleLive:
+		activityType = activity.TypePublished
+		eventType = audit.EventTypeDocumentPublish
+	}
+
+	s.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      d.LabelID,
+		SourceID:     d.RefID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activityType})
+
+	s.Audit.Record(ctx, eventType)
+
+	return
+}
+
+// withoutArchived drops archived documents unless the request asks for them with archived=true.
+func withoutArchived(r *http.Request, documents []doc.Document) []doc.Document {
+	if request.Query(r, "archived") == "true" {
+		return documents
+	}
+
+	filtered := []doc.Document{}
+	for _, d := range documents {
+		if d.Lifecycle != doc.LifecycleArchived {
+			filtered = append(filtered, d)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,665 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package document
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/documize/community/core/event"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/secrets"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	field "github.com/documize/community/domain/metadata"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/domain/tag"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/metadata"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/watch"
+	uuid "github.com/nu7hatch/gouuid"
+	"github.com/pkg/errors"
+)
+
+// MoveRequest lists documents to move between spaces.
+type MoveRequest struct {
+	Documents []string `json:"documents"` // empty means every document in the space
+}
+
+// DuplicateRequest controls how a document is copied.
+type DuplicateRequest struct {
+	Title     string `json:"title"`     // defaults to the original title
+	Revisions bool   `json:"revisions"` // also copy section revision history
+}
+
+// Move puts a single document into another space.
+func (h *Handler) Move(w http.ResponseWriter, r *http.Request) {
+	method := "document.move"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID) || !CanUploadDocument(ctx, *h.Store, spaceID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	values, ok := h.carryFields(w, ctx, method, d, spaceID)
+	if !ok {
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = moveDocument(ctx, *h.Store, &d, spaceID, values)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeDocumentMove)
+
+	d.LabelID = spaceID
+	a, _ := h.Store.Attachment.GetAttachments(ctx, d.RefID)
+	go h.Indexer.IndexDocument(ctx, d, a)
+
+	response.WriteJSON(w, d)
+}
+
+// MoveSpace puts documents from one space into another, either those listed or all of them.
+func (h *Handler) MoveSpace(w http.ResponseWriter, r *http.Request) {
+	method := "document.moveSpace"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	targetID := request.Param(r, "moveToId")
+	if len(targetID) == 0 {
+		response.WriteMissingDataError(w, method, "moveToId")
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, spaceID) || !CanUploadDocument(ctx, *h.Store, targetID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := MoveRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &model)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "Bad payload")
+			return
+		}
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range model.Documents {
+		selected[id] = true
+	}
+
+	documents, err := h.Store.Document.GetBySpace(ctx, spaceID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	values := make(map[string][]metadata.Value)
+	for _, d := range documents {
+		if len(selected) > 0 && !selected[d.RefID] {
+			continue
+		}
+
+		v, ok := h.carryFields(w, ctx, method, d, targetID)
+		if !ok {
+			return
+		}
+		values[d.RefID] = v
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	moved := []doc.Document{}
+	for _, d := range documents {
+		if len(selected) > 0 && !selected[d.RefID] {
+			continue
+		}
+
+		err = moveDocument(ctx, *h.Store, &d, targetID, values[d.RefID])
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		d.LabelID = targetID
+		moved = append(moved, d)
+	}
+
+	ctx.Transaction.Commit()
+
+	for _, d := range moved {
+		h.Store.Audit.Record(ctx, audit.EventTypeDocumentMove)
+
+		a, _ := h.Store.Attachment.GetAttachments(ctx, d.RefID)
+		go h.Indexer.IndexDocument(ctx, d, a)
+	}
+
+	response.WriteJSON(w, moved)
+}
+
+// Duplicate copies a document with its sections, meta and attachments
+// into a space, optionally bringing section revisions along.
+func (h *Handler) Duplicate(w http.ResponseWriter, r *http.Request) {
+	method := "document.duplicate"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !CanViewDocument(ctx, *h.Store, documentID) || !CanUploadDocument(ctx, *h.Store, spaceID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := DuplicateRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &model)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "Bad payload")
+			return
+		}
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	values, ok := h.carryFields(w, ctx, method, d, spaceID)
+	if !ok {
+		return
+	}
+
+	pages, err := h.Store.Page.GetPages(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	attachments, err := h.Store.Attachment.GetAttachmentsWithData(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	links, err := h.Store.Link.GetDocumentOutboundLinks(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	// new identities, so links between sections of the copy stay inside the copy
+	newDocumentID := uniqueid.Generate()
+	pageIDs := make(map[string]string)
+	for _, p := range pages {
+		pageIDs[p.RefID] = uniqueid.Generate()
+	}
+	linkIDs := make(map[string]string)
+	for _, l := range links {
+		linkIDs[l.RefID] = uniqueid.Generate()
+	}
+
+	relink := func(attrs map[string]string) {
+		if id, ok := linkIDs[attrs["data-link-id"]]; ok {
+			attrs["data-link-id"] = id
+		}
+		if attrs["data-link-target-document-id"] == documentID {
+			attrs["data-link-target-document-id"] = newDocumentID
+			attrs["data-link-space-id"] = spaceID
+			if id, ok := pageIDs[attrs["data-link-target-id"]]; ok {
+				attrs["data-link-target-id"] = id
+			}
+		}
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	nd := duplicate(d, newDocumentID, spaceID, ctx.UserID, model.Title)
+
+	// tags outside the space's vocabulary are not copied
+	nd.Tags, err = tag.Fit(ctx, *h.Store, spaceID, nd.Tags)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Document.Add(ctx, nd)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Metadata.SetValues(ctx, newDocumentID, values)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	// authors are subscribed to changes in their documents
+	err = h.Store.Watch.Add(ctx, watch.Watcher{RefID: uniqueid.Generate(), DocumentID: newDocumentID,
+		UserID: ctx.UserID, RoleType: watch.RoleAuthor, AppURL: ctx.GetAppURL("")})
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	copies := []page.Page{}
+	for _, p := range pages {
+		meta, err2 := h.Store.Page.GetPageMeta(ctx, p.RefID)
+		if err2 != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err2)
+			h.Runtime.Log.Error(method, err2)
+			return
+		}
+
+		oldPageID := p.RefID
+
+		p.RefID = pageIDs[oldPageID]
+		p.DocumentID = newDocumentID
+		p.Body = rewriteLinks(p.Body, relink)
+		if !model.Revisions {
+			p.Revisions = 0
+		}
+
+		meta.PageID = p.RefID
+		meta.DocumentID = newDocumentID
+		meta.RawBody = rewriteLinks(meta.RawBody, relink)
+
+		err = h.Store.Page.Add(ctx, page.NewPage{Page: p, Meta: meta})
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		if model.Revisions {
+			err = copyRevisions(ctx, *h.Store, oldPageID, p)
+			if err != nil {
+				ctx.Transaction.Rollback()
+				response.WriteServerError(w, method, err)
+				h.Runtime.Log.Error(method, err)
+				return
+			}
+		}
+
+		copies = append(copies, p)
+	}
+
+	for _, l := range links {
+		l.RefID = linkIDs[l.RefID]
+		l.UserID = ctx.UserID
+		l.SourceDocumentID = newDocumentID
+		l.SourcePageID = pageIDs[l.SourcePageID]
+		if l.TargetDocumentID == documentID {
+			l.TargetDocumentID = newDocumentID
+			l.FolderID = spaceID
+			if id, ok := pageIDs[l.TargetID]; ok {
+				l.TargetID = id
+			}
+		}
+
+		err = h.Store.Link.Add(ctx, l)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	newUUID, _ := uuid.NewV4()
+
+	for _, a := range attachments {
+		a.DocumentID = newDocumentID
+		a.Job = newUUID.String()
+		random := secrets.GenerateSalt()
+		a.FileID = random[0:9]
+		a.RefID = uniqueid.Generate()
+
+		err = h.Store.Attachment.Add(ctx, a)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeDocumentDuplicate)
+
+	nd, err = h.Store.Document.Get(ctx, newDocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	event.Handler().Publish(string(event.TypeAddDocument), nd.Title)
+
+	a, _ := h.Store.Attachment.GetAttachments(ctx, newDocumentID)
+	go h.Indexer.IndexDocument(ctx, nd, a)
+
+	for _, p := range copies {
+		go h.Indexer.IndexContent(ctx, p)
+	}
+
+	response.WriteJSON(w, nd)
+}
+
+// moveDocument changes the space of a document within ctx.Transaction,
+// carrying pins and links that point at the document along with it.
+// Metadata values are replaced with those prepared by carryFields.
+func moveDocument(ctx domain.RequestContext, s domain.Store, d *doc.Document, spaceID string, values []metadata.Value) (err error) {
+	if d.LabelID == spaceID {
+		return
+	}
+
+	err = s.Document.ChangeDocumentSpace(ctx, d.RefID, spaceID)
+	if err != nil {
+		return
+	}
+
+	err = s.Metadata.SetValues(ctx, d.RefID, values)
+	if err != nil {
+		return
+	}
+
+	// tags outside the new space's vocabulary are dropped
+	tags, err := tag.Fit(ctx, s, spaceID, d.Tags)
+	if err != nil {
+		return
+	}
+	if tags != d.Tags {
+		err = s.Tag.UpdateDocumentTags(ctx, d.RefID, tags)
+		if err != nil {
+			return
+		}
+		d.Tags = tags
+	}
+
+	pins, err := s.Pin.GetDocumentPins(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	for _, p := range pins {
+		p.FolderID = spaceID
+		err = s.Pin.UpdatePin(ctx, p)
+		if err != nil {
+			return
+		}
+	}
+
+	inbound, err := s.Link.GetDocumentInboundLinks(ctx, d.RefID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select links into document %s", d.RefID))
+		return
+	}
+
+	err = s.Link.UpdateTargetSpace(ctx, d.RefID, spaceID)
+	if err != nil {
+		return
+	}
+
+	// links in section content also name the space
+	repoint := func(attrs map[string]string) {
+		if attrs["data-link-target-document-id"] == d.RefID {
+			attrs["data-link-space-id"] = spaceID
+		}
+	}
+
+	done := make(map[string]bool)
+	for _, l := range inbound {
+		if done[l.SourcePageID] {
+			continue
+		}
+		done[l.SourcePageID] = true
+
+		var p page.Page
+		p, err = s.Page.Get(ctx, l.SourcePageID)
+		if errors.Cause(err) == sql.ErrNoRows {
+			err = nil
+			continue
+		}
+		if err != nil {
+			return
+		}
+
+		p.Body = rewriteLinks(p.Body, repoint)
+
+		err = s.Page.Update(ctx, p, "", "", true)
+		if err != nil {
+			return
+		}
+
+		var meta page.Meta
+		meta, err = s.Page.GetPageMeta(ctx, p.RefID)
+		if err != nil {
+			return
+		}
+
+		meta.RawBody = rewriteLinks(meta.RawBody, repoint)
+
+		err = s.Page.UpdateMeta(ctx, meta, false)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// copyRevisions gives the copied page p the revision history of the page it was copied from.
+func copyRevisions(ctx domain.RequestContext, s domain.Store, pageID string, p page.Page) (err error) {
+	revisions, err := s.Page.GetPageRevisions(ctx, pageID)
+	if err != nil {
+		return
+	}
+
+	// revisions come newest first
+	for i := len(revisions) - 1; i >= 0; i-- {
+		var rev page.Revision
+		rev, err = s.Page.GetPageRevision(ctx, revisions[i].RefID)
+		if err != nil {
+			return
+		}
+
+		rev.RefID = uniqueid.Generate()
+		rev.DocumentID = p.DocumentID
+		rev.PageID = p.RefID
+
+		err = s.Page.AddRevision(ctx, rev)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// contentLink matches the opening tag of links to documents, sections and attachments.
+var contentLink = regexp.MustCompile(`<a\s[^>]*data-documize=['"]true['"][^>]*>`)
+
+// linkAttr matches one data-link-* attribute of a content link.
+var linkAttr = regexp.MustCompile(`(data-link-[a-z-]+)=(['"])([^'"]*)(['"])`)
+
+// rewriteLinks lets change edit the data-link-* attributes of every content link in body.
+func rewriteLinks(body string, change func(attrs map[string]string)) string {
+	return contentLink.ReplaceAllStringFunc(body, func(tag string) string {
+		attrs := make(map[string]string)
+		for _, m := range linkAttr.FindAllStringSubmatch(tag, -1) {
+			attrs[m[1]] = m[3]
+		}
+
+		change(attrs)
+
+		return linkAttr.ReplaceAllStringFunc(tag, func(a string) string {
+			m := linkAttr.FindStringSubmatch(a)
+			return m[1] + "=" + m[2] + attrs[m[1]] + m[4]
+		})
+	})
+}
+
+// duplicate returns a copy of a document under a new identity. The copy
+// starts live with no approvals required, whatever state the original is in.
+func duplicate(d doc.Document, documentID, spaceID, userID, title string) doc.Document {
+	nd := d
+	nd.RefID = documentID
+	nd.LabelID = spaceID
+	nd.UserID = userID
+	nd.Template = false
+	nd.Lifecycle = doc.LifecycleLive
+	nd.Approvals = 0
+	if len(strings.TrimSpace(title)) > 0 {
+		nd.Title = strings.TrimSpace(title)
+	}
+
+	return nd
+}
+
+// carryFields returns a document's metadata values as they are to be
+// stored in spaceID, keeping values for fields of the same name there
+// and checking them against that space's fields. Failures are written
+// to w.
+func (h *Handler) carryFields(w http.ResponseWriter, ctx domain.RequestContext, method string, d doc.Document, spaceID string) (values []metadata.Value, ok bool) {
+	existing, err := h.Store.Metadata.GetValues(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if d.LabelID == spaceID {
+		return existing, true
+	}
+
+	fields, err := h.Store.Metadata.GetFields(ctx, spaceID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	values, err = field.Validate(ctx, *h.Store, fields, field.Carry(field.Map(existing), fields))
+	if err != nil {
+		response.WriteBadRequestError(w, method, fmt.Sprintf("%s: %s", d.Title, err.Error()))
+		return
+	}
+
+	return values, true
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package document
+
+import (
+	"testing"
+
+	"github.com/documize/community/model/doc"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	repoint := func(attrs map[string]string) {
+		if attrs["data-link-target-document-id"] == "doc1" {
+			attrs["data-link-space-id"] = "space2"
+		}
+	}
+
+	tests := []struct {
+		body string
+		want string
+	}{
+		{
+			`<p><a data-documize='true' data-link-space-id='space1' data-link-id='l1' data-link-target-document-id='doc1' data-link-target-id='p1' data-link-type='section' href='/link/section/l1'>Intro</a></p>`,
+			`<p><a data-documize='true' data-link-space-id='space2' data-link-id='l1' data-link-target-document-id='doc1' data-link-target-id='p1' data-link-type='section' href='/link/section/l1'>Intro</a></p>`,
+		},
+		{
+			`<a data-documize="true" data-link-space-id="space1" data-link-target-document-id="doc1">x</a>`,
+			`<a data-documize="true" data-link-space-id="space2" data-link-target-document-id="doc1">x</a>`,
+		},
+		{
+			`<a data-documize='true' data-link-space-id='space1' data-link-target-document-id='doc9'>other</a>`,
+			`<a data-documize='true' data-link-space-id='space1' data-link-target-document-id='doc9'>other</a>`,
+		},
+		{
+			`<a data-link-space-id='space1' data-link-target-document-id='doc1'>not generated by us</a>`,
+			`<a data-link-space-id='space1' data-link-target-document-id='doc1'>not generated by us</a>`,
+		},
+	}
+
+	for _, test := range tests {
+		got := rewriteLinks(test.body, repoint)
+		if got != test.want {
+			t.Errorf("rewriteLinks(%q) = %q, want %q", test.body, got, test.want)
+		}
+	}
+}
+
+func TestDuplicate(t *testing.T) {
+	d := doc.Document{Title: "Runbook", LabelID: "space1", UserID: "user1", Template: true, Lifecycle: doc.LifecycleArchived, Approvals: 2}
+	d.RefID = "doc1"
+
+	nd := duplicate(d, "doc2", "space2", "user2", " ")
+	if nd.RefID != "doc2" || nd.LabelID != "space2" || nd.UserID != "user2" || nd.Title != "Runbook" {
+		t.Errorf("unexpected copy %+v", nd)
+	}
+	if nd.Template || nd.Lifecycle != doc.LifecycleLive || nd.Approvals != 0 {
+		t.Errorf("expected a live document needing no approvals, got template %v lifecycle %d approvals %d", nd.Template, nd.Lifecycle, nd.Approvals)
+	}
+	if d.Lifecycle != doc.LifecycleArchived || d.RefID != "doc1" {
+		t.Error("original document changed")
+	}
+
+	d.Lifecycle = doc.LifecycleDraft
+	if nd = duplicate(d, "doc3", "space1", "user1", "Copy"); nd.Lifecycle != doc.LifecycleLive || nd.Title != "Copy" {
+		t.Errorf("unexpected copy of draft %+v", nd)
+	}
+}
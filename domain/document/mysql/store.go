@@ -30,12 +30,13 @@ type Scope struct {
 }
 
 // Add inserts the given document record into the document table and audits that it has been done.
+// Callers set the lifecycle: its zero value is draft, whereas the column defaults to live.
 func (s Scope) Add(ctx domain.RequestContext, document doc.Document) (err error) {
 	document.OrgID = ctx.OrgID
 	document.Created = time.Now().UTC()
 	document.Revised = document.Created // put same time in both fields
 
-	stmt, err := ctx.Transaction.Preparex("INSERT INTO document (refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO document (refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, lifecycle, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -43,7 +44,7 @@ func (s Scope) Add(ctx domain.RequestContext, document doc.Document) (err error)
 		return
 	}
 
-	_, err = stmt.Exec(document.RefID, document.OrgID, document.LabelID, document.UserID, document.Job, document.Location, document.Title, document.Excerpt, document.Slug, document.Tags, document.Template, document.Created, document.Revised)
+	_, err = stmt.Exec(document.RefID, document.OrgID, document.LabelID, document.UserID, document.Job, document.Location, document.Title, document.Excerpt, document.Slug, document.Tags, document.Template, document.Lifecycle, document.Created, document.Revised)
 
 	if err != nil {
 		err = errors.Wrap(err, "execuet insert document")
@@ -55,7 +56,7 @@ func (s Scope) Add(ctx domain.RequestContext, document doc.Document) (err error)
 
 // Get fetches the document record with the given id fromt the document table and audits that it has been got.
 func (s Scope) Get(ctx domain.RequestContext, id string) (document doc.Document, err error) {
-	stmt, err := s.Runtime.Db.Preparex("SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? and refid=?")
+	stmt, err := s.Runtime.Db.Preparex("SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? and refid=?")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -108,7 +109,7 @@ func (s Scope) DocumentMeta(ctx domain.RequestContext, id string) (meta doc.Docu
 
 // GetAll returns a slice containg all of the the documents for the client's organisation, with the most recient first.
 func (s Scope) GetAll() (ctx domain.RequestContext, documents []doc.Document, err error) {
-	err = s.Runtime.Db.Select(&documents, "SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND template=0 ORDER BY revised DESC", ctx.OrgID)
+	err = s.Runtime.Db.Select(&documents, "SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND template=0 ORDER BY revised DESC", ctx.OrgID)
 
 	if err != nil {
 		err = errors.Wrap(err, "select documents")
@@ -120,7 +121,7 @@ func (s Scope) GetAll() (ctx domain.RequestContext, documents []doc.Document, er
 
 // GetBySpace returns a slice containing the documents for a given space, most recient first.
 func (s Scope) GetBySpace(ctx domain.RequestContext, folderID string) (documents []doc.Document, err error) {
-	err = s.Runtime.Db.Select(&documents, "SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND template=0 AND labelid=? ORDER BY revised DESC", ctx.OrgID, folderID)
+	err = s.Runtime.Db.Select(&documents, "SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND template=0 AND labelid=? ORDER BY revised DESC", ctx.OrgID, folderID)
 
 	if err != nil {
 		err = errors.Wrap(err, "select documents by space")
@@ -135,7 +136,7 @@ func (s Scope) GetByTag(ctx domain.RequestContext, tag string) (documents []doc.
 	tagQuery := "tags LIKE '%#" + tag + "#%'"
 
 	err = s.Runtime.Db.Select(&documents,
-		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND template=0 AND `+tagQuery+` AND labelid IN
+		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND template=0 AND `+tagQuery+` AND labelid IN
 		(SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
     	UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 		UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
@@ -160,7 +161,7 @@ func (s Scope) GetByTag(ctx domain.RequestContext, tag string) (documents []doc.
 // Templates returns a slice containing the documents available as templates to the client's organisation, in title order.
 func (s Scope) Templates(ctx domain.RequestContext) (documents []doc.Document, err error) {
 	err = s.Runtime.Db.Select(&documents,
-		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND template=1 AND labelid IN
+		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND template=1 AND labelid IN
 		(SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
     	UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 		UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
@@ -185,7 +186,7 @@ func (s Scope) Templates(ctx domain.RequestContext) (documents []doc.Document, e
 // TemplatesBySpace returns a slice containing the documents available as templates for given space.
 func (s Scope) TemplatesBySpace(ctx domain.RequestContext, spaceID string) (documents []doc.Document, err error) {
 	err = s.Runtime.Db.Select(&documents,
-		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND labelid=? AND template=1 AND labelid IN
+		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND labelid=? AND template=1 AND labelid IN
 		(SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
     	UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 		UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
@@ -233,7 +234,7 @@ func (s Scope) PublicDocuments(ctx domain.RequestContext, orgID string) (documen
 // DocumentList returns a slice containing the documents available as templates to the client's organisation, in title order.
 func (s Scope) DocumentList(ctx domain.RequestContext) (documents []doc.Document, err error) {
 	err = s.Runtime.Db.Select(&documents,
-		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, created, revised FROM document WHERE orgid=? AND template=0 AND labelid IN
+		`SELECT id, refid, orgid, labelid, userid, job, location, title, excerpt, slug, tags, template, layout, lifecycle, approvals, created, revised FROM document WHERE orgid=? AND template=0 AND labelid IN
 		(SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
     	UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 		UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
@@ -282,6 +283,48 @@ func (s Scope) Update(ctx domain.RequestContext, document doc.Document) (err err
 	return
 }
 
+// UpdateLifecycle moves the document to another lifecycle state.
+func (s Scope) UpdateLifecycle(ctx domain.RequestContext, documentID string, lifecycle doc.Lifecycle) (err error) {
+	revised := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE document SET lifecycle=?, revised=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare change document lifecycle %s", documentID))
+		return
+	}
+
+	_, err = stmt.Exec(lifecycle, revised, ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute change document lifecycle %s", documentID))
+		return
+	}
+
+	return
+}
+
+// UpdateApprovals sets how many approvals the document needs before it can go live.
+func (s Scope) UpdateApprovals(ctx domain.RequestContext, documentID string, approvals int) (err error) {
+	stmt, err := ctx.Transaction.Preparex("UPDATE document SET approvals=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("prepare change document approvals %s", documentID))
+		return
+	}
+
+	_, err = stmt.Exec(approvals, ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute change document approvals %s", documentID))
+		return
+	}
+
+	return
+}
+
 // ChangeDocumentSpace assigns the specified space to the document.
 func (s Scope) ChangeDocumentSpace(ctx domain.RequestContext, document, space string) (err error) {
 	revised := time.Now().UTC()
@@ -343,5 +386,20 @@ func (s Scope) Delete(ctx domain.RequestContext, documentID string) (rows int64,
 		return
 	}
 
+	_, err = b.DeleteWhere(ctx.Transaction, fmt.Sprintf("DELETE from documentfield WHERE documentid=\"%s\" AND orgid=\"%s\"", documentID, ctx.OrgID))
+	if err != nil {
+		return
+	}
+
+	_, err = b.DeleteWhere(ctx.Transaction, fmt.Sprintf("DELETE from commentrevision WHERE orgid=\"%s\" AND commentid IN (SELECT refid FROM comment WHERE documentid=\"%s\" AND orgid=\"%s\")", ctx.OrgID, documentID, ctx.OrgID))
+	if err != nil {
+		return
+	}
+
+	_, err = b.DeleteWhere(ctx.Transaction, fmt.Sprintf("DELETE from comment WHERE documentid=\"%s\" AND orgid=\"%s\"", documentID, ctx.OrgID))
+	if err != nil {
+		return
+	}
+
 	return b.DeleteConstrained(ctx.Transaction, "document", ctx.OrgID, documentID)
 }
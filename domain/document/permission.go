@@ -15,6 +15,7 @@ import (
 	"database/sql"
 
 	"github.com/documize/community/domain"
+	"github.com/documize/community/model/doc"
 )
 
 // CanViewDocumentInFolder returns if the user has permission to view a document within the specified folder.
@@ -67,6 +68,7 @@ func CanViewDocument(ctx domain.RequestContext, s domain.Store, documentID strin
 }
 
 // CanChangeDocument returns if the clinet has permission to change a given document.
+// Archived documents are read-only.
 func CanChangeDocument(ctx domain.RequestContext, s domain.Store, documentID string) (hasPermission bool) {
 	document, err := s.Document.Get(ctx, documentID)
 
@@ -77,6 +79,10 @@ func CanChangeDocument(ctx domain.RequestContext, s domain.Store, documentID str
 		return false
 	}
 
+	if document.Lifecycle == doc.LifecycleArchived {
+		return false
+	}
+
 	roles, err := s.Space.GetUserRoles(ctx)
 
 	if err == sql.ErrNoRows {
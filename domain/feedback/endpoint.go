@@ -0,0 +1,212 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package feedback
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/feedback"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// maxFeedback caps the length of a reader comment.
+const maxFeedback = 4000
+
+// Add records whether a reader found a document, or one of its sections, helpful.
+// Anonymous readers can leave feedback when the organization allows anonymous access.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	method := "feedback.add"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	f := feedback.Feedback{}
+	err = json.Unmarshal(body, &f)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	f.Feedback = strings.TrimSpace(f.Feedback)
+	if len(f.Feedback) > maxFeedback {
+		response.WriteBadRequestError(w, method, "feedback too long")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(f.PageID) > 0 {
+		p, err := h.Store.Page.Get(ctx, f.PageID)
+		if err != nil || p.DocumentID != documentID {
+			response.WriteBadRequestError(w, method, "section not in document")
+			return
+		}
+		f.PageTitle = p.Title
+	}
+
+	f.RefID = uniqueid.Generate()
+	f.DocumentID = documentID
+
+	if ctx.Guest {
+		f.UserID = ""
+		f.Email = strings.TrimSpace(f.Email)
+	} else {
+		u, err := h.Store.User.Get(ctx, ctx.UserID)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+		f.UserID = u.RefID
+		f.Email = u.Email
+		f.Firstname = u.Firstname
+		f.Lastname = u.Lastname
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Feedback.Add(ctx, f)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      d.LabelID,
+		SourceID:     d.RefID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activity.TypeFeedback})
+
+	ctx.Transaction.Commit()
+
+	f.OrgID = ctx.OrgID
+
+	response.WriteJSON(w, f)
+}
+
+// GetByDocument lists feedback for a document, newest first, for those who can edit it.
+func (h *Handler) GetByDocument(w http.ResponseWriter, r *http.Request) {
+	method := "feedback.getByDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	f, err := h.Store.Feedback.GetByDocument(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(f) == 0 {
+		f = []feedback.Feedback{}
+	}
+
+	response.WriteJSON(w, f)
+}
+
+// GetSpaceSummary totals feedback across a space so administrators
+// can find the documents and sections readers struggle with.
+func (h *Handler) GetSpaceSummary(w http.ResponseWriter, r *http.Request) {
+	method := "feedback.getSpaceSummary"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !ctx.Administrator {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	_, err := h.Store.Space.Get(ctx, spaceID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, spaceID)
+		return
+	}
+
+	f, err := h.Store.Feedback.GetSpaceSummary(ctx, spaceID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(f) == 0 {
+		f = []feedback.Summary{}
+	}
+
+	response.WriteJSON(w, f)
+}
@@ -0,0 +1,87 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/feedback"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// Add records reader feedback.
+func (s Scope) Add(ctx domain.RequestContext, f feedback.Feedback) (err error) {
+	f.OrgID = ctx.OrgID
+	f.Created = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO feedback (refid, orgid, documentid, pageid, userid, email, helpful, feedback, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert feedback")
+		return
+	}
+
+	_, err = stmt.Exec(f.RefID, f.OrgID, f.DocumentID, f.PageID, f.UserID, f.Email, f.Helpful, f.Feedback, f.Created)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert feedback")
+		return
+	}
+
+	return
+}
+
+// GetByDocument returns feedback for a document and its sections, newest first.
+func (s Scope) GetByDocument(ctx domain.RequestContext, documentID string) (f []feedback.Feedback, err error) {
+	err = s.Runtime.Db.Select(&f, `SELECT a.id, a.refid, a.orgid, a.documentid, a.pageid, coalesce(a.userid,'') as userid, a.email, a.helpful, coalesce(a.feedback,'') as feedback, a.created,
+		coalesce(p.title,'') as pagetitle, coalesce(u.firstname,'') as firstname, coalesce(u.lastname,'') as lastname
+		FROM feedback a
+		LEFT JOIN page p ON a.pageid=p.refid AND a.orgid=p.orgid
+		LEFT JOIN user u ON a.userid=u.refid
+		WHERE a.orgid=? AND a.documentid=? ORDER BY a.id DESC`, ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select feedback for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// GetSpaceSummary totals feedback for every rated document and section in a space,
+// least helpful first.
+func (s Scope) GetSpaceSummary(ctx domain.RequestContext, spaceID string) (f []feedback.Summary, err error) {
+	err = s.Runtime.Db.Select(&f, `SELECT a.documentid, d.title as documenttitle, a.pageid, coalesce(p.title,'') as pagetitle,
+		SUM(a.helpful=1) as helpful, SUM(a.helpful=0) as nothelpful, SUM(coalesce(a.feedback,'')<>'') as comments
+		FROM feedback a
+		JOIN document d ON a.documentid=d.refid AND a.orgid=d.orgid
+		LEFT JOIN page p ON a.pageid=p.refid AND a.orgid=p.orgid
+		WHERE a.orgid=? AND d.labelid=?
+		GROUP BY a.documentid, d.title, a.pageid, p.title
+		ORDER BY nothelpful DESC, helpful ASC, d.title`, ctx.OrgID, spaceID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select feedback summary for space %s", spaceID))
+		return
+	}
+
+	return
+}
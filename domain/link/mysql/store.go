@@ -142,6 +142,85 @@ func (s Scope) MarkOrphanAttachmentLink(ctx domain.RequestContext, attachmentID
 	return
 }
 
+// GetInboundLinks returns links of the given type pointing at a document, section or attachment
+// that are not already orphaned.
+func (s Scope) GetInboundLinks(ctx domain.RequestContext, linkType, targetID string) (links []link.Link, err error) {
+	err = s.Runtime.Db.Select(&links,
+		`select l.refid, l.orgid, l.folderid, l.userid, l.sourcedocumentid, l.sourcepageid, l.targetdocumentid, l.targetid, l.linktype, l.orphan, l.created, l.revised
+		FROM link l
+		WHERE l.orgid=? AND l.linktype=? AND l.targetid=? AND l.orphan=0`,
+		ctx.OrgID,
+		linkType,
+		targetID)
+
+	if err != nil {
+		return
+	}
+
+	if len(links) == 0 {
+		links = []link.Link{}
+	}
+
+	return
+}
+
+// MarkLinked clears the orphan flag on a link whose target was restored.
+func (s Scope) MarkLinked(ctx domain.RequestContext, id string) (err error) {
+	revised := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE link SET orphan=0, revised=? WHERE orgid=? AND refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		return
+	}
+
+	_, err = stmt.Exec(revised, ctx.OrgID, id)
+
+	return
+}
+
+// GetDocumentInboundLinks returns links of any type that point into the specified document.
+func (s Scope) GetDocumentInboundLinks(ctx domain.RequestContext, documentID string) (links []link.Link, err error) {
+	err = s.Runtime.Db.Select(&links,
+		`select l.refid, l.orgid, l.folderid, l.userid, l.sourcedocumentid, l.sourcepageid, l.targetdocumentid, l.targetid, l.linktype, l.orphan, l.created, l.revised
+		FROM link l
+		WHERE l.orgid=? AND l.targetdocumentid=?`,
+		ctx.OrgID,
+		documentID)
+
+	if err != nil {
+		return
+	}
+
+	if len(links) == 0 {
+		links = []link.Link{}
+	}
+
+	return
+}
+
+// UpdateTargetSpace re-points links into the specified document at its new space.
+func (s Scope) UpdateTargetSpace(ctx domain.RequestContext, documentID, spaceID string) (err error) {
+	revised := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("UPDATE link SET folderid=?, revised=? WHERE orgid=? AND targetdocumentid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare link space update")
+		return
+	}
+
+	_, err = stmt.Exec(spaceID, revised, ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, "execute link space update")
+		return
+	}
+
+	return
+}
+
 // DeleteSourcePageLinks removes saved links for given source.
 func (s Scope) DeleteSourcePageLinks(ctx domain.RequestContext, pageID string) (rows int64, err error) {
 	b := mysql.BaseQuery{}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
+	"github.com/documize/community/model/watch"
 	"github.com/documize/community/server/web"
 )
 
@@ -218,6 +219,58 @@ func (m *Mailer) ShareFolderExistingUser(recipient, inviter, url, folder, intro
 	}
 }
 
+// ShareDocument sends someone without an account a read-only link to a document.
+func (m *Mailer) ShareDocument(recipient, sender, url, document, message, expires string) {
+	method := "ShareDocument"
+	m.LoadCredentials()
+
+	file, err := web.ReadFile("mail/share-document.html")
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to load email template", method), err)
+		return
+	}
+
+	emailTemplate := string(file)
+
+	// check sender name
+	if sender == "Hello You" || len(sender) == 0 {
+		sender = "Your colleague"
+	}
+
+	subject := fmt.Sprintf("%s has shared %s with you", sender, document)
+
+	e := NewEmail()
+	e.From = m.Credentials.SMTPsender
+	e.To = []string{recipient}
+	e.Subject = subject
+
+	parameters := struct {
+		Subject  string
+		Sender   string
+		Url      string
+		Document string
+		Message  string
+		Expires  string
+	}{
+		subject,
+		sender,
+		url,
+		document,
+		message,
+		expires,
+	}
+
+	buffer := new(bytes.Buffer)
+	t := template.Must(template.New("emailTemplate").Parse(emailTemplate))
+	t.Execute(buffer, &parameters)
+	e.HTML = buffer.Bytes()
+
+	err = e.Send(m.GetHost(), m.GetAuth())
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to send email", method), err)
+	}
+}
+
 // ShareFolderNewUser invites new user providing Credentials, explaining the product and stating who is inviting them.
 func (m *Mailer) ShareFolderNewUser(recipient, inviter, url, folder, invitationMessage string) {
 	method := "ShareFolderNewUser"
@@ -268,6 +321,159 @@ func (m *Mailer) ShareFolderNewUser(recipient, inviter, url, folder, invitationM
 	}
 }
 
+// ApprovalRequest asks a user to approve a document, with an optional due date.
+func (m *Mailer) ApprovalRequest(recipient, requestor, url, document, note, due string) {
+	method := "ApprovalRequest"
+	m.LoadCredentials()
+
+	file, err := web.ReadFile("mail/approval-request.html")
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to load email template", method), err)
+		return
+	}
+
+	emailTemplate := string(file)
+
+	// check requestor name
+	if requestor == "Hello You" || len(requestor) == 0 {
+		requestor = "Your colleague"
+	}
+
+	subject := fmt.Sprintf("%s has asked you to approve %s", requestor, document)
+
+	e := NewEmail()
+	e.From = m.Credentials.SMTPsender
+	e.To = []string{recipient}
+	e.Subject = subject
+
+	parameters := struct {
+		Subject   string
+		Requestor string
+		Url       string
+		Document  string
+		Note      string
+		Due       string
+	}{
+		subject,
+		requestor,
+		url,
+		document,
+		note,
+		due,
+	}
+
+	buffer := new(bytes.Buffer)
+	t := template.Must(template.New("emailTemplate").Parse(emailTemplate))
+	t.Execute(buffer, &parameters)
+	e.HTML = buffer.Bytes()
+
+	err = e.Send(m.GetHost(), m.GetAuth())
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to send email", method), err)
+	}
+}
+
+// ApprovalResult tells the requestor that a document was approved or rejected.
+func (m *Mailer) ApprovalResult(recipient, approver, url, document string, approved bool, note string) {
+	method := "ApprovalResult"
+	m.LoadCredentials()
+
+	file, err := web.ReadFile("mail/approval-result.html")
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to load email template", method), err)
+		return
+	}
+
+	emailTemplate := string(file)
+
+	// check approver name
+	if approver == "Hello You" || len(approver) == 0 {
+		approver = "Your colleague"
+	}
+
+	outcome := "rejected"
+	if approved {
+		outcome = "approved"
+	}
+
+	subject := fmt.Sprintf("%s has %s %s", approver, outcome, document)
+
+	e := NewEmail()
+	e.From = m.Credentials.SMTPsender
+	e.To = []string{recipient}
+	e.Subject = subject
+
+	parameters := struct {
+		Subject  string
+		Approver string
+		Url      string
+		Document string
+		Outcome  string
+		Note     string
+	}{
+		subject,
+		approver,
+		url,
+		document,
+		outcome,
+		note,
+	}
+
+	buffer := new(bytes.Buffer)
+	t := template.Must(template.New("emailTemplate").Parse(emailTemplate))
+	t.Execute(buffer, &parameters)
+	e.HTML = buffer.Bytes()
+
+	err = e.Send(m.GetHost(), m.GetAuth())
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to send email", method), err)
+	}
+}
+
+// WatchDigest lists changes to documents and spaces the recipient watches.
+func (m *Mailer) WatchDigest(recipient, url string, digests []watch.Digest) {
+	method := "WatchDigest"
+	m.LoadCredentials()
+
+	file, err := web.ReadFile("mail/watch-digest.html")
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to load email template", method), err)
+		return
+	}
+
+	emailTemplate := string(file)
+
+	subject := "Changes to documents you watch"
+	if len(digests) == 1 {
+		subject = fmt.Sprintf("Changes to %s", digests[0].Title)
+	}
+
+	e := NewEmail()
+	e.From = m.Credentials.SMTPsender
+	e.To = []string{recipient}
+	e.Subject = subject
+
+	parameters := struct {
+		Subject string
+		Url     string
+		Digests []watch.Digest
+	}{
+		subject,
+		url,
+		digests,
+	}
+
+	buffer := new(bytes.Buffer)
+	t := template.Must(template.New("emailTemplate").Parse(emailTemplate))
+	t.Execute(buffer, &parameters)
+	e.HTML = buffer.Bytes()
+
+	err = e.Send(m.GetHost(), m.GetAuth())
+	if err != nil {
+		m.Runtime.Log.Error(fmt.Sprintf("%s - unable to send email", method), err)
+	}
+}
+
 // Credentials holds SMTP endpoint and authentication methods
 type Credentials struct {
 	SMTPuserid   string
@@ -0,0 +1,299 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package metadata
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/metadata"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// GetFields returns the metadata fields defined for a space.
+func (h *Handler) GetFields(w http.ResponseWriter, r *http.Request) {
+	method := "metadata.getFields"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanViewSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	f, err := h.Store.Metadata.GetFields(ctx, folderID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, f)
+}
+
+// AddField adds a metadata field to a space.
+func (h *Handler) AddField(w http.ResponseWriter, r *http.Request) {
+	method := "metadata.addField"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanManageSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	f, ok := h.readField(w, r, method)
+	if !ok {
+		return
+	}
+
+	existing, err := h.Store.Metadata.GetFields(ctx, folderID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for _, e := range existing {
+		if strings.EqualFold(e.Name, f.Name) {
+			response.WriteBadRequestError(w, method, "field "+f.Name+" already exists")
+			return
+		}
+	}
+
+	f.RefID = uniqueid.Generate()
+	f.LabelID = folderID
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Metadata.AddField(ctx, f)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSpaceFieldAdd)
+
+	f, err = h.Store.Metadata.GetField(ctx, f.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, f)
+}
+
+// UpdateField changes a space's metadata field. Its type cannot be changed.
+func (h *Handler) UpdateField(w http.ResponseWriter, r *http.Request) {
+	method := "metadata.updateField"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	fieldID := request.Param(r, "fieldID")
+	if len(fieldID) == 0 {
+		response.WriteMissingDataError(w, method, "fieldID")
+		return
+	}
+
+	if !space.CanManageSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	current, err := h.Store.Metadata.GetField(ctx, fieldID)
+	if errors.Cause(err) == sql.ErrNoRows || (err == nil && current.LabelID != folderID) {
+		response.WriteNotFoundError(w, method, fieldID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	f, ok := h.readField(w, r, method)
+	if !ok {
+		return
+	}
+
+	if f.Type != current.Type {
+		response.WriteBadRequestError(w, method, "field type cannot be changed")
+		return
+	}
+
+	existing, err := h.Store.Metadata.GetFields(ctx, folderID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for _, e := range existing {
+		if e.RefID != fieldID && strings.EqualFold(e.Name, f.Name) {
+			response.WriteBadRequestError(w, method, "field "+f.Name+" already exists")
+			return
+		}
+	}
+
+	f.RefID = fieldID
+	f.LabelID = folderID
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Metadata.UpdateField(ctx, f)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSpaceFieldUpdate)
+
+	f, err = h.Store.Metadata.GetField(ctx, fieldID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, f)
+}
+
+// DeleteField removes a metadata field from a space along with document values for it.
+func (h *Handler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	method := "metadata.deleteField"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	fieldID := request.Param(r, "fieldID")
+	if len(fieldID) == 0 {
+		response.WriteMissingDataError(w, method, "fieldID")
+		return
+	}
+
+	if !space.CanManageSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	f, err := h.Store.Metadata.GetField(ctx, fieldID)
+	if err != nil || f.LabelID != folderID {
+		response.WriteNotFoundError(w, method, fieldID)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Metadata.DeleteField(ctx, fieldID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSpaceFieldDelete)
+
+	response.WriteEmpty(w)
+}
+
+// readField decodes and checks a field definition from the request body.
+func (h *Handler) readField(w http.ResponseWriter, r *http.Request, method string) (f metadata.Field, ok bool) {
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	err = json.Unmarshal(body, &f)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	f.Name = strings.TrimSpace(f.Name)
+	f.Label = strings.TrimSpace(f.Label)
+	if f.Choices == nil {
+		f.Choices = []string{}
+	}
+
+	err = validateField(f)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	return f, true
+}
@@ -0,0 +1,235 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package metadata handles the typed fields a space defines for its
+// documents and the values documents hold for them.
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/metadata"
+)
+
+// maxValue is the longest value stored for a field.
+const maxValue = 2000
+
+// fieldName is what fields can be called, so they can be used in filters.
+var fieldName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// validateField checks a field definition before it is saved.
+func validateField(f metadata.Field) error {
+	if !fieldName.MatchString(f.Name) || len(f.Name) > 50 {
+		return fmt.Errorf("field name %q must start with a letter and contain only letters, digits and underscores", f.Name)
+	}
+
+	switch f.Type {
+	case metadata.FieldText, metadata.FieldNumber, metadata.FieldDate, metadata.FieldUser:
+	case metadata.FieldEnum:
+		if len(f.Choices) == 0 {
+			return fmt.Errorf("enum field %s has no choices", f.Name)
+		}
+	default:
+		return fmt.Errorf("field %s has unknown type %q", f.Name, f.Type)
+	}
+
+	return nil
+}
+
+// Validate checks values supplied for a document against its space's
+// fields, returning them in the form they are stored.
+func Validate(ctx domain.RequestContext, s domain.Store, fields []metadata.Field, supplied map[string]string) (v []metadata.Value, err error) {
+	known := make(map[string]bool)
+	for _, f := range fields {
+		known[f.Name] = true
+	}
+	for name := range supplied {
+		if !known[name] {
+			return nil, fmt.Errorf("%s is not a field in this space", name)
+		}
+	}
+
+	for _, f := range fields {
+		value := strings.TrimSpace(supplied[f.Name])
+
+		if len(value) == 0 {
+			if f.Required {
+				return nil, fmt.Errorf("%s is required", label(f))
+			}
+			continue
+		}
+
+		switch f.Type {
+		case metadata.FieldText:
+			if len(value) > maxValue {
+				return nil, fmt.Errorf("%s must be at most %d characters", label(f), maxValue)
+			}
+		case metadata.FieldNumber:
+			n, err2 := strconv.ParseFloat(value, 64)
+			if err2 != nil {
+				return nil, fmt.Errorf("%s must be a number", label(f))
+			}
+			value = strconv.FormatFloat(n, 'f', -1, 64)
+		case metadata.FieldDate:
+			if _, err2 := time.Parse(metadata.DateFormat, value); err2 != nil {
+				return nil, fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", label(f))
+			}
+		case metadata.FieldUser:
+			acc, err2 := s.Account.GetUserAccount(ctx, value)
+			if err2 != nil || !acc.Active {
+				return nil, fmt.Errorf("%s must be a user in this organization", label(f))
+			}
+		case metadata.FieldEnum:
+			if !contains(f.Choices, value) {
+				return nil, fmt.Errorf("%s must be one of %s", label(f), strings.Join(f.Choices, ", "))
+			}
+		}
+
+		v = append(v, metadata.Value{FieldID: f.RefID, Name: f.Name, Type: f.Type, Value: value})
+	}
+
+	return
+}
+
+// Map returns values keyed by field name.
+func Map(v []metadata.Value) map[string]string {
+	m := make(map[string]string)
+	for _, value := range v {
+		m[value.Name] = value.Value
+	}
+
+	return m
+}
+
+// Carry keeps the values that name one of the given fields, for
+// documents moving to a space with a different set of fields.
+func Carry(values map[string]string, fields []metadata.Field) map[string]string {
+	m := make(map[string]string)
+	for _, f := range fields {
+		if v, ok := values[f.Name]; ok {
+			m[f.Name] = v
+		}
+	}
+
+	return m
+}
+
+// ByDocument groups values by the document they belong to.
+func ByDocument(v []metadata.Value) map[string][]metadata.Value {
+	m := make(map[string][]metadata.Value)
+	for _, value := range v {
+		m[value.DocumentID] = append(m[value.DocumentID], value)
+	}
+
+	return m
+}
+
+// Matches reports whether values satisfy every filter, keyed by field name.
+// Number and date filters take a single value or a range written as
+// from..to with either end optional. Text filters match anywhere in the
+// value ignoring case and other fields must match exactly.
+func Matches(values []metadata.Value, filters map[string]string) bool {
+	for name, want := range filters {
+		matched := false
+
+		for _, v := range values {
+			if v.Name == name && match(v, want) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func match(v metadata.Value, want string) bool {
+	switch v.Type {
+	case metadata.FieldText:
+		return strings.Contains(strings.ToLower(v.Value), strings.ToLower(want))
+	case metadata.FieldNumber:
+		n, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return false
+		}
+		return inRange(want, func(bound string) (int, bool) {
+			b, err := strconv.ParseFloat(bound, 64)
+			if err != nil {
+				return 0, false
+			}
+			switch {
+			case n < b:
+				return -1, true
+			case n > b:
+				return 1, true
+			}
+			return 0, true
+		})
+	case metadata.FieldDate:
+		return inRange(want, func(bound string) (int, bool) {
+			return strings.Compare(v.Value, bound), true
+		})
+	}
+
+	return v.Value == want
+}
+
+// inRange applies a single value or from..to range, where compare
+// orders the field value against a bound.
+func inRange(want string, compare func(bound string) (int, bool)) bool {
+	parts := strings.SplitN(want, "..", 2)
+
+	if len(parts) == 1 {
+		c, ok := compare(strings.TrimSpace(want))
+		return ok && c == 0
+	}
+
+	if from := strings.TrimSpace(parts[0]); len(from) > 0 {
+		if c, ok := compare(from); !ok || c < 0 {
+			return false
+		}
+	}
+
+	if to := strings.TrimSpace(parts[1]); len(to) > 0 {
+		if c, ok := compare(to); !ok || c > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func label(f metadata.Field) string {
+	if len(f.Label) > 0 {
+		return f.Label
+	}
+
+	return f.Name
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package metadata
+
+import (
+	"testing"
+
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/metadata"
+)
+
+func TestValidate(t *testing.T) {
+	fields := []metadata.Field{
+		{Name: "service", Type: metadata.FieldText, Required: true},
+		{Name: "cost", Type: metadata.FieldNumber},
+		{Name: "review", Type: metadata.FieldDate},
+		{Name: "class", Type: metadata.FieldEnum, Choices: []string{"public", "internal"}},
+	}
+
+	ctx := domain.RequestContext{}
+	s := domain.Store{}
+
+	v, err := Validate(ctx, s, fields, map[string]string{"service": "Billing", "cost": "1.50", "review": "2018-05-01", "class": "internal"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if m := Map(v); m["cost"] != "1.5" || m["class"] != "internal" {
+		t.Errorf("unexpected values %v", m)
+	}
+
+	bad := []map[string]string{
+		{"cost": "1"},                            // service is required
+		{"service": "x", "cost": "lots"},         // not a number
+		{"service": "x", "review": "01/05/2018"}, // not YYYY-MM-DD
+		{"service": "x", "class": "secret"},      // not a choice
+		{"service": "x", "owner": "y"},           // unknown field
+	}
+
+	for _, b := range bad {
+		if _, err := Validate(ctx, s, fields, b); err == nil {
+			t.Errorf("expected error for %v", b)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	values := []metadata.Value{
+		{Name: "service", Type: metadata.FieldText, Value: "Billing API"},
+		{Name: "cost", Type: metadata.FieldNumber, Value: "12.5"},
+		{Name: "review", Type: metadata.FieldDate, Value: "2018-05-01"},
+		{Name: "class", Type: metadata.FieldEnum, Value: "internal"},
+	}
+
+	tests := []struct {
+		filters map[string]string
+		want    bool
+	}{
+		{map[string]string{"service": "billing"}, true},
+		{map[string]string{"cost": "10..20"}, true},
+		{map[string]string{"cost": "..12"}, false},
+		{map[string]string{"review": "2018-01-01.."}, true},
+		{map[string]string{"review": "2018-05-01", "class": "internal"}, true},
+		{map[string]string{"class": "intern"}, false},
+		{map[string]string{"owner": "x"}, false},
+		{map[string]string{}, true},
+	}
+
+	for _, test := range tests {
+		if got := Matches(values, test.filters); got != test.want {
+			t.Errorf("Matches(%v) = %v, want %v", test.filters, got, test.want)
+		}
+	}
+}
+
+func TestCarry(t *testing.T) {
+	fields := []metadata.Field{{Name: "service"}, {Name: "owner"}}
+
+	m := Carry(map[string]string{"service": "Billing", "cost": "12"}, fields)
+	if len(m) != 1 || m["service"] != "Billing" {
+		t.Errorf("unexpected values %v", m)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/metadata"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// fieldRow holds enum choices as stored.
+type fieldRow struct {
+	metadata.Field
+	RawChoices sql.NullString `db:"choices"`
+}
+
+const selectField = `SELECT id, refid, orgid, labelid, name, label, type, required, choices, sequence, created, revised FROM spacefield`
+
+// Values are only returned for fields of the space a document is in,
+// so values recorded before a document moved spaces are ignored.
+const selectValue = `SELECT v.documentid, v.fieldid, f.name, f.type, v.value
+	FROM documentfield v, spacefield f, document d
+	WHERE v.orgid=? AND f.orgid=v.orgid AND f.refid=v.fieldid AND d.orgid=v.orgid AND d.refid=v.documentid AND d.labelid=f.labelid`
+
+func (r fieldRow) field() (f metadata.Field, err error) {
+	f = r.Field
+	f.Choices = []string{}
+
+	if r.RawChoices.Valid && len(r.RawChoices.String) > 0 {
+		err = json.Unmarshal([]byte(r.RawChoices.String), &f.Choices)
+	}
+
+	return
+}
+
+// AddField adds a field to a space's metadata schema.
+func (s Scope) AddField(ctx domain.RequestContext, f metadata.Field) (err error) {
+	f.OrgID = ctx.OrgID
+	f.Created = time.Now().UTC()
+	f.Revised = time.Now().UTC()
+
+	choices, err := json.Marshal(f.Choices)
+	if err != nil {
+		err = errors.Wrap(err, "marshal field choices")
+		return
+	}
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO spacefield (refid, orgid, labelid, name, label, type, required, choices, sequence, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert space field")
+		return
+	}
+
+	_, err = stmt.Exec(f.RefID, f.OrgID, f.LabelID, f.Name, f.Label, f.Type, f.Required, string(choices), f.Sequence, f.Created, f.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert space field")
+		return
+	}
+
+	return
+}
+
+// GetField returns a metadata field.
+func (s Scope) GetField(ctx domain.RequestContext, id string) (f metadata.Field, err error) {
+	r := fieldRow{}
+
+	err = s.Runtime.Db.Get(&r, selectField+" WHERE orgid=? AND refid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select space field %s", id))
+		return
+	}
+
+	return r.field()
+}
+
+// GetFields returns a space's metadata schema in display order.
+func (s Scope) GetFields(ctx domain.RequestContext, spaceID string) (f []metadata.Field, err error) {
+	rows := []fieldRow{}
+
+	err = s.Runtime.Db.Select(&rows, selectField+" WHERE orgid=? AND labelid=? ORDER BY sequence, name", ctx.OrgID, spaceID)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select fields for space %s", spaceID))
+		return
+	}
+
+	f = []metadata.Field{}
+	for _, r := range rows {
+		var field metadata.Field
+		field, err = r.field()
+		if err != nil {
+			err = errors.Wrap(err, fmt.Sprintf("unmarshal choices for field %s", r.RefID))
+			return
+		}
+		f = append(f, field)
+	}
+
+	return
+}
+
+// UpdateField changes a metadata field. The field's type cannot change.
+func (s Scope) UpdateField(ctx domain.RequestContext, f metadata.Field) (err error) {
+	choices, err := json.Marshal(f.Choices)
+	if err != nil {
+		err = errors.Wrap(err, "marshal field choices")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("UPDATE spacefield SET name=?, label=?, required=?, choices=?, sequence=?, revised=? WHERE orgid=? AND refid=?",
+		f.Name, f.Label, f.Required, string(choices), f.Sequence, time.Now().UTC(), ctx.OrgID, f.RefID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update space field %s", f.RefID))
+		return
+	}
+
+	return
+}
+
+// DeleteField removes a metadata field and all document values for it.
+func (s Scope) DeleteField(ctx domain.RequestContext, id string) (err error) {
+	_, err = ctx.Transaction.Exec("DELETE FROM documentfield WHERE orgid=? AND fieldid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete values for space field %s", id))
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("DELETE FROM spacefield WHERE orgid=? AND refid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete space field %s", id))
+		return
+	}
+
+	return
+}
+
+// GetValues returns a document's metadata values.
+func (s Scope) GetValues(ctx domain.RequestContext, documentID string) (v []metadata.Value, err error) {
+	err = s.Runtime.Db.Select(&v, selectValue+" AND v.documentid=?", ctx.OrgID, documentID)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select field values for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// GetSpaceValues returns metadata values for all documents in a space.
+func (s Scope) GetSpaceValues(ctx domain.RequestContext, spaceID string) (v []metadata.Value, err error) {
+	err = s.Runtime.Db.Select(&v, selectValue+" AND d.labelid=?", ctx.OrgID, spaceID)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select field values for space %s", spaceID))
+		return
+	}
+
+	return
+}
+
+// GetDocumentsValues returns metadata values for the given documents.
+func (s Scope) GetDocumentsValues(ctx domain.RequestContext, documentIDs []string) (v []metadata.Value, err error) {
+	if len(documentIDs) == 0 {
+		return
+	}
+
+	query, args, err := sqlx.In(selectValue+" AND v.documentid IN (?)", ctx.OrgID, documentIDs)
+	if err != nil {
+		err = errors.Wrap(err, "expand document field values query")
+		return
+	}
+
+	err = s.Runtime.Db.Select(&v, s.Runtime.Db.Rebind(query), args...)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "select field values for documents")
+		return
+	}
+
+	return
+}
+
+// SetValues replaces a document's metadata values.
+func (s Scope) SetValues(ctx domain.RequestContext, documentID string, v []metadata.Value) (err error) {
+	_, err = ctx.Transaction.Exec("DELETE FROM documentfield WHERE orgid=? AND documentid=?", ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete field values for document %s", documentID))
+		return
+	}
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO documentfield (orgid, documentid, fieldid, value, created) VALUES (?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert document field")
+		return
+	}
+
+	for _, value := range v {
+		if len(value.Value) == 0 {
+			continue
+		}
+
+		_, err = stmt.Exec(ctx.OrgID, documentID, value.FieldID, value.Value, time.Now().UTC())
+		if err != nil {
+			err = errors.Wrap(err, fmt.Sprintf("execute insert document field %s", value.FieldID))
+			return
+		}
+	}
+
+	return
+}
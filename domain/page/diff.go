@@ -0,0 +1,401 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/snapshot"
+	"github.com/documize/community/model/trash"
+	"github.com/pkg/errors"
+)
+
+// visitGap separates visits, so reopening a document shortly after
+// reading it does not hide what changed since the reader was last here.
+const visitGap = 30 * time.Minute
+
+// errPointInTime is returned for diff end points that cannot be understood
+// or name a snapshot of another document.
+var errPointInTime = errors.New("expected current, lastvisit, a snapshot ID, an RFC 3339 time or a date")
+
+// GetDocumentDiff compares the whole document between two points in time,
+// given by the from and to query parameters. To defaults to the current
+// document.
+func (h *Handler) GetDocumentDiff(w http.ResponseWriter, r *http.Request) {
+	method := "page.documentDiff"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	from := request.Query(r, "from")
+	if len(from) == 0 {
+		response.WriteMissingDataError(w, method, "from")
+		return
+	}
+
+	to := request.Query(r, "to")
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	now := time.Now().UTC()
+
+	before, fromTime, fromExact, err := h.pagesAt(ctx, documentID, from, now)
+	if err != nil {
+		h.writePointError(w, method, from, err)
+		return
+	}
+
+	after, toTime, toExact, err := h.pagesAt(ctx, documentID, to, now)
+	if err != nil {
+		h.writePointError(w, method, to, err)
+		return
+	}
+
+	d := Compare(before, after, fromExact && toExact)
+	d.From = fromTime
+	d.To = toTime
+
+	response.WriteJSON(w, d)
+}
+
+// pagesAt returns document pages as they were at the given point, which is
+// "current" (or empty), "lastvisit", a snapshot ID, an RFC 3339 time or a
+// date. Snapshots hold the complete document, so they are exact. Otherwise
+// content is rebuilt from page revisions and sections deleted since are
+// recovered from trash, but section order and levels are not recorded over
+// time, so sections keep their current position and moves or indent changes
+// cannot be seen. Sections purged from trash are missing.
+func (h *Handler) pagesAt(ctx domain.RequestContext, documentID, point string, now time.Time) (pages []page.Page, at time.Time, exact bool, err error) {
+	switch point {
+	case "", "current":
+		pages, err = h.Store.Page.GetPages(ctx, documentID)
+		if errors.Cause(err) == sql.ErrNoRows {
+			err = nil
+		}
+		return pages, now, true, err
+
+	case "lastvisit":
+		at, err = h.Store.Activity.GetLastRead(ctx, documentID, now.Add(-visitGap))
+		if errors.Cause(err) == sql.ErrNoRows {
+			at, err = time.Time{}, nil // never read, so everything is new
+		}
+		if err != nil {
+			return
+		}
+		pages, err = h.revisedPages(ctx, documentID, at)
+		return
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, perr := time.Parse(layout, point); perr == nil {
+			at = t.UTC()
+			pages, err = h.revisedPages(ctx, documentID, at)
+			return
+		}
+	}
+
+	sn, err := h.Store.Snapshot.Get(ctx, point)
+	if errors.Cause(err) == sql.ErrNoRows {
+		err = errPointInTime
+		return
+	}
+	if err != nil {
+		return
+	}
+	if sn.DocumentID != documentID {
+		err = errPointInTime
+		return
+	}
+
+	c := snapshot.Content{}
+	err = json.Unmarshal(sn.Data, &c)
+	if err != nil {
+		err = errors.Wrap(err, "decode snapshot")
+		return
+	}
+
+	for _, np := range c.Pages {
+		pages = append(pages, np.Page)
+	}
+
+	return pages, sn.Created, true, nil
+}
+
+// revisedPages rebuilds document pages as they were at a given time.
+// Revisions hold a page as it was before each change, so the first
+// revision made after that time has the content current then.
+func (h *Handler) revisedPages(ctx domain.RequestContext, documentID string, at time.Time) (pages []page.Page, err error) {
+	current, err := h.Store.Page.GetPages(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	revisions, err := h.Store.Page.GetDocumentRevisionsSince(ctx, documentID, at)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	// sections deleted since take their revisions to trash with them
+	items, err := h.Store.Trash.GetDeletedSections(ctx, documentID, at)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	err = nil
+
+	deleted := []page.Page{}
+	for _, item := range items {
+		c := trash.Content{}
+		if jerr := json.Unmarshal(item.Data, &c); jerr != nil {
+			h.Runtime.Log.Error("page.revisedPages", jerr)
+			continue
+		}
+		for _, np := range c.Pages {
+			deleted = append(deleted, np.Page)
+		}
+		for _, r := range c.Revisions {
+			if r.Created.After(at) {
+				revisions = append(revisions, r)
+			}
+		}
+	}
+
+	return pagesAsOf(current, deleted, revisions, at), nil
+}
+
+// pagesAsOf combines current and deleted pages into those that existed
+// at a given time, with the content they had then.
+func pagesAsOf(current, deleted []page.Page, revisions []page.Revision, at time.Time) (pages []page.Page) {
+	then := make(map[string]page.Revision)
+	for _, r := range revisions {
+		if first, ok := then[r.PageID]; !ok || r.Created.Before(first.Created) {
+			then[r.PageID] = r
+		}
+	}
+
+	seen := make(map[string]bool)
+	pages = []page.Page{}
+	for _, p := range append(current, deleted...) {
+		if p.Created.After(at) || seen[p.RefID] {
+			continue
+		}
+		seen[p.RefID] = true
+
+		if r, ok := then[p.RefID]; ok {
+			p.Title = r.Title
+			p.Body = r.Body
+		}
+		pages = append(pages, p)
+	}
+
+	return
+}
+
+// writePointError responds to a diff end point that could not be resolved.
+func (h *Handler) writePointError(w http.ResponseWriter, method, point string, err error) {
+	switch errors.Cause(err) {
+	case errPointInTime:
+		response.WriteBadRequestError(w, method, fmt.Sprintf("%s: %s", point, err.Error()))
+	default:
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+	}
+}
+
+// Compare aligns two versions of a document's pages by RefID and reports
+// added, deleted, moved, re-leveled and edited sections. Deleted sections
+// are placed where they used to be. Moves and indent changes are only
+// reported when positions is set, i.e. both versions hold the order and
+// levels the document really had.
+func Compare(before, after []page.Page, positions bool) (d page.DocumentDiff) {
+	d.Positions = positions
+	before = bySequence(before)
+	after = bySequence(after)
+
+	previous := make(map[string]int)
+	for i, p := range before {
+		previous[p.RefID] = i
+	}
+
+	current := make(map[string]bool)
+	for _, p := range after {
+		current[p.RefID] = true
+	}
+
+	stayed := unmoved(after, previous)
+	d.Sections = []page.SectionDiff{}
+
+	var html bytes.Buffer
+	next := 0 // next before page to consider for deletion
+
+	emit := func(s page.SectionDiff, body string) {
+		if s.Added || s.Deleted || s.Moved || s.Releveled || s.Changed {
+			d.Sections = append(d.Sections, s)
+		}
+		writeSection(&html, s, body)
+	}
+
+	deleted := func(upto int) {
+		for ; next < upto; next++ {
+			p := before[next]
+			if current[p.RefID] {
+				continue
+			}
+			emit(page.SectionDiff{PageID: p.RefID, Title: p.Title, PreviousTitle: p.Title,
+				Level: p.Level, PreviousLevel: p.Level, Deleted: true}, p.Body)
+		}
+	}
+
+	for _, p := range after {
+		s := page.SectionDiff{PageID: p.RefID, Title: p.Title, Level: p.Level}
+
+		i, ok := previous[p.RefID]
+		if !ok {
+			s.Added = true
+			emit(s, p.Body)
+			continue
+		}
+
+		deleted(i)
+
+		old := before[i]
+		s.PreviousTitle = old.Title
+		s.PreviousLevel = old.Level
+		if positions {
+			s.Moved = !stayed[p.RefID]
+			s.Releveled = old.Level != p.Level
+		} else {
+			s.PreviousLevel = p.Level
+		}
+		s.Changed = old.Title != p.Title || old.Body != p.Body
+
+		body := p.Body
+		if old.Body != p.Body {
+			diff, err := diffHTML(old.Body, p.Body)
+			if err == nil {
+				s.Diff = diff
+				body = diff
+			}
+		}
+
+		emit(s, body)
+	}
+
+	deleted(len(before))
+
+	d.HTML = html.String()
+
+	return
+}
+
+// bySequence returns pages in document order without changing the caller's slice.
+func bySequence(pages []page.Page) []page.Page {
+	sorted := make([]page.Page, len(pages))
+	copy(sorted, pages)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Sequence < sorted[j].Sequence })
+	return sorted
+}
+
+// unmoved finds the largest set of pages that kept their relative order,
+// i.e. the longest increasing run of previous positions. Pages outside
+// it are the ones that were moved.
+func unmoved(after []page.Page, previous map[string]int) map[string]bool {
+	var ids []string
+	var positions []int
+	for _, p := range after {
+		if i, ok := previous[p.RefID]; ok {
+			ids = append(ids, p.RefID)
+			positions = append(positions, i)
+		}
+	}
+
+	// patience sorting, tails[k] holds the index ending the best run of length k+1
+	tails := []int{}
+	parent := make([]int, len(positions))
+	for i, pos := range positions {
+		k := sort.Search(len(tails), func(t int) bool { return positions[tails[t]] >= pos })
+		if k > 0 {
+			parent[i] = tails[k-1]
+		} else {
+			parent[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	stayed := make(map[string]bool)
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = parent[i] {
+			stayed[ids[i]] = true
+		}
+	}
+
+	return stayed
+}
+
+// sectionStyles marks up whole sections like html-diff marks up text.
+var sectionStyles = map[string]string{
+	"added":   "background-color: palegreen;",
+	"deleted": "background-color: lightpink; text-decoration: line-through;",
+	"moved":   "border-left: 3px solid lightskyblue; padding-left: 10px;",
+}
+
+func writeSection(b *bytes.Buffer, s page.SectionDiff, body string) {
+	state := "unchanged"
+	switch {
+	case s.Added:
+		state = "added"
+	case s.Deleted:
+		state = "deleted"
+	case s.Moved:
+		state = "moved"
+	case s.Changed || s.Releveled:
+		state = "changed"
+	}
+
+	level := s.Level + 1
+	if level > 6 {
+		level = 6
+	}
+
+	title := template.HTMLEscapeString(s.Title)
+	if !s.Added && !s.Deleted && s.PreviousTitle != s.Title {
+		title = fmt.Sprintf(`<span style="%s">%s</span> <span style="%s">%s</span>`,
+			sectionStyles["deleted"], template.HTMLEscapeString(s.PreviousTitle), sectionStyles["added"], title)
+	}
+
+	fmt.Fprintf(b, `<div class="documize-diff-section documize-diff-%s" data-id="%s" style="%s">`, state, template.HTMLEscapeString(s.PageID), sectionStyles[state])
+	fmt.Fprintf(b, "<h%d>%s</h%d>", level, title, level)
+	b.WriteString(body)
+	b.WriteString("</div>")
+}
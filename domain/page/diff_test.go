@@ -0,0 +1,145 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/documize/community/model/page"
+)
+
+// changes lists diffed sections as "id:flags", flags being
+// a(dded), d(eleted), m(oved), l(eveled) and c(hanged).
+func changes(d page.DocumentDiff) string {
+	var s []string
+	for _, c := range d.Sections {
+		flags := ""
+		for _, f := range []struct {
+			set  bool
+			flag string
+		}{{c.Added, "a"}, {c.Deleted, "d"}, {c.Moved, "m"}, {c.Releveled, "l"}, {c.Changed, "c"}} {
+			if f.set {
+				flags += f.flag
+			}
+		}
+		s = append(s, c.PageID+":"+flags)
+	}
+	return strings.Join(s, " ")
+}
+
+func TestCompare(t *testing.T) {
+	retitled := outline("a:1", "b:2", "c:1")
+	retitled[1].Title = "new title"
+
+	tests := []struct {
+		name      string
+		before    []page.Page
+		after     []page.Page
+		positions bool
+		want      string
+	}{
+		{"unchanged", outline("a:1", "b:2"), outline("a:1", "b:2"), true, ""},
+		{"added", outline("a:1", "c:1"), outline("a:1", "b:2", "c:1"), true, "b:a"},
+		{"deleted", outline("a:1", "b:2", "c:1"), outline("a:1", "c:1"), true, "b:d"},
+		{"moved", outline("a:1", "b:1", "c:1"), outline("a:1", "c:1", "b:1"), true, "c:m"},
+		{"releveled", outline("a:1", "b:2"), outline("a:1", "b:1"), true, "b:l"},
+		{"changed", outline("a:1", "b:2", "c:1"), retitled, true, "b:c"},
+		{"deleted in place", outline("a:1", "b:1", "c:1", "d:1"), outline("a:1", "d:1"), true, "b:d c:d"},
+		{"without positions", outline("a:1", "b:2", "c:1"), outline("c:1", "a:1", "b:1"), false, ""},
+		{"without positions still added and deleted", outline("a:1", "b:1"), outline("a:2", "c:1"), false, "c:a b:d"},
+	}
+
+	for _, test := range tests {
+		d := Compare(test.before, test.after, test.positions)
+		if got := changes(d); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+		if d.Positions != test.positions {
+			t.Errorf("%s: positions %v, want %v", test.name, d.Positions, test.positions)
+		}
+	}
+}
+
+func TestUnmoved(t *testing.T) {
+	tests := []struct {
+		before string
+		after  string
+		want   string
+	}{
+		{"a b c d", "a b c d", "a b c d"},
+		{"a b c d", "b c d a", "b c d"},
+		{"a b c d", "a d b c", "a b c"},
+		{"a b c d", "d c b a", "a"},
+		{"a b c", "a x b c", "a b c"},
+		{"a b c", "", ""},
+	}
+
+	for _, test := range tests {
+		previous := make(map[string]int)
+		for i, id := range strings.Fields(test.before) {
+			previous[id] = i
+		}
+
+		var after []page.Page
+		for _, id := range strings.Fields(test.after) {
+			p := page.Page{}
+			p.RefID = id
+			after = append(after, p)
+		}
+
+		var got []string
+		for id := range unmoved(after, previous) {
+			got = append(got, id)
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("%q -> %q: got %q, want %q", test.before, test.after, strings.Join(got, " "), test.want)
+		}
+	}
+}
+
+func TestPagesAsOf(t *testing.T) {
+	at := time.Date(2018, 1, 10, 0, 0, 0, 0, time.UTC)
+	before, after := at.Add(-time.Hour), at.Add(time.Hour)
+
+	current := outline("a:1", "b:1", "d:1")
+	current[0].Created, current[0].Title = before, "a now"
+	current[1].Created = after // added since
+	current[2].Created = before
+
+	deleted := outline("c:1")
+	deleted[0].Created, deleted[0].Title = before, "c last"
+
+	rev := func(pageID, title string, created time.Time) (r page.Revision) {
+		r.PageID, r.Title, r.Created = pageID, title, created
+		return
+	}
+	revisions := []page.Revision{
+		rev("a", "a later", after.Add(time.Hour)),
+		rev("a", "a then", after),
+		rev("c", "c then", after),
+	}
+
+	var got []string
+	for _, p := range pagesAsOf(current, deleted, revisions, at) {
+		got = append(got, p.RefID+"="+p.Title)
+	}
+
+	want := "a=a then d= c=c then"
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", strings.Join(got, " "), want)
+	}
+}
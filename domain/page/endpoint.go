@@ -25,15 +25,18 @@ import (
 	"github.com/documize/community/core/streamutil"
 	"github.com/documize/community/core/uniqueid"
 	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/block"
+	"github.com/documize/community/domain/comment"
 	"github.com/documize/community/domain/document"
 	"github.com/documize/community/domain/link"
 	indexer "github.com/documize/community/domain/search"
 	"github.com/documize/community/domain/section/provider"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/domain/trash"
 	"github.com/documize/community/model/activity"
 	"github.com/documize/community/model/audit"
 	"github.com/documize/community/model/doc"
 	"github.com/documize/community/model/page"
-	htmldiff "github.com/documize/html-diff"
 )
 
 // Handler contains the runtime information such as logging and database.
@@ -99,6 +102,31 @@ func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
 	model.Meta.SetDefaults()
 	// page.Title = template.HTMLEscapeString(page.Title)
 
+	// Linked sections take their content from the block and follow later changes to it.
+	if model.Page.BlockLinked {
+		if len(model.Page.BlockID) == 0 {
+			response.WriteBadRequestError(w, method, "blockId required for linked section")
+			return
+		}
+
+		b, err2 := h.Store.Block.Get(ctx, model.Page.BlockID)
+		if err2 != nil {
+			response.WriteBadRequestError(w, method, "blockId")
+			return
+		}
+
+		if !space.CanViewSpace(ctx, *h.Store, b.LabelID) {
+			response.WriteForbiddenError(w)
+			return
+		}
+
+		model.Page.ContentType = b.ContentType
+		model.Page.PageType = b.PageType
+		model.Meta.RawBody = b.RawBody
+		model.Meta.Config = b.Config
+		model.Meta.ExternalSource = b.ExternalSource
+	}
+
 	doc, err := h.Store.Document.Get(ctx, documentID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -186,6 +214,11 @@ func (h *Handler) GetPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if l, err := h.Store.Page.GetLock(ctx, pageID); err == nil {
+		page.Lock = &l
+	}
+
+	w.Header().Set("ETag", etag(page))
 	response.WriteJSON(w, page)
 }
 
@@ -205,6 +238,8 @@ func (h *Handler) GetPages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.resyncBlocks(ctx, documentID)
+
 	var pages []page.Page
 	var err error
 	content := request.Query(r, "content")
@@ -224,9 +259,47 @@ func (h *Handler) GetPages(w http.ResponseWriter, r *http.Request) {
 		h.Runtime.Log.Error(method, err)
 	}
 
+	h.attachLocks(ctx, documentID, pages)
+
 	response.WriteJSON(w, pages)
 }
 
+// resyncBlocks brings linked sections that missed block updates up to
+// date, e.g. once the document is no longer archived, when someone who
+// can change the document reads it. Failures are logged, not returned,
+// so reading is never blocked.
+func (h *Handler) resyncBlocks(ctx domain.RequestContext, documentID string) {
+	method := "page.resyncBlocks"
+
+	stale, err := h.Store.Block.GetStalePages(ctx, documentID)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	if len(stale) == 0 || !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	updated, err := block.Resync(ctx, h.Runtime, *h.Store, documentID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	for _, p := range updated {
+		go h.Indexer.IndexContent(ctx, p)
+	}
+}
+
 // GetPagesBatch gets specified pages for document.
 func (h *Handler) GetPagesBatch(w http.ResponseWriter, r *http.Request) {
 	method := "page.batch"
@@ -295,6 +368,11 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if l, locked := h.lockedByOther(ctx, pageID); locked {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
 	doc, err := h.Store.Document.Get(ctx, documentID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -317,6 +395,14 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = trash.Pages(ctx, *h.Store, doc, []string{pageID})
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	if len(page.BlockID) > 0 {
 		h.Store.Block.DecrementUsage(ctx, page.BlockID)
 	}
@@ -345,6 +431,10 @@ func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 
 	h.Store.Page.DeletePageRevisions(ctx, pageID)
 
+	h.Store.Page.DeleteLock(ctx, pageID)
+
+	h.Store.Comment.MarkPageOutdated(ctx, pageID)
+
 	ctx.Transaction.Commit()
 
 	response.WriteEmpty(w)
@@ -385,6 +475,13 @@ func (h *Handler) DeletePages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, p := range *model {
+		if l, locked := h.lockedByOther(ctx, p.PageID); locked {
+			response.WriteLockedError(w, method, l)
+			return
+		}
+	}
+
 	doc, err := h.Store.Document.Get(ctx, documentID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -399,6 +496,19 @@ func (h *Handler) DeletePages(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pageIDs := []string{}
+	for _, p := range *model {
+		pageIDs = append(pageIDs, p.PageID)
+	}
+
+	err = trash.Pages(ctx, *h.Store, doc, pageIDs)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	for _, page := range *model {
 		pageData, err := h.Store.Page.Get(ctx, page.PageID)
 		if err != nil {
@@ -426,7 +536,11 @@ func (h *Handler) DeletePages(w http.ResponseWriter, r *http.Request) {
 
 		h.Store.Link.MarkOrphanPageLink(ctx, page.PageID)
 
+		h.Store.Comment.MarkPageOutdated(ctx, page.PageID)
+
 		h.Store.Page.DeletePageRevisions(ctx, page.PageID)
+
+		h.Store.Page.DeleteLock(ctx, page.PageID)
 	}
 
 	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
@@ -445,6 +559,8 @@ func (h *Handler) DeletePages(w http.ResponseWriter, r *http.Request) {
 // Update will persist changed page and note the fact
 // that this is a new revision. If the page is the first in a document
 // then the corresponding document title will also be changed.
+// Changes must be based on the current page version, sent as page.version
+// or If-Match, otherwise 409 Conflict returns the current page.
 func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	method := "page.update"
 	ctx := domain.GetRequestContext(r)
@@ -497,6 +613,18 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	model.Page.Version, err = baseVersion(r, model.Page.Version)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "If-Match")
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if l, locked := h.lockedByOther(ctx, pageID); locked {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
 	doc, err := h.Store.Document.Get(ctx, documentID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -504,6 +632,13 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	current, err := h.Store.Page.Get(ctx, pageID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -521,6 +656,20 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Linked sections keep showing the block's content; they must be
+	// detached before their content can be edited. Users who cannot see
+	// the block's space keep the content the section already has.
+	if current.BlockLinked {
+		model.Meta.RawBody = oldPageMeta.RawBody
+		model.Meta.Config = oldPageMeta.Config
+
+		b, err2 := h.Store.Block.Get(ctx, current.BlockID)
+		if err2 == nil && space.CanViewSpace(ctx, *h.Store, b.LabelID) {
+			model.Meta.RawBody = b.RawBody
+			model.Meta.Config = b.Config
+		}
+	}
+
 	output, ok := provider.Render(model.Page.ContentType, provider.NewContext(model.Meta.OrgID, oldPageMeta.UserID, ctx), model.Meta.Config, model.Meta.RawBody)
 	if !ok {
 		h.Runtime.Log.Info("provider.Render could not find: " + model.Page.ContentType)
@@ -532,6 +681,11 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	skipRevision, err = strconv.ParseBool(request.Query(r, "r"))
 
 	err = h.Store.Page.Update(ctx, model.Page, refID, ctx.UserID, skipRevision)
+	if err == domain.ErrVersionConflict {
+		ctx.Transaction.Rollback()
+		h.writeConflict(w, ctx, method, pageID, model.Page.Body)
+		return
+	}
 	if err != nil {
 		response.WriteServerError(w, method, err)
 		ctx.Transaction.Rollback()
@@ -547,6 +701,11 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = comment.Reanchor(ctx, *h.Store, model.Page)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+	}
+
 	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
 		LabelID:      doc.LabelID,
 		SourceID:     model.Page.DocumentID,
@@ -598,10 +757,13 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 
 	updatedPage, err := h.Store.Page.Get(ctx, pageID)
 
+	w.Header().Set("ETag", etag(updatedPage))
 	response.WriteJSON(w, updatedPage)
 }
 
 // ChangePageSequence will swap page sequence for a given number of pages.
+// Each change must carry the page version it was based on and the new
+// versions are returned.
 func (h *Handler) ChangePageSequence(w http.ResponseWriter, r *http.Request) {
 	method := "page.sequence"
 	ctx := domain.GetRequestContext(r)
@@ -645,24 +807,33 @@ func (h *Handler) ChangePageSequence(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, p := range *model {
-		err = h.Store.Page.UpdateSequence(ctx, documentID, p.PageID, p.Sequence)
+	for i, p := range *model {
+		err = h.Store.Page.UpdateSequence(ctx, documentID, p.PageID, p.Sequence, p.Version)
+		if err == domain.ErrVersionConflict {
+			ctx.Transaction.Rollback()
+			h.writeConflict(w, ctx, method, p.PageID, "")
+			return
+		}
 		if err != nil {
 			ctx.Transaction.Rollback()
 			response.WriteServerError(w, method, err)
 			h.Runtime.Log.Error(method, err)
 			return
 		}
+
+		(*model)[i].Version++
 	}
 
 	h.Store.Audit.Record(ctx, audit.EventTypeSectionResequence)
 
 	ctx.Transaction.Commit()
 
-	response.WriteEmpty(w)
+	response.WriteJSON(w, model)
 }
 
 // ChangePageLevel handles page indent/outdent changes.
+// Each change must carry the page version it was based on and the new
+// versions are returned.
 func (h *Handler) ChangePageLevel(w http.ResponseWriter, r *http.Request) {
 	method := "page.level"
 	ctx := domain.GetRequestContext(r)
@@ -706,21 +877,28 @@ func (h *Handler) ChangePageLevel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	for _, p := range *model {
-		err = h.Store.Page.UpdateLevel(ctx, documentID, p.PageID, p.Level)
+	for i, p := range *model {
+		err = h.Store.Page.UpdateLevel(ctx, documentID, p.PageID, p.Level, p.Version)
+		if err == domain.ErrVersionConflict {
+			ctx.Transaction.Rollback()
+			h.writeConflict(w, ctx, method, p.PageID, "")
+			return
+		}
 		if err != nil {
 			ctx.Transaction.Rollback()
 			response.WriteServerError(w, method, err)
 			h.Runtime.Log.Error(method, err)
 			return
 		}
+
+		(*model)[i].Version++
 	}
 
 	h.Store.Audit.Record(ctx, audit.EventTypeSectionResequence)
 
 	ctx.Transaction.Commit()
 
-	response.WriteEmpty(w)
+	response.WriteJSON(w, model)
 }
 
 // GetMeta gets page meta data for specified document page.
@@ -821,6 +999,11 @@ func (h *Handler) Copy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if l, locked := h.lockedByOther(ctx, pageID); locked {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
 	// fetch data
 	doc, err := h.Store.Document.Get(ctx, documentID)
 	if err != nil {
@@ -1019,26 +1202,13 @@ func (h *Handler) GetDiff(w http.ResponseWriter, r *http.Request) {
 
 	revision, _ := h.Store.Page.GetPageRevision(ctx, revisionID)
 
-	latestHTML := p.Body
-	previousHTML := revision.Body
-	var result []byte
-
-	var cfg = &htmldiff.Config{
-		Granularity:  5,
-		InsertedSpan: []htmldiff.Attribute{{Key: "style", Val: "background-color: palegreen;"}},
-		DeletedSpan:  []htmldiff.Attribute{{Key: "style", Val: "background-color: lightpink; text-decoration: line-through;"}},
-		ReplacedSpan: []htmldiff.Attribute{{Key: "style", Val: "background-color: lightskyblue;"}},
-		CleanTags:    []string{"documize"},
-	}
-
-	res, err := cfg.HTMLdiff([]string{latestHTML, previousHTML})
+	res, err := diffHTML(p.Body, revision.Body)
 	if err != nil {
 		response.WriteServerError(w, method, err)
 		return
 	}
 
-	result = []byte(res[0])
-	w.Write(result)
+	w.Write([]byte(res))
 }
 
 // Rollback rolls back to a specific page revision.
@@ -1069,6 +1239,11 @@ func (h *Handler) Rollback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if l, locked := h.lockedByOther(ctx, pageID); locked {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
 	var err error
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
@@ -1104,6 +1279,18 @@ func (h *Handler) Rollback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Restoring earlier content makes a linked section independent of its block.
+	if p.BlockLinked {
+		err = h.Store.Block.Unlink(ctx, pageID)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+		p.BlockLinked = false
+	}
+
 	// roll back page
 	p.Body = revision.Body
 	refID := uniqueid.Generate()
@@ -1128,6 +1315,11 @@ func (h *Handler) Rollback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = comment.Reanchor(ctx, *h.Store, p)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+	}
+
 	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
 		LabelID:      doc.LabelID,
 		SourceID:     p.DocumentID,
@@ -1140,3 +1332,67 @@ func (h *Handler) Rollback(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSON(w, p)
 }
+
+// Detach turns a section linked to a reusable block into an independent
+// copy that no longer follows changes to the block.
+func (h *Handler) Detach(w http.ResponseWriter, r *http.Request) {
+	method := "page.detach"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	pageID := request.Param(r, "pageID")
+	if len(pageID) == 0 {
+		response.WriteMissingDataError(w, method, "pageID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	p, err := h.Store.Page.Get(ctx, pageID)
+	if err != nil || p.DocumentID != documentID {
+		response.WriteNotFoundError(w, method, pageID)
+		return
+	}
+
+	if !p.BlockLinked {
+		response.WriteBadRequestError(w, method, "section is not linked to a reusable block")
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Block.Unlink(ctx, pageID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeBlockDetach)
+
+	p, err = h.Store.Page.Get(ctx, pageID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(p))
+	response.WriteJSON(w, p)
+}
@@ -0,0 +1,257 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/page"
+	"github.com/pkg/errors"
+)
+
+// Lock takes the lock on a page for the current user, or renews it if
+// already held. The optional ttl query parameter sets the lease in seconds.
+// 423 Locked returns the lock when someone else holds it.
+func (h *Handler) Lock(w http.ResponseWriter, r *http.Request) {
+	h.lock(w, r, "page.lock", false)
+}
+
+// RenewLock extends the lock held by the current user.
+// Renewing a lock that has been lost to someone else fails with 423 Locked,
+// and 409 Conflict is returned when no lock is held, e.g. it has expired.
+func (h *Handler) RenewLock(w http.ResponseWriter, r *http.Request) {
+	h.lock(w, r, "page.lock.renew", true)
+}
+
+func (h *Handler) lock(w http.ResponseWriter, r *http.Request, method string, renew bool) {
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	pageID := request.Param(r, "pageID")
+	if len(pageID) == 0 {
+		response.WriteMissingDataError(w, method, "pageID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	p, err := h.Store.Page.Get(ctx, pageID)
+	if errors.Cause(err) == sql.ErrNoRows || p.DocumentID != documentID {
+		response.WriteNotFoundError(w, method, pageID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if l, locked := h.lockedByOther(ctx, pageID); locked {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
+	// renewing never takes a lock that is not already held
+	if renew {
+		_, err = h.Store.Page.GetLock(ctx, pageID)
+		if errors.Cause(err) == sql.ErrNoRows {
+			response.WriteConflictError(w, method, page.Lock{DocumentID: documentID, PageID: pageID})
+			return
+		}
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Page.AddLock(ctx, page.Lock{
+		DocumentID: documentID,
+		PageID:     pageID,
+		UserID:     ctx.UserID,
+		Expires:    time.Now().UTC().Add(lockTTL(r))})
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if renew {
+		h.Store.Audit.Record(ctx, audit.EventTypeSectionLockRenew)
+	} else {
+		h.Store.Audit.Record(ctx, audit.EventTypeSectionLock)
+	}
+
+	ctx.Transaction.Commit()
+
+	// someone else may have taken the lock since we checked
+	l, err := h.Store.Page.GetLock(ctx, pageID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	if l.UserID != ctx.UserID {
+		response.WriteLockedError(w, method, l)
+		return
+	}
+
+	response.WriteJSON(w, l)
+}
+
+// Unlock releases the lock on a page. Administrators can break
+// locks held by others using force=true. Linked sections that missed
+// block updates while locked catch up once unlocked.
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	method := "page.unlock"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	pageID := request.Param(r, "pageID")
+	if len(pageID) == 0 {
+		response.WriteMissingDataError(w, method, "pageID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	l, err := h.Store.Page.GetLock(ctx, pageID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteEmpty(w)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	if l.DocumentID != documentID {
+		response.WriteNotFoundError(w, method, pageID)
+		return
+	}
+
+	event := audit.EventTypeSectionUnlock
+	if l.UserID != ctx.UserID {
+		force, _ := strconv.ParseBool(request.Query(r, "force"))
+		if !force || !ctx.Administrator {
+			response.WriteLockedError(w, method, l)
+			return
+		}
+
+		event = audit.EventTypeSectionLockBreak
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Page.DeleteLock(ctx, pageID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, event)
+
+	ctx.Transaction.Commit()
+
+	h.resyncBlocks(ctx, documentID)
+
+	response.WriteEmpty(w)
+}
+
+// lockedByOther returns the active lock on a page when it is held by
+// someone other than the current user.
+func (h *Handler) lockedByOther(ctx domain.RequestContext, pageID string) (l page.Lock, locked bool) {
+	l, err := h.Store.Page.GetLock(ctx, pageID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		return l, false
+	}
+	if err != nil {
+		h.Runtime.Log.Error("page.lock", err)
+		return l, false
+	}
+
+	return l, l.UserID != ctx.UserID
+}
+
+// lockTTL returns the requested lease, limited to between one minute and one hour.
+func lockTTL(r *http.Request) time.Duration {
+	seconds, err := strconv.Atoi(request.Query(r, "ttl"))
+	if err != nil || seconds <= 0 {
+		return page.LockTTL
+	}
+
+	ttl := time.Duration(seconds) * time.Second
+	if ttl < time.Minute {
+		return time.Minute
+	}
+	if ttl > time.Hour {
+		return time.Hour
+	}
+
+	return ttl
+}
+
+// attachLocks notes who holds locks on the given pages.
+func (h *Handler) attachLocks(ctx domain.RequestContext, documentID string, pages []page.Page) {
+	locks, err := h.Store.Page.GetDocumentLocks(ctx, documentID)
+	if err != nil {
+		h.Runtime.Log.Error("page.locks", err)
+		return
+	}
+
+	for i := range locks {
+		for j := range pages {
+			if pages[j].RefID == locks[i].PageID {
+				pages[j].Lock = &locks[i]
+			}
+		}
+	}
+}
@@ -12,6 +12,7 @@
 package mysql
 
 import (
+	"database/sql"
 	"fmt"
 	"strings"
 	"time"
@@ -56,7 +57,7 @@ func (s Scope) Add(ctx domain.RequestContext, model page.NewPage) (err error) {
 		model.Page.Sequence = maxSeq * 2
 	}
 
-	stmt, err := ctx.Transaction.Preparex("INSERT INTO page (refid, orgid, documentid, userid, contenttype, pagetype, level, title, body, revisions, sequence, blockid, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO page (refid, orgid, documentid, userid, contenttype, pagetype, level, title, body, revisions, sequence, blockid, blocklinked, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -64,7 +65,7 @@ func (s Scope) Add(ctx domain.RequestContext, model page.NewPage) (err error) {
 		return
 	}
 
-	_, err = stmt.Exec(model.Page.RefID, model.Page.OrgID, model.Page.DocumentID, model.Page.UserID, model.Page.ContentType, model.Page.PageType, model.Page.Level, model.Page.Title, model.Page.Body, model.Page.Revisions, model.Page.Sequence, model.Page.BlockID, model.Page.Created, model.Page.Revised)
+	_, err = stmt.Exec(model.Page.RefID, model.Page.OrgID, model.Page.DocumentID, model.Page.UserID, model.Page.ContentType, model.Page.PageType, model.Page.Level, model.Page.Title, model.Page.Body, model.Page.Revisions, model.Page.Sequence, model.Page.BlockID, model.Page.BlockLinked, model.Page.Created, model.Page.Revised)
 	if err != nil {
 		err = errors.Wrap(err, "execute page insert")
 		return
@@ -90,7 +91,7 @@ func (s Scope) Add(ctx domain.RequestContext, model page.NewPage) (err error) {
 
 // Get returns the pageID page record from the page table.
 func (s Scope) Get(ctx domain.RequestContext, pageID string) (p page.Page, err error) {
-	stmt, err := s.Runtime.Db.Preparex("SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.revisions, a.blockid, a.created, a.revised FROM page a WHERE a.orgid=? AND a.refid=?")
+	stmt, err := s.Runtime.Db.Preparex("SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.revisions, a.version, a.blockid, a.blocklinked, a.created, a.revised FROM page a WHERE a.orgid=? AND a.refid=?")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -109,7 +110,7 @@ func (s Scope) Get(ctx domain.RequestContext, pageID string) (p page.Page, err e
 
 // GetPages returns a slice containing all the page records for a given documentID, in presentation sequence.
 func (s Scope) GetPages(ctx domain.RequestContext, documentID string) (p []page.Page, err error) {
-	err = s.Runtime.Db.Select(&p, "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.revisions, a.blockid, a.created, a.revised FROM page a WHERE a.orgid=? AND a.documentid=? ORDER BY a.sequence", ctx.OrgID, documentID)
+	err = s.Runtime.Db.Select(&p, "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.revisions, a.version, a.blockid, a.blocklinked, a.created, a.revised FROM page a WHERE a.orgid=? AND a.documentid=? ORDER BY a.sequence", ctx.OrgID, documentID)
 
 	if err != nil {
 		err = errors.Wrap(err, "execute get pages")
@@ -125,7 +126,7 @@ func (s Scope) GetPagesWhereIn(ctx domain.RequestContext, documentID, inPages st
 	args := []interface{}{ctx.OrgID, documentID}
 	tempValues := strings.Split(inPages, ",")
 
-	sql := "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.blockid, a.revisions, a.created, a.revised FROM page a WHERE a.orgid=? AND a.documentid=? AND a.refid IN (?" + strings.Repeat(",?", len(tempValues)-1) + ") ORDER BY sequence"
+	sql := "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.contenttype, a.pagetype, a.level, a.sequence, a.title, a.body, a.blockid, a.blocklinked, a.revisions, a.version, a.created, a.revised FROM page a WHERE a.orgid=? AND a.documentid=? AND a.refid IN (?" + strings.Repeat(",?", len(tempValues)-1) + ") ORDER BY sequence"
 
 	inValues := make([]interface{}, len(tempValues))
 
@@ -174,7 +175,7 @@ func (s Scope) GetPagesWhereIn(ctx domain.RequestContext, documentID, inPages st
 // GetPagesWithoutContent returns a slice containing all the page records for a given documentID, in presentation sequence,
 // but without the body field (which holds the HTML content).
 func (s Scope) GetPagesWithoutContent(ctx domain.RequestContext, documentID string) (pages []page.Page, err error) {
-	err = s.Runtime.Db.Select(&pages, "SELECT id, refid, orgid, documentid, userid, contenttype, pagetype, sequence, level, title, revisions, blockid, created, revised FROM page WHERE orgid=? AND documentid=? ORDER BY sequence", ctx.OrgID, documentID)
+	err = s.Runtime.Db.Select(&pages, "SELECT id, refid, orgid, documentid, userid, contenttype, pagetype, sequence, level, title, revisions, version, blockid, blocklinked, created, revised FROM page WHERE orgid=? AND documentid=? ORDER BY sequence", ctx.OrgID, documentID)
 
 	if err != nil {
 		err = errors.Wrap(err, fmt.Sprintf("Unable to execute select pages for org %s and document %s", ctx.OrgID, documentID))
@@ -210,7 +211,7 @@ func (s Scope) Update(ctx domain.RequestContext, page page.Page, refID, userID s
 
 	// Update page
 	var stmt2 *sqlx.NamedStmt
-	stmt2, err = ctx.Transaction.PrepareNamed("UPDATE page SET documentid=:documentid, level=:level, title=:title, body=:body, revisions=:revisions, sequence=:sequence, revised=:revised WHERE orgid=:orgid AND refid=:refid")
+	stmt2, err = ctx.Transaction.PrepareNamed("UPDATE page SET documentid=:documentid, level=:level, title=:title, body=:body, revisions=:revisions, sequence=:sequence, version=version+1, revised=:revised WHERE orgid=:orgid AND refid=:refid AND version=:version")
 	defer streamutil.Close(stmt2)
 
 	if err != nil {
@@ -218,12 +219,24 @@ func (s Scope) Update(ctx domain.RequestContext, page page.Page, refID, userID s
 		return
 	}
 
-	_, err = stmt2.Exec(&page)
+	result, err := stmt2.Exec(&page)
 	if err != nil {
 		err = errors.Wrap(err, "execute page insert")
 		return
 	}
 
+	err = versionChanged(result)
+	if err != nil {
+		return
+	}
+
+	// Remember who last changed the page for change notifications
+	_, err = ctx.Transaction.Exec("UPDATE page SET revisedby=? WHERE orgid=? AND refid=?", userID, ctx.OrgID, page.RefID)
+	if err != nil {
+		err = errors.Wrap(err, "execute page last editor update")
+		return
+	}
+
 	// Update revisions counter
 	if !skipRevision {
 		stmt3, err := ctx.Transaction.Preparex("UPDATE page SET revisions=revisions+1 WHERE orgid=? AND refid=?")
@@ -272,8 +285,8 @@ func (s Scope) UpdateMeta(ctx domain.RequestContext, meta page.Meta, updateUserI
 
 // UpdateSequence changes the presentation sequence of the pageID page in the document.
 // It then propagates that change into the search table and audits that it has occurred.
-func (s Scope) UpdateSequence(ctx domain.RequestContext, documentID, pageID string, sequence float64) (err error) {
-	stmt, err := ctx.Transaction.Preparex("UPDATE page SET sequence=? WHERE orgid=? AND refid=?")
+func (s Scope) UpdateSequence(ctx domain.RequestContext, documentID, pageID string, sequence float64, version uint64) (err error) {
+	stmt, err := ctx.Transaction.Preparex("UPDATE page SET sequence=?, version=version+1 WHERE orgid=? AND refid=? AND version=?")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -281,19 +294,19 @@ func (s Scope) UpdateSequence(ctx domain.RequestContext, documentID, pageID stri
 		return
 	}
 
-	_, err = stmt.Exec(sequence, ctx.OrgID, pageID)
+	result, err := stmt.Exec(sequence, ctx.OrgID, pageID, version)
 	if err != nil {
 		err = errors.Wrap(err, "execute page sequence update")
 		return
 	}
 
-	return
+	return versionChanged(result)
 }
 
 // UpdateLevel changes the heading level of the pageID page in the document.
 // It then propagates that change into the search table and audits that it has occurred.
-func (s Scope) UpdateLevel(ctx domain.RequestContext, documentID, pageID string, level int) (err error) {
-	stmt, err := ctx.Transaction.Preparex("UPDATE page SET level=? WHERE orgid=? AND refid=?")
+func (s Scope) UpdateLevel(ctx domain.RequestContext, documentID, pageID string, level int, version uint64) (err error) {
+	stmt, err := ctx.Transaction.Preparex("UPDATE page SET level=?, version=version+1 WHERE orgid=? AND refid=? AND version=?")
 	defer streamutil.Close(stmt)
 
 	if err != nil {
@@ -301,12 +314,91 @@ func (s Scope) UpdateLevel(ctx domain.RequestContext, documentID, pageID string,
 		return
 	}
 
-	_, err = stmt.Exec(level, ctx.OrgID, pageID)
+	result, err := stmt.Exec(level, ctx.OrgID, pageID, version)
 	if err != nil {
 		err = errors.Wrap(err, "execute page level update")
 		return
 	}
 
+	return versionChanged(result)
+}
+
+// versionChanged reports domain.ErrVersionConflict when a versioned
+// update matched no rows, i.e. the page was changed or removed since
+// the caller read it.
+func versionChanged(result sql.Result) (err error) {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "page version check")
+	}
+	if rows == 0 {
+		return domain.ErrVersionConflict
+	}
+
+	return nil
+}
+
+// AddLock takes or renews the lock on a page. Expired locks are replaced,
+// but an active lock held by another user is left untouched, so callers
+// should check the holder with GetLock afterwards.
+func (s Scope) AddLock(ctx domain.RequestContext, l page.Lock) (err error) {
+	now := time.Now().UTC()
+
+	_, err = ctx.Transaction.Exec("DELETE FROM pagelock WHERE orgid=? AND pageid=? AND expires<=?", ctx.OrgID, l.PageID, now)
+	if err != nil {
+		err = errors.Wrap(err, "delete expired page lock")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("INSERT INTO pagelock (orgid, documentid, pageid, userid, expires, created) VALUES (?, ?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE expires=IF(userid=VALUES(userid), VALUES(expires), expires)",
+		ctx.OrgID, l.DocumentID, l.PageID, l.UserID, l.Expires.UTC(), now)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert page lock")
+		return
+	}
+
+	return
+}
+
+// GetLock returns the active lock on a page, or sql.ErrNoRows if there is none.
+func (s Scope) GetLock(ctx domain.RequestContext, pageID string) (l page.Lock, err error) {
+	err = s.Runtime.Db.Get(&l, "SELECT a.id, a.orgid, a.documentid, a.pageid, a.userid, a.expires, a.created, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM pagelock a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.pageid=? AND a.expires>?",
+		ctx.OrgID, pageID, time.Now().UTC())
+
+	if err != nil {
+		err = errors.Wrap(err, "get page lock")
+		return
+	}
+
+	return
+}
+
+// GetDocumentLocks returns active locks on pages within a document.
+func (s Scope) GetDocumentLocks(ctx domain.RequestContext, documentID string) (l []page.Lock, err error) {
+	err = s.Runtime.Db.Select(&l, "SELECT a.id, a.orgid, a.documentid, a.pageid, a.userid, a.expires, a.created, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM pagelock a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.documentid=? AND a.expires>?",
+		ctx.OrgID, documentID, time.Now().UTC())
+
+	if err != nil {
+		err = errors.Wrap(err, "get document page locks")
+		return
+	}
+
+	return
+}
+
+// DeleteLock releases the lock on a page whoever holds it.
+func (s Scope) DeleteLock(ctx domain.RequestContext, pageID string) (rows int64, err error) {
+	result, err := ctx.Transaction.Exec("DELETE FROM pagelock WHERE orgid=? AND pageid=?", ctx.OrgID, pageID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete lock for page %s", pageID))
+		return
+	}
+
+	rows, err = result.RowsAffected()
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete lock for page %s", pageID))
+	}
+
 	return
 }
 
@@ -412,6 +504,38 @@ func (s Scope) GetDocumentRevisions(ctx domain.RequestContext, documentID string
 	return
 }
 
+// GetDocumentRevisionsSince returns revisions of document pages made after
+// the given time, oldest first, including content.
+func (s Scope) GetDocumentRevisionsSince(ctx domain.RequestContext, documentID string, since time.Time) (revisions []page.Revision, err error) {
+	err = s.Runtime.Db.Select(&revisions, "SELECT a.id, a.refid, a.orgid, a.documentid, a.ownerid, a.pageid, a.userid, a.contenttype, a.pagetype, a.title, coalesce(a.body,'') as body, coalesce(a.rawbody,'') as rawbody, coalesce(a.config,'') as config, a.created, a.revised FROM revision a WHERE a.orgid=? AND a.documentid=? AND a.created>? ORDER BY a.id", ctx.OrgID, documentID, since.UTC())
+
+	if err != nil {
+		err = errors.Wrap(err, "get document revisions since")
+		return
+	}
+
+	return
+}
+
+// AddRevision inserts a page revision as it was, used when restoring deleted pages.
+func (s Scope) AddRevision(ctx domain.RequestContext, r page.Revision) (err error) {
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO revision (refid, orgid, documentid, ownerid, pageid, userid, contenttype, pagetype, title, body, rawbody, config, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare revision insert")
+		return
+	}
+
+	_, err = stmt.Exec(r.RefID, ctx.OrgID, r.DocumentID, r.OwnerID, r.PageID, r.UserID, r.ContentType, r.PageType, r.Title, r.Body, r.RawBody, r.Config, r.Created, r.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute revision insert")
+		return
+	}
+
+	return
+}
+
 // DeletePageRevisions deletes all of the page revision records for a given pageID.
 func (s Scope) DeletePageRevisions(ctx domain.RequestContext, pageID string) (rows int64, err error) {
 	b := mysql.BaseQuery{}
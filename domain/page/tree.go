@@ -0,0 +1,386 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/page"
+)
+
+// GetTree returns the document's sections as an outline.
+// Pass ?numbered=true for outline numbers.
+func (h *Handler) GetTree(w http.ResponseWriter, r *http.Request) {
+	method := "page.tree"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	pages, err := h.Store.Page.GetPagesWithoutContent(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, buildTree(pages, request.Query(r, "numbered") == "true"))
+}
+
+// GetContents returns a table of contents for the document as HTML.
+// Pass ?numbered=true for outline numbers.
+func (h *Handler) GetContents(w http.ResponseWriter, r *http.Request) {
+	method := "page.contents"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	pages, err := h.Store.Page.GetPagesWithoutContent(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, struct {
+		Contents string `json:"contents"`
+	}{tableOfContents(buildTree(pages, request.Query(r, "numbered") == "true"))})
+}
+
+// MoveSubtree moves, indents or outdents a section together with the
+// sections nested beneath it in one change, then renormalizes sequences.
+func (h *Handler) MoveSubtree(w http.ResponseWriter, r *http.Request) {
+	method := "page.moveSubtree"
+	ctx := domain.GetRequestContext(r)
+
+	if !h.Runtime.Product.License.IsValid() {
+		response.WriteBadLicense(w)
+		return
+	}
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	model := page.TreeMoveRequest{}
+	err = json.Unmarshal(body, &model)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	pages, err := h.Store.Page.GetPagesWithoutContent(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for _, p := range pages {
+		if p.RefID == model.PageID && p.Version != model.Version {
+			h.writeConflict(w, ctx, method, p.RefID, "")
+			return
+		}
+	}
+
+	moved, err := moveSubtree(pages, model.PageID, model.After, model.Indent)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	renormalize(moved)
+
+	h.saveOutline(w, ctx, method, pages, moved)
+}
+
+// Renormalize spaces section sequences evenly, keeping their order.
+func (h *Handler) Renormalize(w http.ResponseWriter, r *http.Request) {
+	method := "page.renormalize"
+	ctx := domain.GetRequestContext(r)
+
+	if !h.Runtime.Product.License.IsValid() {
+		response.WriteBadLicense(w)
+		return
+	}
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	pages, err := h.Store.Page.GetPagesWithoutContent(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ordered := make([]page.Page, len(pages))
+	copy(ordered, pages)
+	renormalize(ordered)
+
+	h.saveOutline(w, ctx, method, pages, ordered)
+}
+
+// saveOutline stores the sequence and level of sections that changed
+// between before and after, and responds with the new outline.
+func (h *Handler) saveOutline(w http.ResponseWriter, ctx domain.RequestContext, method string, before, after []page.Page) {
+	previous := make(map[string]page.Page)
+	for _, p := range before {
+		previous[p.RefID] = p
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for i, p := range after {
+		old := previous[p.RefID]
+
+		if old.Sequence != p.Sequence {
+			err = h.Store.Page.UpdateSequence(ctx, p.DocumentID, p.RefID, p.Sequence, p.Version)
+			if err == nil {
+				p.Version++
+			}
+		}
+		if err == nil && old.Level != p.Level {
+			err = h.Store.Page.UpdateLevel(ctx, p.DocumentID, p.RefID, int(p.Level), p.Version)
+			if err == nil {
+				p.Version++
+			}
+		}
+
+		if err == domain.ErrVersionConflict {
+			ctx.Transaction.Rollback()
+			h.writeConflict(w, ctx, method, p.RefID, "")
+			return
+		}
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		after[i] = p
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSectionResequence)
+
+	ctx.Transaction.Commit()
+
+	response.WriteJSON(w, buildTree(after, false))
+}
+
+// sequenceStep is the gap left between sections when sequences are
+// renormalized, so sections can be inserted between them.
+const sequenceStep = 1024
+
+// buildTree nests sections beneath the closest preceding section with a
+// lower level. Pages must be in sequence order. When numbered, sections
+// are given outline numbers such as 1, 1.1 and 1.2.
+func buildTree(pages []page.Page, numbered bool) []*page.Node {
+	roots := []*page.Node{}
+	stack := []*page.Node{}
+
+	for _, p := range pages {
+		n := &page.Node{PageID: p.RefID, Title: p.Title, Level: p.Level, Sequence: p.Sequence, Version: p.Version, Children: []*page.Node{}}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= n.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			roots = append(roots, n)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+		}
+
+		stack = append(stack, n)
+	}
+
+	if numbered {
+		number(roots, "")
+	}
+
+	return roots
+}
+
+func number(nodes []*page.Node, prefix string) {
+	for i, n := range nodes {
+		n.Number = fmt.Sprintf("%s%d", prefix, i+1)
+		number(n.Children, n.Number+".")
+	}
+}
+
+// subtreeEnd returns the index just past the sections nested beneath pages[i].
+func subtreeEnd(pages []page.Page, i int) int {
+	end := i + 1
+	for end < len(pages) && pages[end].Level > pages[i].Level {
+		end++
+	}
+
+	return end
+}
+
+// moveSubtree moves a section and the sections nested beneath it after
+// another section, or to the start when after is empty, and shifts their
+// levels by indent. A nil after keeps the subtree where it is. Pages must
+// be in sequence order; the result is in the new order.
+func moveSubtree(pages []page.Page, pageID string, after *string, indent int) ([]page.Page, error) {
+	start := -1
+	for i := range pages {
+		if pages[i].RefID == pageID {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, errors.New("section not found")
+	}
+
+	end := subtreeEnd(pages, start)
+
+	moved := make([]page.Page, end-start)
+	copy(moved, pages[start:end])
+
+	for i := range moved {
+		level := int(moved[i].Level) + indent
+		if level < 1 {
+			return nil, errors.New("section cannot be outdented further")
+		}
+		moved[i].Level = uint64(level)
+	}
+
+	rest := make([]page.Page, 0, len(pages)-len(moved))
+	rest = append(rest, pages[:start]...)
+	rest = append(rest, pages[end:]...)
+
+	at := start
+	if after != nil {
+		at = -1
+		if len(*after) == 0 {
+			at = 0
+		}
+		for i := range rest {
+			if rest[i].RefID == *after {
+				at = i + 1
+				break
+			}
+		}
+		if at < 0 {
+			for _, m := range moved {
+				if m.RefID == *after {
+					return nil, errors.New("section cannot be moved within itself")
+				}
+			}
+			return nil, errors.New("target section not found")
+		}
+	}
+
+	result := make([]page.Page, 0, len(pages))
+	result = append(result, rest[:at]...)
+	result = append(result, moved...)
+	result = append(result, rest[at:]...)
+
+	return result, nil
+}
+
+// renormalize spaces sequences evenly in the current order.
+func renormalize(pages []page.Page) {
+	for i := range pages {
+		pages[i].Sequence = float64((i + 1) * sequenceStep)
+	}
+}
+
+// tableOfContents renders the outline as nested lists linking to each section.
+func tableOfContents(nodes []*page.Node) string {
+	var b bytes.Buffer
+	writeContents(&b, nodes)
+	return b.String()
+}
+
+func writeContents(b *bytes.Buffer, nodes []*page.Node) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	b.WriteString(`<ol class="toc">`)
+	for _, n := range nodes {
+		title := html.EscapeString(n.Title)
+		if len(n.Number) > 0 {
+			title = n.Number + " " + title
+		}
+
+		fmt.Fprintf(b, `<li><a href="#page-%s">%s</a>`, n.PageID, title)
+		writeContents(b, n.Children)
+		b.WriteString(`</li>`)
+	}
+	b.WriteString(`</ol>`)
+}
@@ -0,0 +1,96 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/documize/community/model/page"
+)
+
+// outline builds pages from "id:level" pairs in sequence order.
+func outline(spec ...string) (pages []page.Page) {
+	for i, s := range spec {
+		parts := strings.Split(s, ":")
+		p := page.Page{Sequence: float64(i + 1)}
+		p.RefID = parts[0]
+		p.Level = uint64(parts[1][0] - '0')
+		pages = append(pages, p)
+	}
+	return
+}
+
+func describe(pages []page.Page) string {
+	var s []string
+	for _, p := range pages {
+		s = append(s, p.RefID+":"+string(rune('0'+p.Level)))
+	}
+	return strings.Join(s, " ")
+}
+
+func TestBuildTree(t *testing.T) {
+	tree := buildTree(outline("a:1", "b:2", "c:3", "d:2", "e:1"), true)
+
+	if len(tree) != 2 || len(tree[0].Children) != 2 || len(tree[0].Children[0].Children) != 1 {
+		t.Fatalf("unexpected nesting")
+	}
+	if tree[0].Children[1].Number != "1.2" || tree[0].Children[0].Children[0].Number != "1.1.1" || tree[1].Number != "2" {
+		t.Errorf("unexpected numbering")
+	}
+}
+
+func TestMoveSubtree(t *testing.T) {
+	pages := outline("a:1", "b:2", "c:3", "d:2", "e:1")
+	after := func(s string) *string { return &s }
+
+	tests := []struct {
+		pageID string
+		after  *string
+		indent int
+		want   string
+	}{
+		{"b", after("e"), 0, "a:1 d:2 e:1 b:2 c:3"},
+		{"b", after(""), -1, "b:1 c:2 a:1 d:2 e:1"},
+		{"e", nil, 1, "a:1 b:2 c:3 d:2 e:2"},
+		{"d", after("a"), 0, "a:1 d:2 b:2 c:3 e:1"},
+	}
+
+	for _, test := range tests {
+		got, err := moveSubtree(pages, test.pageID, test.after, test.indent)
+		if err != nil {
+			t.Errorf("move %s: %v", test.pageID, err)
+			continue
+		}
+		if describe(got) != test.want {
+			t.Errorf("move %s = %s, want %s", test.pageID, describe(got), test.want)
+		}
+	}
+
+	if _, err := moveSubtree(pages, "b", after("c"), 0); err == nil {
+		t.Error("expected error moving a section within itself")
+	}
+	if _, err := moveSubtree(pages, "a", nil, -1); err == nil {
+		t.Error("expected error outdenting past the top level")
+	}
+}
+
+func TestTableOfContents(t *testing.T) {
+	pages := outline("a:1", "b:2")
+	pages[0].Title = "Intro & scope"
+
+	got := tableOfContents(buildTree(pages, true))
+	want := `<ol class="toc"><li><a href="#page-a">1 Intro &amp; scope</a><ol class="toc"><li><a href="#page-b">1.1 </a></li></ol></li></ol>`
+	if got != want {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package page
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/page"
+	htmldiff "github.com/documize/html-diff"
+)
+
+// etag identifies the version of a page for If-Match headers.
+func etag(p page.Page) string {
+	return fmt.Sprintf(`"%d"`, p.Version)
+}
+
+// baseVersion returns the page version the client edited, taken from
+// the If-Match header when present, otherwise from the request body.
+func baseVersion(r *http.Request, body uint64) (version uint64, err error) {
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if len(match) == 0 {
+		return body, nil
+	}
+
+	match = strings.Trim(strings.TrimPrefix(match, "W/"), `"`)
+
+	return strconv.ParseUint(match, 10, 64)
+}
+
+// diffHTML marks up the changes needed to turn from into to.
+func diffHTML(from, to string) (string, error) {
+	var cfg = &htmldiff.Config{
+		Granularity:  5,
+		InsertedSpan: []htmldiff.Attribute{{Key: "style", Val: "background-color: palegreen;"}},
+		DeletedSpan:  []htmldiff.Attribute{{Key: "style", Val: "background-color: lightpink; text-decoration: line-through;"}},
+		ReplacedSpan: []htmldiff.Attribute{{Key: "style", Val: "background-color: lightskyblue;"}},
+		CleanTags:    []string{"documize"},
+	}
+
+	res, err := cfg.HTMLdiff([]string{from, to})
+	if err != nil {
+		return "", err
+	}
+
+	return res[0], nil
+}
+
+// writeConflict responds with the current version of a page that was
+// changed by someone else, and how it differs from the rejected body.
+func (h *Handler) writeConflict(w http.ResponseWriter, ctx domain.RequestContext, method, pageID, rejected string) {
+	current, err := h.Store.Page.Get(ctx, pageID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, pageID)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	conflict := page.Conflict{Page: current}
+
+	if len(rejected) > 0 {
+		conflict.Diff, err = diffHTML(current.Body, rejected)
+		if err != nil {
+			h.Runtime.Log.Error(method, err)
+		}
+	}
+
+	w.Header().Set("ETag", etag(current))
+	response.WriteConflictError(w, method, conflict)
+}
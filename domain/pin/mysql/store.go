@@ -91,6 +91,18 @@ func (s Scope) GetUserPins(ctx domain.RequestContext, userID string) (pins []pin
 	return
 }
 
+// GetDocumentPins returns every user's pins for a document.
+func (s Scope) GetDocumentPins(ctx domain.RequestContext, documentID string) (pins []pin.Pin, err error) {
+	err = s.Runtime.Db.Select(&pins, "SELECT id, refid, orgid, userid, labelid as folderid, documentid, pin, sequence, created, revised FROM pin WHERE orgid=? AND documentid=?", ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute select pins for org %s and document %s", ctx.OrgID, documentID))
+		return
+	}
+
+	return
+}
+
 // UpdatePin updates existing pinned item.
 func (s Scope) UpdatePin(ctx domain.RequestContext, pin pin.Pin) (err error) {
 	pin.Revised = time.Now().UTC()
@@ -171,18 +171,63 @@ func (s Scope) IndexContent(ctx domain.RequestContext, p page.Page) (err error)
 	return nil
 }
 
+// IndexItems replaces search entries contributed by a section for its parts,
+// e.g. individual API operations. Each entry links back to the section.
+func (s Scope) IndexItems(ctx domain.RequestContext, p page.Page, items []string) (err error) {
+	var stmt1 *sqlx.Stmt
+	stmt1, err = ctx.Transaction.Preparex("DELETE FROM search WHERE orgid=? AND documentid=? AND itemid=? AND itemtype='item'")
+	defer streamutil.Close(stmt1)
+	if err != nil {
+		err = errors.Wrap(err, "prepare delete section item entries")
+		return
+	}
+
+	_, err = stmt1.Exec(ctx.OrgID, p.DocumentID, p.RefID)
+	if err != nil {
+		err = errors.Wrap(err, "execute delete section item entries")
+		return
+	}
+
+	if len(items) == 0 {
+		return
+	}
+
+	var stmt2 *sqlx.Stmt
+	stmt2, err = ctx.Transaction.Preparex("INSERT INTO search (orgid, documentid, itemid, itemtype, content) VALUES (?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt2)
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert section item entry")
+		return
+	}
+
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		_, err = stmt2.Exec(ctx.OrgID, p.DocumentID, p.RefID, "item", item)
+		if err != nil {
+			err = errors.Wrap(err, "execute insert section item entry")
+			return
+		}
+	}
+
+	return
+}
+
 // DeleteContent removes all search entries for specific document content.
 func (s Scope) DeleteContent(ctx domain.RequestContext, pageID string) (err error) {
 	// remove all search entries
 	var stmt1 *sqlx.Stmt
-	stmt1, err = ctx.Transaction.Preparex("DELETE FROM search WHERE orgid=? AND itemid=? AND itemtype=?")
+	stmt1, err = ctx.Transaction.Preparex("DELETE FROM search WHERE orgid=? AND itemid=? AND itemtype IN ('page', 'item')")
 	defer streamutil.Close(stmt1)
 	if err != nil {
 		err = errors.Wrap(err, "prepare delete document content entry")
 		return
 	}
 
-	_, err = stmt1.Exec(ctx.OrgID, pageID, "page")
+	_, err = stmt1.Exec(ctx.OrgID, pageID)
 	if err != nil {
 		err = errors.Wrap(err, "execute delete document content entry")
 		return
@@ -204,7 +249,7 @@ func (s Scope) Documents(ctx domain.RequestContext, q search.QueryOptions) (resu
 
 	// Match doc names
 	if q.Doc {
-		r1, err1 := s.matchFullText(ctx, q.Keywords, "doc")
+		r1, err1 := s.matchFullText(ctx, q.Keywords, "doc", q.Archived)
 		if err1 != nil {
 			err = errors.Wrap(err1, "search document names")
 			return
@@ -215,18 +260,36 @@ func (s Scope) Documents(ctx domain.RequestContext, q search.QueryOptions) (resu
 
 	// Match doc content
 	if q.Content {
-		r2, err2 := s.matchFullText(ctx, q.Keywords, "page")
+		r2, err2 := s.matchFullText(ctx, q.Keywords, "page", q.Archived)
 		if err2 != nil {
 			err = errors.Wrap(err2, "search document content")
 			return
 		}
 
 		results = append(results, r2...)
+
+		// section parts, skipping sections already matched as a whole
+		r5, err5 := s.matchFullText(ctx, q.Keywords, "item", q.Archived)
+		if err5 != nil {
+			err = errors.Wrap(err5, "search document content items")
+			return
+		}
+
+		matched := make(map[string]bool)
+		for _, r := range r2 {
+			matched[r.ItemID] = true
+		}
+		for _, r := range r5 {
+			if !matched[r.ItemID] {
+				matched[r.ItemID] = true
+				results = append(results, r)
+			}
+		}
 	}
 
 	// Match doc tags
 	if q.Tag {
-		r3, err3 := s.matchFullText(ctx, q.Keywords, "tag")
+		r3, err3 := s.matchFullText(ctx, q.Keywords, "tag", q.Archived)
 		if err3 != nil {
 			err = errors.Wrap(err3, "search document tag")
 			return
@@ -237,7 +300,7 @@ func (s Scope) Documents(ctx domain.RequestContext, q search.QueryOptions) (resu
 
 	// Match doc attachments
 	if q.Attachment {
-		r4, err4 := s.matchLike(ctx, q.Keywords, "file")
+		r4, err4 := s.matchLike(ctx, q.Keywords, "file", q.Archived)
 		if err4 != nil {
 			err = errors.Wrap(err4, "search document attachments")
 			return
@@ -249,7 +312,7 @@ func (s Scope) Documents(ctx domain.RequestContext, q search.QueryOptions) (resu
 	return
 }
 
-func (s Scope) matchFullText(ctx domain.RequestContext, keywords, itemType string) (r []search.QueryResult, err error) {
+func (s Scope) matchFullText(ctx domain.RequestContext, keywords, itemType string, archived bool) (r []search.QueryResult, err error) {
 	sql1 := `
 	SELECT 
 		s.id, s.orgid, s.documentid, s.itemid, s.itemtype, 
@@ -268,7 +331,7 @@ func (s Scope) matchFullText(ctx domain.RequestContext, keywords, itemType strin
 		AND d.labelid IN (SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
 			UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 			UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
-		AND MATCH(s.content) AGAINST(? IN BOOLEAN MODE)`
+		AND MATCH(s.content) AGAINST(? IN BOOLEAN MODE)` + lifecycleFilter(archived)
 
 	err = s.Runtime.Db.Select(&r,
 		sql1,
@@ -296,7 +359,7 @@ func (s Scope) matchFullText(ctx domain.RequestContext, keywords, itemType strin
 	return
 }
 
-func (s Scope) matchLike(ctx domain.RequestContext, keywords, itemType string) (r []search.QueryResult, err error) {
+func (s Scope) matchLike(ctx domain.RequestContext, keywords, itemType string, archived bool) (r []search.QueryResult, err error) {
 	// LIKE clause does not like quotes!
 	keywords = strings.Replace(keywords, "'", "", -1)
 	keywords = strings.Replace(keywords, "\"", "", -1)
@@ -321,7 +384,7 @@ func (s Scope) matchLike(ctx domain.RequestContext, keywords, itemType string) (
 		AND d.labelid IN (SELECT refid from label WHERE orgid=? AND type=2 AND userid=?
 			UNION ALL SELECT refid FROM label a where orgid=? AND type=1 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid='' AND (canedit=1 OR canview=1))
 			UNION ALL SELECT refid FROM label a where orgid=? AND type=3 AND refid IN (SELECT labelid from labelrole WHERE orgid=? AND userid=? AND (canedit=1 OR canview=1)))
-		AND s.content LIKE ?`
+		AND s.content LIKE ?` + lifecycleFilter(archived)
 
 	err = s.Runtime.Db.Select(&r,
 		sql1,
@@ -348,3 +411,12 @@ func (s Scope) matchLike(ctx domain.RequestContext, keywords, itemType string) (
 
 	return
 }
+
+// lifecycleFilter leaves out archived documents unless they were asked for.
+func lifecycleFilter(archived bool) string {
+	if archived {
+		return ""
+	}
+
+	return fmt.Sprintf(" AND d.lifecycle<>%d", doc.LifecycleArchived)
+}
@@ -13,6 +13,7 @@ package search
 
 import (
 	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/section/provider"
 	"github.com/documize/community/model/attachment"
 	"github.com/documize/community/model/doc"
 	"github.com/documize/community/model/page"
@@ -63,10 +64,17 @@ func (m *Indexer) DeleteDocument(ctx domain.RequestContext, ID string) {
 
 // IndexContent adds search index entry for document context.
 // Any existing document entries are removed.
+// Sections may contribute further entries for parts of their content.
 func (m *Indexer) IndexContent(ctx domain.RequestContext, p page.Page) {
 	method := "search.IndexContent"
 	var err error
 
+	var items []string
+	meta, err := m.store.Page.GetPageMeta(ctx, p.RefID)
+	if err == nil {
+		items = provider.SearchItems(p.ContentType, meta.Config, meta.RawBody)
+	}
+
 	ctx.Transaction, err = m.runtime.Db.Beginx()
 	if err != nil {
 		m.runtime.Log.Error(method, err)
@@ -80,6 +88,13 @@ func (m *Indexer) IndexContent(ctx domain.RequestContext, p page.Page) {
 		return
 	}
 
+	err = m.store.Search.IndexItems(ctx, p, items)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		m.runtime.Log.Error(method, err)
+		return
+	}
+
 	ctx.Transaction.Commit()
 }
 
@@ -0,0 +1,172 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package chart
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/section/provider"
+)
+
+const me = "chart"
+
+// previewRows is how many rows the editor shows for column mapping.
+const previewRows = 10
+
+// Provider represents a chart drawn from CSV or JSON data.
+type Provider struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// Meta describes us.
+func (*Provider) Meta() provider.TypeMeta {
+	section := provider.TypeMeta{}
+	section.ID = "c3f6eb53-2ba7-467d-b464-1b37b8640587"
+	section.Title = "Chart"
+	section.Description = "Line, bar, pie and stacked charts from CSV or JSON"
+	section.ContentType = "chart"
+	section.PageType = "section"
+
+	return section
+}
+
+// Command previews column mapping and loads data from attachments.
+func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		provider.WriteMessage(w, me, "Bad payload")
+		return
+	}
+
+	cmd := chartCommand{}
+	err = json.Unmarshal(body, &cmd)
+	if err != nil {
+		provider.WriteMessage(w, me, "Bad payload")
+		return
+	}
+
+	cmd.Config.Clean()
+
+	switch method {
+	case "preview":
+		provider.WriteJSON(w, preview(cmd.Config, cmd.Data))
+	case "attachment":
+		p.attachment(ctx, w, r, cmd.Config)
+	default:
+		provider.WriteMessage(w, me, "unknown method name")
+	}
+}
+
+// Render draws the chart as static SVG.
+func (p *Provider) Render(ctx *provider.Context, config, data string) string {
+	c := chartConfig{}
+	json.Unmarshal([]byte(config), &c)
+	c.Clean()
+
+	svg, err := render(c, data)
+	if err != nil {
+		return fmt.Sprintf(`<p class="color-red">Unable to draw chart: %s</p>`, template.HTMLEscapeString(err.Error()))
+	}
+
+	return svg
+}
+
+// Refresh just sends back data as-is.
+func (*Provider) Refresh(ctx *provider.Context, config, data string) string {
+	return data
+}
+
+// attachment returns text of a document attachment for use as chart data.
+func (p *Provider) attachment(ctx *provider.Context, w http.ResponseWriter, r *http.Request, c chartConfig) {
+	a, err := p.Store.Attachment.GetAttachment(ctx.Request, ctx.OrgID, c.AttachmentID)
+	if err != nil {
+		p.Runtime.Log.Error("unable to get chart attachment", err)
+		provider.WriteMessage(w, me, "attachment not found")
+		return
+	}
+
+	// command permissions were checked against this document
+	if a.DocumentID != r.URL.Query().Get("documentID") {
+		provider.WriteForbidden(w)
+		return
+	}
+
+	if !utf8.Valid(a.Data) {
+		provider.WriteMessage(w, me, "attachment is not CSV or JSON text")
+		return
+	}
+
+	var result struct {
+		Data string `json:"data"`
+	}
+	result.Data = string(a.Data)
+
+	provider.WriteJSON(w, result)
+}
+
+func render(c chartConfig, data string) (string, error) {
+	t, err := parseData(data)
+	if err != nil {
+		return "", err
+	}
+
+	chart, err := t.chart(c)
+	if err != nil {
+		return "", err
+	}
+
+	return draw(c, chart), nil
+}
+
+// preview describes data columns and draws chart using current mapping.
+func preview(c chartConfig, data string) (pv chartPreview) {
+	pv.Columns = []string{}
+	pv.Numeric = []string{}
+	pv.Rows = [][]string{}
+
+	t, err := parseData(data)
+	if err != nil {
+		pv.Error = err.Error()
+		return
+	}
+
+	pv.Columns = t.Columns
+	if n := t.numeric(); len(n) > 0 {
+		pv.Numeric = n
+	}
+	if len(t.Rows) > previewRows {
+		pv.Rows = t.Rows[:previewRows]
+	} else if len(t.Rows) > 0 {
+		pv.Rows = t.Rows
+	}
+
+	chart, err := t.chart(c)
+	if err != nil {
+		pv.Error = err.Error()
+		return
+	}
+
+	pv.SVG = draw(c, chart)
+
+	return
+}
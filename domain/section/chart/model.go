@@ -0,0 +1,348 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package chart
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/documize/community/core/svgchart"
+)
+
+// Chart types.
+const (
+	typeLine    = "line"
+	typeBar     = "bar"
+	typePie     = "pie"
+	typeStacked = "stacked"
+)
+
+// maxRows limits how much data a chart plots.
+const maxRows = 500
+
+// chartConfig is saved as page meta config by the section editor.
+// Data itself is kept in page meta raw body.
+type chartConfig struct {
+	Type         string   `json:"type"`         // line, bar, pie or stacked
+	Title        string   `json:"title"`        // shown above chart and read by screen readers
+	LabelColumn  string   `json:"labelColumn"`  // category column, defaults to first
+	ValueColumns []string `json:"valueColumns"` // plotted columns, defaults to all numeric
+	AttachmentID string   `json:"attachmentId"` // attachment data was last loaded from
+	Width        int      `json:"width"`
+	Height       int      `json:"height"`
+}
+
+// Clean removes surrounding whitespace and applies defaults.
+func (c *chartConfig) Clean() {
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	c.Title = strings.TrimSpace(c.Title)
+	c.LabelColumn = strings.TrimSpace(c.LabelColumn)
+	c.AttachmentID = strings.TrimSpace(c.AttachmentID)
+
+	switch c.Type {
+	case typeLine, typeBar, typePie, typeStacked:
+	default:
+		c.Type = typeBar
+	}
+
+	var cols []string
+	for _, v := range c.ValueColumns {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			cols = append(cols, v)
+		}
+	}
+	c.ValueColumns = cols
+}
+
+// table is tabular data read from CSV or JSON.
+type table struct {
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// parseData reads JSON (array of objects or array of arrays with a
+// header row) when data looks like JSON, otherwise CSV with a header row.
+func parseData(data string) (t table, err error) {
+	data = strings.TrimSpace(strings.TrimPrefix(data, "\ufeff"))
+	if len(data) == 0 {
+		return t, errors.New("no data")
+	}
+
+	if strings.HasPrefix(data, "[") {
+		t, err = parseJSON(data)
+	} else {
+		t, err = parseCSV(data)
+	}
+	if err != nil {
+		return
+	}
+
+	if len(t.Columns) == 0 {
+		return t, errors.New("data has no columns")
+	}
+	if len(t.Rows) > maxRows {
+		t.Rows = t.Rows[:maxRows]
+	}
+
+	return
+}
+
+func parseCSV(data string) (t table, err error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return t, fmt.Errorf("unable to read CSV: %s", err)
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	t.Columns = records[0]
+	for _, rec := range records[1:] {
+		t.Rows = append(t.Rows, pad(rec, len(t.Columns)))
+	}
+
+	return
+}
+
+func parseJSON(data string) (t table, err error) {
+	var items []json.RawMessage
+	err = json.Unmarshal([]byte(data), &items)
+	if err != nil {
+		return t, fmt.Errorf("unable to read JSON: %s", err)
+	}
+
+	for i, item := range items {
+		item = bytes.TrimSpace(item)
+
+		if bytes.HasPrefix(item, []byte("[")) {
+			var values []interface{}
+			if err = json.Unmarshal(item, &values); err != nil {
+				return t, fmt.Errorf("unable to read JSON row %d: %s", i+1, err)
+			}
+			if i == 0 {
+				t.Columns = text(values)
+			} else {
+				t.Rows = append(t.Rows, pad(text(values), len(t.Columns)))
+			}
+			continue
+		}
+
+		keys, values, err := object(item)
+		if err != nil {
+			return t, fmt.Errorf("unable to read JSON row %d: %s", i+1, err)
+		}
+		for _, k := range keys {
+			if indexOf(t.Columns, k) == -1 {
+				t.Columns = append(t.Columns, k)
+			}
+		}
+
+		row := make([]string, len(t.Columns))
+		for j, c := range t.Columns {
+			row[j] = values[c]
+		}
+		t.Rows = append(t.Rows, row)
+	}
+
+	// rows read before later objects added columns
+	for i := range t.Rows {
+		t.Rows[i] = pad(t.Rows[i], len(t.Columns))
+	}
+
+	return
+}
+
+// object decodes a JSON object keeping key order.
+func object(data []byte) (keys []string, values map[string]string, err error) {
+	values = make(map[string]string)
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		return nil, nil, errors.New("expected object or array")
+	}
+
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return
+		}
+		key, _ := tok.(string)
+
+		var v interface{}
+		if err = dec.Decode(&v); err != nil {
+			return
+		}
+
+		keys = append(keys, key)
+		values[key] = text([]interface{}{v})[0]
+	}
+
+	return
+}
+
+// text converts decoded JSON values to strings.
+func text(values []interface{}) (s []string) {
+	for _, v := range values {
+		switch t := v.(type) {
+		case nil:
+			s = append(s, "")
+		case string:
+			s = append(s, t)
+		default:
+			s = append(s, fmt.Sprintf("%v", t))
+		}
+	}
+	return
+}
+
+// pad sizes row to given length.
+func pad(row []string, n int) []string {
+	for len(row) < n {
+		row = append(row, "")
+	}
+	return row[:n]
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if strings.EqualFold(x, s) {
+			return i
+		}
+	}
+	return -1
+}
+
+// numeric reports which columns hold only numbers (ignoring blanks).
+func (t table) numeric() (cols []string) {
+	for i, c := range t.Columns {
+		ok, seen := true, false
+		for _, r := range t.Rows {
+			v := strings.TrimSpace(r[i])
+			if len(v) == 0 {
+				continue
+			}
+			seen = true
+			if _, err := number(v); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok && seen {
+			cols = append(cols, c)
+		}
+	}
+	return
+}
+
+// number parses values such as "1,234.5", "$12" or "45%".
+func number(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	v = strings.TrimLeft(v, "$€£¥")
+	v = strings.TrimSuffix(v, "%")
+	v = strings.Replace(v, ",", "", -1)
+	return strconv.ParseFloat(v, 64)
+}
+
+// chart maps table columns onto chart labels and series.
+func (t table) chart(c chartConfig) (chart svgchart.Chart, err error) {
+	label := indexOf(t.Columns, c.LabelColumn)
+	if len(c.LabelColumn) == 0 {
+		label = 0
+	}
+	if label == -1 {
+		return chart, fmt.Errorf("label column %s not found", c.LabelColumn)
+	}
+
+	var values []int
+	for _, v := range c.ValueColumns {
+		i := indexOf(t.Columns, v)
+		if i == -1 {
+			return chart, fmt.Errorf("value column %s not found", v)
+		}
+		values = append(values, i)
+	}
+	if len(values) == 0 {
+		for _, n := range t.numeric() {
+			if i := indexOf(t.Columns, n); i != label {
+				values = append(values, i)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return chart, errors.New("no numeric columns to plot")
+	}
+
+	chart.Title = c.Title
+	chart.Width = c.Width
+	chart.Height = c.Height
+
+	for _, i := range values {
+		chart.Series = append(chart.Series, svgchart.Series{Name: t.Columns[i]})
+	}
+
+	for _, row := range t.Rows {
+		chart.Labels = append(chart.Labels, row[label])
+		for j, i := range values {
+			v, err := number(row[i])
+			if err != nil && len(strings.TrimSpace(row[i])) > 0 {
+				return chart, fmt.Errorf("%s is not a number in column %s", row[i], t.Columns[i])
+			}
+			chart.Series[j].Values = append(chart.Series[j].Values, v)
+		}
+	}
+
+	return
+}
+
+// draw renders chart of configured type.
+func draw(c chartConfig, chart svgchart.Chart) string {
+	switch c.Type {
+	case typeLine:
+		return svgchart.Line(chart)
+	case typePie:
+		return svgchart.Pie(chart)
+	case typeStacked:
+		return svgchart.Stacked(chart)
+	default:
+		return svgchart.Bar(chart)
+	}
+}
+
+// chartPreview is sent back to the editor for column mapping.
+type chartPreview struct {
+	Columns []string   `json:"columns"`
+	Numeric []string   `json:"numeric"`
+	Rows    [][]string `json:"rows"` // first few rows
+	SVG     string     `json:"svg"`
+	Error   string     `json:"error"`
+}
+
+// chartCommand is sent by the editor.
+type chartCommand struct {
+	Config chartConfig `json:"config"`
+	Data   string      `json:"data"`
+}
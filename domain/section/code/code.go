@@ -12,8 +12,16 @@
 package code
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
 	"net/http"
 
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/section/provider"
@@ -39,17 +47,117 @@ func (*Provider) Meta() provider.TypeMeta {
 	return section
 }
 
-// Command stub.
+// Command returns the languages and themes available for highlighting.
 func (*Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.Request) {
-	provider.WriteEmpty(w)
+	method := r.URL.Query().Get("method")
+
+	switch method {
+	case "options":
+		opts := codeOptions{}
+		opts.Languages = lexers.Names(false)
+		opts.Themes = styles.Names()
+
+		provider.WriteJSON(w, opts)
+	default:
+		provider.WriteEmpty(w)
+	}
 }
 
-// Render just sends back HMTL as-is.
-func (*Provider) Render(ctx *provider.Context, config, data string) string {
-	return data
+// Render produces syntax highlighted HTML from raw code.
+// Styles are inlined so output survives export and email.
+func (p *Provider) Render(ctx *provider.Context, config, data string) string {
+	c := codeConfig{}
+	json.Unmarshal([]byte(config), &c) // empty config is valid
+
+	if lang, code, ok := parseLegacy(data); ok {
+		if len(c.Lang) == 0 {
+			c.Lang = lang
+		}
+		data = code
+	}
+
+	c.Clean()
+
+	output, err := highlight(c, data)
+	if err != nil {
+		p.Runtime.Log.Error("code section highlight", err)
+		return fmt.Sprintf("<pre>%s</pre>", template.HTMLEscapeString(data))
+	}
+
+	return output
 }
 
 // Refresh just sends back data as-is.
 func (*Provider) Refresh(ctx *provider.Context, config, data string) string {
 	return data
 }
+
+// highlight formats code according to config.
+func highlight(c codeConfig, code string) (string, error) {
+	lexer := findLexer(c.Lang, code)
+
+	style := styles.Get(c.Theme)
+	if style == styles.Fallback {
+		style = styles.Get(defaultTheme)
+	}
+
+	formatter := html.New(
+		html.WithClasses(false),
+		html.TabWidth(4),
+		html.WithLineNumbers(c.LineNumbers),
+		html.LineNumbersInTable(true),
+		html.HighlightLines(c.HighlightRanges()),
+		html.WithPreWrapper(codeWrapper{}))
+
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := new(bytes.Buffer)
+	fmt.Fprintf(buffer, `<div class="documize-code" data-lang="%s">`, template.HTMLEscapeString(lexer.Config().Name))
+
+	err = formatter.Format(buffer, style, iterator)
+	if err != nil {
+		return "", err
+	}
+
+	buffer.WriteString("</div>")
+
+	return buffer.String(), nil
+}
+
+// findLexer resolves language name, falling back to content analysis
+// and finally plain text.
+func findLexer(lang, code string) (lexer chroma.Lexer) {
+	if mapped, ok := codeMirrorModes[lang]; ok {
+		lang = mapped
+	}
+
+	if len(lang) > 0 {
+		lexer = lexers.Get(lang)
+	}
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	return chroma.Coalesce(lexer)
+}
+
+// codeWrapper marks the line number gutter so that search
+// indexing can skip it (see stringutil.HTML).
+type codeWrapper struct{}
+
+func (codeWrapper) Start(code bool, styleAttr string) string {
+	if code {
+		return fmt.Sprintf("<pre%s>", styleAttr)
+	}
+	return fmt.Sprintf(`<pre class="documize-code-lines"%s>`, styleAttr)
+}
+
+func (codeWrapper) End(code bool) string {
+	return "</pre>"
+}
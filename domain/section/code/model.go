@@ -0,0 +1,108 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package code
+
+import (
+	"strconv"
+	"strings"
+)
+
+// defaultTheme is used when no theme (or an unknown theme) is configured.
+const defaultTheme = "github"
+
+// legacyPrefix is how the code editor used to wrap snippets before
+// rendering was moved server-side. Existing sections still carry it in RawBody.
+const legacyPrefix = `<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="`
+
+// codeConfig holds the section options stored in page meta config.
+type codeConfig struct {
+	Lang        string `json:"lang"`        // lexer name, alias or CodeMirror mode
+	Theme       string `json:"theme"`       // style name, e.g. github, monokai
+	LineNumbers bool   `json:"lineNumbers"` // show line number gutter
+	Highlight   string `json:"highlight"`   // lines to highlight, e.g. "1,4-6"
+}
+
+// Clean removes surrounding whitespace from user supplied options.
+func (c *codeConfig) Clean() {
+	c.Lang = strings.TrimSpace(c.Lang)
+	c.Theme = strings.TrimSpace(c.Theme)
+	c.Highlight = strings.TrimSpace(c.Highlight)
+
+	if len(c.Theme) == 0 {
+		c.Theme = defaultTheme
+	}
+}
+
+// HighlightRanges parses Highlight into inclusive line ranges.
+// Malformed entries are ignored.
+func (c *codeConfig) HighlightRanges() (ranges [][2]int) {
+	for _, part := range strings.Split(c.Highlight, ",") {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil || start < 1 {
+			continue
+		}
+
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || end < start {
+				continue
+			}
+		}
+
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	return
+}
+
+// codeOptions lists what the editor can choose from.
+type codeOptions struct {
+	Languages []string `json:"languages"`
+	Themes    []string `json:"themes"`
+}
+
+// codeMirrorModes maps CodeMirror modes that have no lexer of the same name.
+var codeMirrorModes = map[string]string{
+	"htmlmixed":    "html",
+	"htmlembedded": "html",
+	"clike":        "c",
+	"stex":         "latex",
+	"gfm":          "markdown",
+	"text/plain":   "plaintext",
+}
+
+// parseLegacy splits a RawBody saved by the old client-side editor
+// into language and code. ok is false when data is not in that format.
+func parseLegacy(data string) (lang, code string, ok bool) {
+	if !strings.HasPrefix(data, legacyPrefix) {
+		return
+	}
+
+	body := strings.TrimPrefix(data, legacyPrefix)
+	pos := strings.Index(body, `">`)
+	if pos == -1 {
+		return
+	}
+
+	lang = body[:pos]
+	code = strings.TrimSuffix(body[pos+2:], "</pre>")
+	ok = true
+
+	return
+}
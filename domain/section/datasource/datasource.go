@@ -0,0 +1,227 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package datasource
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/svgchart"
+	"github.com/documize/community/domain"
+	source "github.com/documize/community/domain/datasource"
+	"github.com/documize/community/domain/section/provider"
+)
+
+const me = "datasource"
+
+// Provider represents a read-only query against an admin defined data source.
+type Provider struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// Meta describes us.
+func (*Provider) Meta() provider.TypeMeta {
+	section := provider.TypeMeta{}
+	section.ID = "d2c59bb5-52ee-4fae-85d6-0884e95fdb31"
+	section.Title = "Data Source"
+	section.Description = "Live query results as tables and charts"
+	section.ContentType = "datasource"
+	section.PageType = "tab"
+
+	return section
+}
+
+// Command lists available data sources and previews query results.
+func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+
+	if len(method) == 0 {
+		provider.WriteMessage(w, me, "missing method name")
+		return
+	}
+
+	switch method {
+	case "sources":
+		p.sources(ctx, w)
+	case "preview":
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			provider.WriteMessage(w, me, "Bad payload")
+			return
+		}
+
+		c := queryConfig{}
+		err = json.Unmarshal(body, &c)
+		if err != nil {
+			provider.WriteMessage(w, me, "Bad config")
+			return
+		}
+
+		c.Clean()
+
+		provider.WriteJSON(w, p.run(ctx, c))
+	default:
+		provider.WriteMessage(w, me, "unknown method name")
+	}
+}
+
+// Render turns query results into an HTML table or SVG chart.
+func (p *Provider) Render(ctx *provider.Context, config, data string) string {
+	c := queryConfig{}
+	json.Unmarshal([]byte(config), &c)
+	c.Clean()
+
+	d := queryData{}
+	json.Unmarshal([]byte(data), &d)
+
+	if len(d.Error) == 0 && len(d.Result.Rows) > 0 && c.Display != displayTable {
+		chart, ok := toChart(c, d)
+		if ok {
+			if c.Display == displayLine {
+				return svgchart.Line(chart)
+			}
+			return svgchart.Bar(chart)
+		}
+	}
+
+	payload := queryRender{Result: d.Result, Error: d.Error, HasData: len(d.Result.Rows) > 0}
+
+	t := template.New("datasource")
+	t, _ = t.Parse(renderTemplate)
+
+	buffer := new(bytes.Buffer)
+	t.Execute(buffer, payload)
+
+	return buffer.String()
+}
+
+// Refresh runs the query again.
+func (p *Provider) Refresh(ctx *provider.Context, config, data string) (newData string) {
+	c := queryConfig{}
+	err := json.Unmarshal([]byte(config), &c)
+	if err != nil {
+		p.Runtime.Log.Error("unable to read data source section config", err)
+		return data
+	}
+
+	c.Clean()
+
+	j, err := json.Marshal(p.run(ctx, c))
+	if err != nil {
+		p.Runtime.Log.Error("unable to marshal data source query result", err)
+		return data
+	}
+
+	return string(j)
+}
+
+// sources sends back data sources without connection details.
+func (p *Provider) sources(ctx *provider.Context, w http.ResponseWriter) {
+	ds, err := p.Store.DataSource.GetAll(ctx.Request)
+	if err != nil {
+		p.Runtime.Log.Error("unable to list data sources", err)
+		provider.WriteError(w, me, err)
+		return
+	}
+
+	opts := []sourceOption{}
+	for _, d := range ds {
+		opts = append(opts, sourceOption{ID: d.RefID, Name: d.Name, Driver: d.Driver, MaxRows: d.MaxRows})
+	}
+
+	provider.WriteJSON(w, opts)
+}
+
+// run executes configured query, capturing any failure for display.
+func (p *Provider) run(ctx *provider.Context, c queryConfig) (d queryData) {
+	if len(c.DataSourceID) == 0 {
+		d.Error = "no data source selected"
+		return
+	}
+
+	ds, err := p.Store.DataSource.Get(ctx.Request, c.DataSourceID)
+	if err != nil {
+		p.Runtime.Log.Error("unable to get data source "+c.DataSourceID, err)
+		d.Error = "data source not found"
+		return
+	}
+
+	d.Result, err = source.Query(ds, c.Query, c.args(), c.MaxRows)
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	return
+}
+
+// toChart maps result columns onto chart labels and series.
+// The first column provides labels and all others values, unless configured.
+func toChart(c queryConfig, d queryData) (chart svgchart.Chart, ok bool) {
+	cols := d.Result.Columns
+	if len(cols) < 2 {
+		return
+	}
+
+	label := indexOf(cols, c.LabelColumn)
+	if label == -1 {
+		label = 0
+	}
+
+	var values []int
+	for _, v := range c.ValueColumns {
+		if i := indexOf(cols, strings.TrimSpace(v)); i != -1 && i != label {
+			values = append(values, i)
+		}
+	}
+	if len(values) == 0 {
+		for i := range cols {
+			if i != label {
+				values = append(values, i)
+			}
+		}
+	}
+
+	chart.Title = c.Title
+	for _, i := range values {
+		chart.Series = append(chart.Series, svgchart.Series{Name: cols[i]})
+	}
+
+	for _, row := range d.Result.Rows {
+		chart.Labels = append(chart.Labels, row[label])
+		for j, i := range values {
+			v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+			if err != nil {
+				return chart, false // not numeric, show as table
+			}
+			chart.Series[j].Values = append(chart.Series[j].Values, v)
+		}
+	}
+
+	return chart, true
+}
+
+func indexOf(list []string, s string) int {
+	for i, x := range list {
+		if len(s) > 0 && strings.EqualFold(x, s) {
+			return i
+		}
+	}
+	return -1
+}
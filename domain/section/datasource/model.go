@@ -0,0 +1,104 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package datasource
+
+import (
+	"strings"
+
+	"github.com/documize/community/model/datasource"
+)
+
+// the HTML that is rendered by this section when showing a table.
+const renderTemplate = `
+{{if .Error}}
+<p class="color-red">Query failed: {{.Error}}</p>
+{{else if .HasData}}
+<table class="basic-table section-datasource-table">
+	<thead>
+		<tr>
+			{{range $col := .Result.Columns}}<th class="bordered">{{$col}}</th>{{end}}
+		</tr>
+	</thead>
+	<tbody>
+		{{range $row := .Result.Rows}}
+		<tr>
+			{{range $cell := $row}}<td class="bordered">{{$cell}}</td>{{end}}
+		</tr>
+		{{end}}
+	</tbody>
+</table>
+{{if .Result.Truncated}}<p class="color-gray">Showing the first {{len .Result.Rows}} rows.</p>{{end}}
+{{else}}
+<p>The query returned no rows.</p>
+{{end}}
+`
+
+// Display options.
+const (
+	displayTable = "table"
+	displayBar   = "bar"
+	displayLine  = "line"
+)
+
+// queryConfig is saved as page meta config by the section editor.
+type queryConfig struct {
+	DataSourceID string   `json:"dataSourceId"`
+	Query        string   `json:"query"`        // SELECT with ? placeholders
+	Params       []string `json:"params"`       // values bound to placeholders in order
+	MaxRows      int      `json:"maxRows"`      // capped by data source limit
+	Display      string   `json:"display"`      // table, bar or line
+	Title        string   `json:"title"`        // chart title
+	LabelColumn  string   `json:"labelColumn"`  // chart category column
+	ValueColumns []string `json:"valueColumns"` // chart series columns
+}
+
+// Clean removes surrounding whitespace and applies defaults.
+func (c *queryConfig) Clean() {
+	c.DataSourceID = strings.TrimSpace(c.DataSourceID)
+	c.Query = strings.TrimSpace(c.Query)
+	c.Display = strings.ToLower(strings.TrimSpace(c.Display))
+	c.Title = strings.TrimSpace(c.Title)
+	c.LabelColumn = strings.TrimSpace(c.LabelColumn)
+
+	if c.Display != displayBar && c.Display != displayLine {
+		c.Display = displayTable
+	}
+}
+
+// args converts params for binding.
+func (c *queryConfig) args() (a []interface{}) {
+	for _, p := range c.Params {
+		a = append(a, p)
+	}
+	return
+}
+
+// queryData is saved as page meta raw body on each refresh.
+type queryData struct {
+	Result datasource.Result `json:"result"`
+	Error  string            `json:"error"`
+}
+
+// queryRender is passed into the table template.
+type queryRender struct {
+	Result  datasource.Result
+	Error   string
+	HasData bool
+}
+
+// sourceOption lists a data source without connection details.
+type sourceOption struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Driver  string `json:"driver"`
+	MaxRows int    `json:"maxRows"`
+}
@@ -86,6 +86,8 @@ func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http
 		workspace(ctx, p.Store, w, r)
 	case "items":
 		items(ctx, p.Store, w, r)
+	default:
+		provider.WriteMessage(w, "gemini", "unknown method name")
 	}
 }
 
@@ -14,8 +14,8 @@ package markdown
 import (
 	"net/http"
 
-	"github.com/documize/blackfriday"
 	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/mdutil"
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/section/provider"
 )
@@ -32,7 +32,7 @@ func (*Provider) Meta() provider.TypeMeta {
 
 	section.ID = "1470bb4a-36c6-4a98-a443-096f5658378b"
 	section.Title = "Markdown"
-	section.Description = "CommonMark based content"
+	section.Description = "GitHub Flavored Markdown content"
 	section.ContentType = "markdown"
 	section.PageType = "section"
 	section.Order = 9998
@@ -45,9 +45,9 @@ func (*Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.R
 	provider.WriteEmpty(w)
 }
 
-// Render converts markdown data into HTML suitable for browser rendering.
+// Render converts markdown data into sanitized HTML suitable for browser rendering.
 func (*Provider) Render(ctx *provider.Context, config, data string) string {
-	result := blackfriday.MarkdownCommon([]byte(data))
+	result := mdutil.Render([]byte(data))
 
 	return string(result)
 }
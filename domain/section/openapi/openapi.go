@@ -0,0 +1,148 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/section/provider"
+)
+
+const me = "openapi"
+
+// Provider represents an OpenAPI 3 specification.
+type Provider struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// validation is sent back to the editor.
+type validation struct {
+	Valid      bool     `json:"valid"`
+	Problems   []string `json:"problems"`
+	Title      string   `json:"title"`
+	Operations int      `json:"operations"`
+}
+
+// Meta describes us.
+func (*Provider) Meta() provider.TypeMeta {
+	section := provider.TypeMeta{}
+	section.ID = "4bb1a81b-8c95-4939-8c38-a487bb4f97aa"
+	section.Title = "OpenAPI"
+	section.Description = "REST API reference from OpenAPI 3 specification"
+	section.ContentType = "openapi"
+	section.PageType = "section"
+
+	return section
+}
+
+// Command validates specification before the editor saves it.
+func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+
+	switch method {
+	case "validate":
+		defer r.Body.Close()
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			provider.WriteMessage(w, me, "Bad payload")
+			return
+		}
+
+		var payload struct {
+			Spec string `json:"spec"`
+		}
+		err = json.Unmarshal(body, &payload)
+		if err != nil {
+			provider.WriteMessage(w, me, "Bad payload")
+			return
+		}
+
+		provider.WriteJSON(w, validate(payload.Spec))
+	default:
+		provider.WriteEmpty(w)
+	}
+}
+
+// Render converts specification into API reference documentation.
+func (p *Provider) Render(ctx *provider.Context, config, data string) string {
+	if len(strings.TrimSpace(data)) == 0 {
+		return ""
+	}
+
+	s, err := parse(data)
+	if err != nil {
+		return failed(err.Error())
+	}
+
+	html, err := render(s)
+	if err != nil {
+		p.Runtime.Log.Error("unable to render OpenAPI section", err)
+		return failed(err.Error())
+	}
+
+	return html
+}
+
+// Refresh just sends back data as-is.
+func (*Provider) Refresh(ctx *provider.Context, config, data string) string {
+	return data
+}
+
+// SearchItems returns one entry per API operation.
+func (*Provider) SearchItems(config, data string) (items []string) {
+	s, err := parse(data)
+	if err != nil {
+		return
+	}
+
+	for _, e := range s.endpoints() {
+		fields := []string{strings.ToUpper(e.Method), e.Path, e.Operation.OperationID, e.Operation.Summary, e.Operation.Description}
+		fields = append(fields, e.Operation.Tags...)
+		for _, x := range e.Params {
+			fields = append(fields, x.Name)
+		}
+
+		items = append(items, strings.TrimSpace(strings.Join(fields, " ")))
+	}
+
+	return
+}
+
+// validate parses and checks specification.
+func validate(data string) (v validation) {
+	v.Problems = []string{}
+
+	s, err := parse(data)
+	if err != nil {
+		v.Problems = append(v.Problems, "unable to parse specification: "+err.Error())
+		return
+	}
+
+	v.Problems = append(v.Problems, s.validate()...)
+	v.Valid = len(v.Problems) == 0
+	v.Title = s.Info.Title
+	v.Operations = len(s.endpoints())
+
+	return
+}
+
+func failed(reason string) string {
+	return fmt.Sprintf(`<p class="color-red">Invalid OpenAPI specification: %s</p>`, template.HTMLEscapeString(reason))
+}
@@ -0,0 +1,471 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+
+	"github.com/documize/community/core/mdutil"
+)
+
+// maxDepth stops runaway recursion through nested or circular schemas.
+const maxDepth = 6
+
+// renderTemplate lays out the specification.
+const renderTemplate = `
+<div class="documize-openapi">
+	<h2 class="openapi-title">{{.Info.Title}} {{if .Info.Version}}<span class="openapi-version">{{.Info.Version}}</span>{{end}}</h2>
+	{{markdown .Info.Description}}
+	{{if .Servers}}
+	<ul class="openapi-servers">
+		{{range .Servers}}<li><code>{{.URL}}</code> {{.Description}}</li>{{end}}
+	</ul>
+	{{end}}
+	{{range .Groups}}
+	<div class="openapi-group">
+		<h3>{{if .Name}}{{.Name}}{{else}}Other{{end}}</h3>
+		{{markdown .Description}}
+		{{range .Endpoints}}
+		<div class="openapi-operation{{if .Operation.Deprecated}} openapi-deprecated{{end}}" id="{{.ID}}">
+			<h4><span class="openapi-method openapi-method-{{.Method}}">{{upper .Method}}</span> <code>{{.Path}}</code> {{.Operation.Summary}}</h4>
+			{{markdown .Operation.Description}}
+			{{if .Params}}
+			<table class="basic-table openapi-parameters">
+				<thead><tr><th class="bordered">Parameter</th><th class="bordered">In</th><th class="bordered">Type</th><th class="bordered">Description</th></tr></thead>
+				<tbody>
+				{{range .Params}}
+					<tr>
+						<td class="bordered"><code>{{.Name}}</code>{{if .Required}} <span class="openapi-required">required</span>{{end}}</td>
+						<td class="bordered">{{.In}}</td>
+						<td class="bordered">{{typeOf .Schema}}</td>
+						<td class="bordered">{{markdown .Description}}</td>
+					</tr>
+				{{end}}
+				</tbody>
+			</table>
+			{{end}}
+			{{with requestBody .Operation.RequestBody}}
+			<h5>Request body{{if .Required}} <span class="openapi-required">required</span>{{end}}</h5>
+			{{markdown .Description}}
+			{{content .Content}}
+			{{end}}
+			<h5>Responses</h5>
+			<table class="basic-table openapi-responses">
+				<thead><tr><th class="bordered">Status</th><th class="bordered">Description</th></tr></thead>
+				<tbody>
+				{{range responses .Operation.Responses}}
+					<tr>
+						<td class="bordered"><code>{{.Code}}</code></td>
+						<td class="bordered">{{markdown .Response.Description}}{{content .Response.Content}}</td>
+					</tr>
+				{{end}}
+				</tbody>
+			</table>
+		</div>
+		{{end}}
+	</div>
+	{{end}}
+	{{if .Schemas}}
+	<div class="openapi-schemas">
+		<h3>Schemas</h3>
+		{{range .Schemas}}
+		<div class="openapi-schema" id="schema-{{.Name}}">
+			<h4>{{.Name}}</h4>
+			{{markdown .Schema.Description}}
+			{{fields .Schema}}
+		</div>
+		{{end}}
+	</div>
+	{{end}}
+</div>
+`
+
+// named pairs a component schema with its name.
+type named struct {
+	Name   string
+	Schema *schema
+}
+
+// status pairs a response with its status code.
+type status struct {
+	Code     string
+	Response *response
+}
+
+// renderData is passed into the template.
+type renderData struct {
+	Info    info
+	Servers []server
+	Groups  []group
+	Schemas []named
+}
+
+// render produces HTML for a parsed specification.
+func render(s spec) (string, error) {
+	d := renderData{Info: s.Info, Servers: s.Servers, Groups: s.groups()}
+
+	var names []string
+	for n := range s.Components.Schemas {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if s.Components.Schemas[n] != nil {
+			d.Schemas = append(d.Schemas, named{Name: n, Schema: s.Components.Schemas[n]})
+		}
+	}
+
+	r := renderer{s: &s}
+
+	t, err := template.New("openapi").Funcs(template.FuncMap{
+		"markdown":    markdown,
+		"upper":       strings.ToUpper,
+		"typeOf":      r.typeOf,
+		"fields":      r.fields,
+		"content":     r.content,
+		"requestBody": s.requestBody,
+		"responses":   r.responses,
+	}).Parse(renderTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := new(bytes.Buffer)
+	err = t.Execute(buffer, d)
+	if err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
+
+// markdown renders CommonMark descriptions.
+func markdown(s string) template.HTML {
+	if len(strings.TrimSpace(s)) == 0 {
+		return ""
+	}
+	return template.HTML(mdutil.Render([]byte(s)))
+}
+
+// renderer provides template functions that need to resolve references.
+type renderer struct {
+	s *spec
+}
+
+// typeOf describes a schema type in a single line, linking named schemas.
+func (r renderer) typeOf(x *schema) template.HTML {
+	return template.HTML(r.describe(x, 0))
+}
+
+func (r renderer) describe(x *schema, depth int) string {
+	if x == nil {
+		return ""
+	}
+	if depth > maxDepth {
+		return "…"
+	}
+
+	if len(x.Ref) > 0 {
+		name := template.HTMLEscapeString(refName(x.Ref, "schemas"))
+		return fmt.Sprintf(`<a href="#schema-%s">%s</a>`, name, name)
+	}
+
+	var parts []string
+	for _, combo := range []struct {
+		label string
+		list  []*schema
+	}{{"all of", x.AllOf}, {"one of", x.OneOf}, {"any of", x.AnyOf}} {
+		if len(combo.list) == 0 {
+			continue
+		}
+		var items []string
+		for _, y := range combo.list {
+			items = append(items, r.describe(y, depth+1))
+		}
+		parts = append(parts, combo.label+" "+strings.Join(items, ", "))
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "; ")
+	}
+
+	t := template.HTMLEscapeString(x.Type)
+	if x.Type == "array" {
+		t = "array of " + r.describe(x.Items, depth+1)
+	}
+	if len(x.Format) > 0 {
+		t += " (" + template.HTMLEscapeString(x.Format) + ")"
+	}
+	if x.Nullable {
+		t += ", nullable"
+	}
+	if len(x.Enum) > 0 {
+		var values []string
+		for _, v := range x.Enum {
+			values = append(values, "<code>"+template.HTMLEscapeString(fmt.Sprintf("%v", v))+"</code>")
+		}
+		t += " one of " + strings.Join(values, ", ")
+	}
+
+	return t
+}
+
+// fields renders object properties as a table, nesting inline objects.
+func (r renderer) fields(x *schema) template.HTML {
+	b := new(bytes.Buffer)
+	r.writeFields(b, x, 0)
+	return template.HTML(b.String())
+}
+
+func (r renderer) writeFields(b *bytes.Buffer, x *schema, depth int) {
+	if depth > maxDepth {
+		return
+	}
+
+	props, required := r.properties(x, depth)
+	if len(props) == 0 {
+		if x != nil && len(x.Ref) == 0 && x.Type != "object" {
+			fmt.Fprintf(b, `<p>%s</p>`, r.describe(x, depth))
+		}
+		return
+	}
+
+	var names []string
+	for n := range props {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	b.WriteString(`<table class="basic-table openapi-fields"><thead><tr><th class="bordered">Field</th><th class="bordered">Type</th><th class="bordered">Description</th></tr></thead><tbody>`)
+	for _, n := range names {
+		p := props[n]
+		fmt.Fprintf(b, `<tr><td class="bordered"><code>%s</code>`, template.HTMLEscapeString(n))
+		if required[n] {
+			b.WriteString(` <span class="openapi-required">required</span>`)
+		}
+		fmt.Fprintf(b, `</td><td class="bordered">%s</td><td class="bordered">%s`, r.describe(p, depth+1), markdown(p.Description))
+
+		// expand inline objects, named ones link to their own table
+		if len(p.Ref) == 0 {
+			inner := p
+			if p.Type == "array" && p.Items != nil && len(p.Items.Ref) == 0 {
+				inner = p.Items
+			}
+			if len(inner.Properties) > 0 {
+				r.writeFields(b, inner, depth+1)
+			}
+		}
+
+		b.WriteString(`</td></tr>`)
+	}
+	b.WriteString(`</tbody></table>`)
+}
+
+// properties collects object properties including those from allOf.
+func (r renderer) properties(x *schema, depth int) (props map[string]*schema, required map[string]bool) {
+	props = map[string]*schema{}
+	required = map[string]bool{}
+
+	if depth > maxDepth {
+		return
+	}
+
+	x = r.s.schema(x)
+	if x == nil {
+		return
+	}
+
+	for n, p := range x.Properties {
+		if p != nil {
+			props[n] = p
+		}
+	}
+	for _, n := range x.Required {
+		required[n] = true
+	}
+	for _, y := range x.AllOf {
+		p, req := r.properties(y, depth+1)
+		for n, v := range p {
+			props[n] = v
+		}
+		for n := range req {
+			required[n] = true
+		}
+	}
+
+	return
+}
+
+// content renders body media types with schema and example.
+func (r renderer) content(c map[string]*mediaType) template.HTML {
+	var types []string
+	for t := range c {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	b := new(bytes.Buffer)
+	for _, t := range types {
+		m := c[t]
+		if m == nil {
+			continue
+		}
+
+		fmt.Fprintf(b, `<div class="openapi-content"><p><code>%s</code> %s</p>`, template.HTMLEscapeString(t), r.describe(m.Schema, 0))
+		if m.Schema != nil && len(m.Schema.Ref) == 0 {
+			r.writeFields(b, m.Schema, 0)
+		}
+		for _, e := range r.examples(m) {
+			fmt.Fprintf(b, `<pre class="openapi-example">%s</pre>`, template.HTMLEscapeString(e))
+		}
+		b.WriteString(`</div>`)
+	}
+
+	return template.HTML(b.String())
+}
+
+// examples returns given examples, or one generated from the schema.
+func (r renderer) examples(m *mediaType) (e []string) {
+	if m.Example != nil {
+		return []string{pretty(m.Example)}
+	}
+
+	var names []string
+	for n := range m.Examples {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		if x := m.Examples[n]; x != nil && x.Value != nil {
+			e = append(e, pretty(x.Value))
+		}
+	}
+	if len(e) > 0 {
+		return
+	}
+
+	if m.Schema != nil {
+		if v := r.sample(m.Schema, 0, map[string]bool{}); v != nil {
+			e = append(e, pretty(v))
+		}
+	}
+
+	return
+}
+
+// sample builds an example value from a schema.
+// Named schemas already being expanded are left out to stop cycles.
+func (r renderer) sample(x *schema, depth int, expanding map[string]bool) interface{} {
+	if x != nil && len(x.Ref) > 0 {
+		if expanding[x.Ref] {
+			return nil
+		}
+		expanding[x.Ref] = true
+		defer delete(expanding, x.Ref)
+	}
+
+	x = r.s.schema(x)
+	if x == nil || depth > maxDepth {
+		return nil
+	}
+
+	switch {
+	case x.Example != nil:
+		return x.Example
+	case x.Default != nil:
+		return x.Default
+	case len(x.Enum) > 0:
+		return x.Enum[0]
+	case len(x.OneOf) > 0:
+		return r.sample(x.OneOf[0], depth+1, expanding)
+	case len(x.AnyOf) > 0:
+		return r.sample(x.AnyOf[0], depth+1, expanding)
+	}
+
+	switch x.Type {
+	case "array":
+		if v := r.sample(x.Items, depth+1, expanding); v != nil {
+			return []interface{}{v}
+		}
+		return []interface{}{}
+	case "string":
+		switch x.Format {
+		case "date":
+			return "2017-01-01"
+		case "date-time":
+			return "2017-01-01T00:00:00Z"
+		case "email":
+			return "user@example.com"
+		case "uuid":
+			return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+		case "uri", "url":
+			return "https://example.com"
+		}
+		return "string"
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return true
+	}
+
+	props, _ := r.properties(x, depth)
+	if len(props) == 0 && x.Type != "object" {
+		return nil
+	}
+
+	o := map[string]interface{}{}
+	for n, p := range props {
+		if v := r.sample(p, depth+1, expanding); v != nil {
+			o[n] = v
+		}
+	}
+
+	return o
+}
+
+// responses orders responses by status code with default last.
+func (r renderer) responses(m map[string]*response) (list []status) {
+	var codes []string
+	for c := range m {
+		codes = append(codes, c)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if codes[i] == "default" || codes[j] == "default" {
+			return codes[j] == "default" && codes[i] != "default"
+		}
+		return codes[i] < codes[j]
+	})
+
+	for _, c := range codes {
+		if x := r.s.response(m[c]); x != nil {
+			list = append(list, status{Code: c, Response: x})
+		}
+	}
+
+	return
+}
+
+// pretty formats example values as indented JSON.
+func pretty(v interface{}) string {
+	b := new(bytes.Buffer)
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSpace(b.String())
+}
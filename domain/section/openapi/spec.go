@@ -0,0 +1,457 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// methods lists path item operations in display order.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// spec is the subset of an OpenAPI 3 document that we display.
+type spec struct {
+	OpenAPI    string              `yaml:"openapi"`
+	Info       info                `yaml:"info"`
+	Servers    []server            `yaml:"servers"`
+	Tags       []tag               `yaml:"tags"`
+	Paths      map[string]pathItem `yaml:"paths"`
+	Components components          `yaml:"components"`
+}
+
+type info struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+type server struct {
+	URL         string `yaml:"url"`
+	Description string `yaml:"description"`
+}
+
+type tag struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+type components struct {
+	Schemas       map[string]*schema      `yaml:"schemas"`
+	Parameters    map[string]*parameter   `yaml:"parameters"`
+	RequestBodies map[string]*requestBody `yaml:"requestBodies"`
+	Responses     map[string]*response    `yaml:"responses"`
+}
+
+type pathItem struct {
+	Summary     string       `yaml:"summary"`
+	Description string       `yaml:"description"`
+	Parameters  []*parameter `yaml:"parameters"`
+	Get         *operation   `yaml:"get"`
+	Put         *operation   `yaml:"put"`
+	Post        *operation   `yaml:"post"`
+	Delete      *operation   `yaml:"delete"`
+	Options     *operation   `yaml:"options"`
+	Head        *operation   `yaml:"head"`
+	Patch       *operation   `yaml:"patch"`
+	Trace       *operation   `yaml:"trace"`
+}
+
+// operation returns the operation for the given lower case method.
+func (p pathItem) operation(method string) *operation {
+	switch method {
+	case "get":
+		return p.Get
+	case "put":
+		return p.Put
+	case "post":
+		return p.Post
+	case "delete":
+		return p.Delete
+	case "options":
+		return p.Options
+	case "head":
+		return p.Head
+	case "patch":
+		return p.Patch
+	case "trace":
+		return p.Trace
+	}
+	return nil
+}
+
+type operation struct {
+	Tags        []string             `yaml:"tags"`
+	Summary     string               `yaml:"summary"`
+	Description string               `yaml:"description"`
+	OperationID string               `yaml:"operationId"`
+	Deprecated  bool                 `yaml:"deprecated"`
+	Parameters  []*parameter         `yaml:"parameters"`
+	RequestBody *requestBody         `yaml:"requestBody"`
+	Responses   map[string]*response `yaml:"responses"`
+}
+
+type parameter struct {
+	Ref         string      `yaml:"$ref"`
+	Name        string      `yaml:"name"`
+	In          string      `yaml:"in"`
+	Description string      `yaml:"description"`
+	Required    bool        `yaml:"required"`
+	Deprecated  bool        `yaml:"deprecated"`
+	Schema      *schema     `yaml:"schema"`
+	Example     interface{} `yaml:"example"`
+}
+
+type requestBody struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Required    bool                  `yaml:"required"`
+	Content     map[string]*mediaType `yaml:"content"`
+}
+
+type response struct {
+	Ref         string                `yaml:"$ref"`
+	Description string                `yaml:"description"`
+	Content     map[string]*mediaType `yaml:"content"`
+}
+
+type mediaType struct {
+	Schema   *schema             `yaml:"schema"`
+	Example  interface{}         `yaml:"example"`
+	Examples map[string]*example `yaml:"examples"`
+}
+
+type example struct {
+	Summary string      `yaml:"summary"`
+	Value   interface{} `yaml:"value"`
+}
+
+type schema struct {
+	Ref                  string             `yaml:"$ref"`
+	Type                 string             `yaml:"type"`
+	Format               string             `yaml:"format"`
+	Title                string             `yaml:"title"`
+	Description          string             `yaml:"description"`
+	Nullable             bool               `yaml:"nullable"`
+	Required             []string           `yaml:"required"`
+	Properties           map[string]*schema `yaml:"properties"`
+	AdditionalProperties interface{}        `yaml:"additionalProperties"`
+	Items                *schema            `yaml:"items"`
+	Enum                 []interface{}      `yaml:"enum"`
+	Default              interface{}        `yaml:"default"`
+	Example              interface{}        `yaml:"example"`
+	AllOf                []*schema          `yaml:"allOf"`
+	OneOf                []*schema          `yaml:"oneOf"`
+	AnyOf                []*schema          `yaml:"anyOf"`
+}
+
+// endpoint is an operation together with where it lives.
+type endpoint struct {
+	Method    string
+	Path      string
+	Operation *operation
+	Params    []*parameter // path level and operation parameters, resolved
+}
+
+// ID returns a stable anchor for the endpoint.
+func (e endpoint) ID() string {
+	if len(e.Operation.OperationID) > 0 {
+		return "op-" + slug(e.Operation.OperationID)
+	}
+	return "op-" + slug(e.Method+" "+e.Path)
+}
+
+// group holds endpoints sharing a tag.
+type group struct {
+	Name        string
+	Description string
+	Endpoints   []endpoint
+}
+
+// parse reads YAML or JSON (a subset of YAML) into spec.
+func parse(data string) (s spec, err error) {
+	err = yaml.Unmarshal([]byte(data), &s)
+	return
+}
+
+// validate reports problems that would stop the spec displaying properly.
+func (s *spec) validate() (problems []string) {
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		problems = append(problems, "openapi version must be 3.x")
+	}
+	if len(strings.TrimSpace(s.Info.Title)) == 0 {
+		problems = append(problems, "info.title is required")
+	}
+	if len(s.Paths) == 0 {
+		problems = append(problems, "paths must define at least one endpoint")
+	}
+
+	for _, e := range s.endpoints() {
+		at := strings.ToUpper(e.Method) + " " + e.Path
+
+		if len(e.Operation.Responses) == 0 {
+			problems = append(problems, at+": responses are required")
+		}
+		for _, p := range e.Params {
+			if len(p.Ref) > 0 {
+				problems = append(problems, at+": unresolved parameter "+p.Ref)
+				continue
+			}
+			if len(p.Name) == 0 || len(p.In) == 0 {
+				problems = append(problems, at+": parameters need name and in")
+			}
+			if p.In == "path" && !p.Required {
+				problems = append(problems, at+": path parameter "+p.Name+" must be required")
+			}
+		}
+		if b := e.Operation.RequestBody; b != nil && len(b.Ref) > 0 && s.requestBody(b) == nil {
+			problems = append(problems, at+": unresolved request body "+b.Ref)
+		}
+		for code, r := range e.Operation.Responses {
+			if r != nil && len(r.Ref) > 0 && s.response(r) == nil {
+				problems = append(problems, fmt.Sprintf("%s %s: unresolved response %s", at, code, r.Ref))
+			}
+		}
+	}
+
+	for _, ref := range s.schemaRefs() {
+		if s.schema(&schema{Ref: ref}) == nil {
+			problems = append(problems, "unresolved schema "+ref)
+		}
+	}
+
+	return
+}
+
+// endpoints returns all operations ordered by path then method.
+func (s *spec) endpoints() (e []endpoint) {
+	var paths []string
+	for p := range s.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := s.Paths[p]
+		for _, m := range methods {
+			op := item.operation(m)
+			if op == nil {
+				continue
+			}
+
+			e = append(e, endpoint{Method: m, Path: p, Operation: op, Params: s.parameters(item.Parameters, op.Parameters)})
+		}
+	}
+
+	return
+}
+
+// groups arranges endpoints by first tag: declared tags first,
+// then undeclared tags alphabetically, then untagged endpoints.
+func (s *spec) groups() (g []group) {
+	byName := map[string]*group{}
+	var order []string
+
+	add := func(name, description string) {
+		if _, ok := byName[name]; !ok {
+			byName[name] = &group{Name: name, Description: description}
+			order = append(order, name)
+		}
+	}
+
+	for _, t := range s.Tags {
+		add(t.Name, t.Description)
+	}
+
+	var extra []string
+	for _, e := range s.endpoints() {
+		if len(e.Operation.Tags) > 0 {
+			if _, ok := byName[e.Operation.Tags[0]]; !ok {
+				extra = append(extra, e.Operation.Tags[0])
+			}
+		}
+	}
+	sort.Strings(extra)
+	for _, t := range extra {
+		add(t, "")
+	}
+	add("", "")
+
+	for _, e := range s.endpoints() {
+		name := ""
+		if len(e.Operation.Tags) > 0 {
+			name = e.Operation.Tags[0]
+		}
+		byName[name].Endpoints = append(byName[name].Endpoints, e)
+	}
+
+	for _, name := range order {
+		if len(byName[name].Endpoints) > 0 {
+			g = append(g, *byName[name])
+		}
+	}
+
+	return
+}
+
+// parameters merges path and operation parameters, operation taking precedence.
+func (s *spec) parameters(pathParams, opParams []*parameter) (params []*parameter) {
+	seen := map[string]int{}
+
+	for _, list := range [][]*parameter{pathParams, opParams} {
+		for _, p := range list {
+			if p == nil {
+				continue
+			}
+			if r := s.parameter(p); r != nil {
+				p = r
+			}
+
+			key := p.In + ":" + p.Name
+			if i, ok := seen[key]; ok && len(p.Ref) == 0 {
+				params[i] = p
+				continue
+			}
+
+			seen[key] = len(params)
+			params = append(params, p)
+		}
+	}
+
+	return
+}
+
+// refName returns the component name for a local reference of the given kind.
+func refName(ref, kind string) string {
+	prefix := "#/components/" + kind + "/"
+	if !strings.HasPrefix(ref, prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(ref, prefix)
+}
+
+func (s *spec) schema(x *schema) *schema {
+	if x == nil || len(x.Ref) == 0 {
+		return x
+	}
+	return s.Components.Schemas[refName(x.Ref, "schemas")]
+}
+
+func (s *spec) parameter(x *parameter) *parameter {
+	if len(x.Ref) == 0 {
+		return x
+	}
+	return s.Components.Parameters[refName(x.Ref, "parameters")]
+}
+
+func (s *spec) requestBody(x *requestBody) *requestBody {
+	if x == nil || len(x.Ref) == 0 {
+		return x
+	}
+	return s.Components.RequestBodies[refName(x.Ref, "requestBodies")]
+}
+
+func (s *spec) response(x *response) *response {
+	if x == nil || len(x.Ref) == 0 {
+		return x
+	}
+	return s.Components.Responses[refName(x.Ref, "responses")]
+}
+
+// schemaRefs lists every schema reference used anywhere in the spec.
+func (s *spec) schemaRefs() (refs []string) {
+	seen := map[*schema]bool{}
+
+	var walk func(x *schema)
+	walk = func(x *schema) {
+		if x == nil || seen[x] {
+			return
+		}
+		seen[x] = true
+
+		if len(x.Ref) > 0 {
+			refs = append(refs, x.Ref)
+		}
+		for _, p := range x.Properties {
+			walk(p)
+		}
+		walk(x.Items)
+		for _, list := range [][]*schema{x.AllOf, x.OneOf, x.AnyOf} {
+			for _, y := range list {
+				walk(y)
+			}
+		}
+	}
+
+	content := func(c map[string]*mediaType) {
+		for _, m := range c {
+			if m != nil {
+				walk(m.Schema)
+			}
+		}
+	}
+
+	for _, x := range s.Components.Schemas {
+		walk(x)
+	}
+	for _, p := range s.Components.Parameters {
+		if p != nil {
+			walk(p.Schema)
+		}
+	}
+	for _, b := range s.Components.RequestBodies {
+		if b != nil {
+			content(b.Content)
+		}
+	}
+	for _, r := range s.Components.Responses {
+		if r != nil {
+			content(r.Content)
+		}
+	}
+	for _, e := range s.endpoints() {
+		for _, p := range e.Params {
+			walk(p.Schema)
+		}
+		if b := e.Operation.RequestBody; b != nil {
+			content(b.Content)
+		}
+		for _, r := range e.Operation.Responses {
+			if r != nil {
+				content(r.Content)
+			}
+		}
+	}
+
+	sort.Strings(refs)
+
+	return
+}
+
+// slug makes an anchor safe identifier.
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
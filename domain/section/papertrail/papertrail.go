@@ -122,6 +122,8 @@ func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http
 		auth(p.Runtime, p.Store, ctx, config, w, r)
 	case "options":
 		options(config, w, r)
+	default:
+		provider.WriteMessage(w, me, "unknown method name")
 	}
 }
 
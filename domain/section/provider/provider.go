@@ -55,6 +55,12 @@ type Provider interface {
 	Refresh(ctx *Context, config, data string) string             // Refresh returns latest data
 }
 
+// Searcher is implemented by sections that index parts of their content
+// as separate search entries, e.g. one entry per API operation.
+type Searcher interface {
+	SearchItems(config, data string) []string // SearchItems returns text for each entry
+}
+
 // Context describes the environment the section code runs in
 type Context struct {
 	OrgID     string
@@ -122,6 +128,15 @@ func Render(section string, ctx *Context, config, data string) (string, bool) {
 	return "", false
 }
 
+// SearchItems returns separately searchable content for a section.
+// Sections that do not implement Searcher return none.
+func SearchItems(section, config, data string) []string {
+	if s, ok := sectionsMap[section].(Searcher); ok {
+		return s.SearchItems(config, data)
+	}
+	return nil
+}
+
 // Refresh returns the latest data for a section.
 func Refresh(section string, ctx *Context, config, data string) (string, bool) {
 	s, ok := sectionsMap[section]
@@ -164,28 +179,34 @@ func WriteEmpty(w http.ResponseWriter) {
 func WriteMarshalError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusBadRequest)
-	w.Write([]byte("{Error: 'JSON marshal failed'}"))
+	writeBody(w, "Error", "JSON marshal failed")
 }
 
 // WriteMessage write string to HTTP response.
 func WriteMessage(w http.ResponseWriter, section, msg string) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusBadRequest)
-	w.Write([]byte("{Message: " + msg + "}"))
+	writeBody(w, "Message", msg)
 }
 
 // WriteError write given error to HTTP response.
 func WriteError(w http.ResponseWriter, section string, err error) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusBadRequest)
-	w.Write([]byte("{Error: 'Internal server error'}"))
+	writeBody(w, "Error", "Internal server error")
 }
 
 // WriteForbidden write 403 to HTTP response.
 func WriteForbidden(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusForbidden)
-	w.Write([]byte("{Error: 'Unauthorized'}"))
+	writeBody(w, "Error", "Unauthorized")
+}
+
+// writeBody writes a single key and message as a JSON object.
+func writeBody(w http.ResponseWriter, key, msg string) {
+	j, _ := json.Marshal(map[string]string{key: msg})
+	w.Write(j)
 }
 
 // Secrets handling
@@ -0,0 +1,321 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package providertest checks that a section provider behaves the way
+// the section endpoints and document editor rely on.
+//
+// Construct the provider with NewRuntime and NewStore (or a store of your
+// own) and pass it to Run from a test. Run registers the provider under its
+// content type and swaps http.DefaultTransport for one that refuses
+// connections, so it must not be used from parallel tests.
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/section/provider"
+)
+
+// Canary is the value of every seeded secret. It must never appear in
+// rendered HTML, refreshed data or command responses.
+const Canary = "providertest-canary-secret"
+
+// defaultSecrets covers the secret names used by the built-in providers.
+var defaultSecrets = fmt.Sprintf(`{"token":%[1]q,"APIToken":%[1]q,"apikey":%[1]q,"password":%[1]q}`, Canary)
+
+// The conformance checks act as this organization. Secrets are seeded
+// for secretUser only so that plainUser exercises missing secrets.
+const (
+	orgID      = "providertest-org"
+	secretUser = "providertest-user"
+	plainUser  = "providertest-plain"
+)
+
+// Case is section config and data to render and refresh.
+type Case struct {
+	Name   string
+	Config string
+	Data   string
+}
+
+// Command is a Command method, with request body, that the provider
+// supports. Unknown methods and malformed bodies are always checked.
+type Command struct {
+	Method string
+	Body   string
+}
+
+// Options describe what to check beyond the defaults.
+type Options struct {
+	ID       string        // expected Meta().ID, IDs are persisted and must never change
+	Cases    []Case        // section config and data, empty and malformed values are always checked
+	Commands []Command     // supported Command methods
+	Secrets  string        // JSON secrets seeded for the user, values should contain Canary
+	Timeout  time.Duration // limit for each provider call, defaults to 30 seconds
+}
+
+// Run checks provider metadata, rendering, refreshing, commands and the
+// handling of secrets held in s.
+func Run(t *testing.T, p provider.Provider, s *domain.Store, opts Options) {
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if len(opts.Secrets) == 0 {
+		opts.Secrets = defaultSecrets
+	}
+
+	meta := p.Meta()
+	provider.Register(meta.ContentType, p)
+
+	transport := http.DefaultTransport
+	http.DefaultTransport = offline{}
+	defer func() { http.DefaultTransport = transport }()
+
+	if err := s.Setting.SetUser(orgID, secretUser, meta.ContentType, opts.Secrets); err != nil {
+		t.Fatalf("unable to seed secrets: %v", err)
+	}
+
+	t.Run("Meta", func(t *testing.T) { checkMeta(t, p, opts) })
+	t.Run("Render", func(t *testing.T) { checkRender(t, meta.ContentType, opts) })
+	t.Run("Refresh", func(t *testing.T) { checkRefresh(t, meta.ContentType, opts) })
+	t.Run("Command", func(t *testing.T) { checkCommand(t, meta.ContentType, opts) })
+}
+
+// Unique checks that registered providers have distinct IDs and are
+// registered under their own content type, which is how pages find them.
+func Unique(t *testing.T, providers map[string]provider.Provider) {
+	ids := make(map[string]string)
+
+	for name, p := range providers {
+		m := p.Meta()
+
+		if m.ContentType != name {
+			t.Errorf("provider registered as %q has content type %q", name, m.ContentType)
+		}
+		if other, ok := ids[m.ID]; ok {
+			t.Errorf("providers %q and %q share ID %q", other, name, m.ID)
+		}
+
+		ids[m.ID] = name
+	}
+}
+
+func checkMeta(t *testing.T, p provider.Provider, opts Options) {
+	m := p.Meta()
+
+	if len(m.ID) == 0 {
+		t.Error("missing ID")
+	}
+	if len(opts.ID) > 0 && m.ID != opts.ID {
+		t.Errorf("ID changed from %q to %q", opts.ID, m.ID)
+	}
+	if len(m.ContentType) == 0 || strings.ContainsAny(m.ContentType, " \t\n/?&") {
+		t.Errorf("content type %q is not usable in URLs", m.ContentType)
+	}
+	if m.PageType != "section" && m.PageType != "tab" {
+		t.Errorf("page type %q is neither section nor tab", m.PageType)
+	}
+	if len(m.Title) == 0 {
+		t.Error("missing title")
+	}
+
+	again := p.Meta()
+	m.Callback, again.Callback = nil, nil
+	if !reflect.DeepEqual(m, again) {
+		t.Errorf("Meta() is not stable, got %+v then %+v", m, again)
+	}
+}
+
+// cases returns the configured cases plus empty and malformed values.
+func cases(opts Options) []Case {
+	return append([]Case{
+		{Name: "empty"},
+		{Name: "malformed", Config: "{", Data: "{"},
+	}, opts.Cases...)
+}
+
+func checkRender(t *testing.T, contentType string, opts Options) {
+	for _, c := range cases(opts) {
+		for _, user := range []string{plainUser, secretUser} {
+			name := fmt.Sprintf("Render(%s) as %s", c.Name, user)
+			render(t, opts, name, contentType, user, c.Config, c.Data)
+		}
+	}
+}
+
+func checkRefresh(t *testing.T, contentType string, opts Options) {
+	for _, c := range cases(opts) {
+		for _, user := range []string{plainUser, secretUser} {
+			name := fmt.Sprintf("Refresh(%s) as %s", c.Name, user)
+
+			var data string
+			ok := call(t, opts.Timeout, name, func() {
+				data, _ = provider.Refresh(contentType, newContext(user), c.Config, c.Data)
+			})
+			if !ok {
+				continue
+			}
+			if strings.Contains(data, Canary) {
+				t.Errorf("%s leaked a secret into section data", name)
+			}
+
+			render(t, opts, name+" then Render", contentType, user, c.Config, data)
+		}
+	}
+}
+
+// render checks that rendering is repeatable and does not leak secrets.
+func render(t *testing.T, opts Options, name, contentType, user, config, data string) {
+	var first, second string
+
+	ok := call(t, opts.Timeout, name, func() {
+		first, _ = provider.Render(contentType, newContext(user), config, data)
+		second, _ = provider.Render(contentType, newContext(user), config, data)
+	})
+	if !ok {
+		return
+	}
+
+	if first != second {
+		t.Errorf("%s is not repeatable, got %q then %q", name, first, second)
+	}
+	if strings.Contains(first, Canary) {
+		t.Errorf("%s leaked a secret into HTML", name)
+	}
+}
+
+func checkCommand(t *testing.T, contentType string, opts Options) {
+	commands := []Command{
+		{Method: ""},
+		{Method: "providertest-unknown", Body: "{}"},
+	}
+	for _, c := range opts.Commands {
+		commands = append(commands, c, Command{Method: c.Method, Body: "{"})
+	}
+
+	for _, c := range commands {
+		for _, user := range []string{plainUser, secretUser} {
+			name := fmt.Sprintf("Command(%q, %q) as %s", c.Method, c.Body, user)
+
+			w := &recorder{ResponseRecorder: httptest.NewRecorder()}
+			ok := call(t, opts.Timeout, name, func() {
+				provider.Command(contentType, newContext(user), w, newRequest(contentType, user, c))
+			})
+			if !ok {
+				continue
+			}
+
+			if !w.wrote {
+				t.Errorf("%s wrote no response", name)
+				continue
+			}
+			if w.Code >= http.StatusInternalServerError {
+				t.Errorf("%s returned status %d", name, w.Code)
+			}
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") && !json.Valid(w.Body.Bytes()) {
+				t.Errorf("%s returned invalid JSON %q", name, w.Body.String())
+			}
+			if strings.Contains(w.Body.String(), Canary) {
+				t.Errorf("%s leaked a secret into the response", name)
+			}
+		}
+	}
+}
+
+// newContext returns what the section endpoints give providers.
+func newContext(user string) *provider.Context {
+	return provider.NewContext(orgID, user, requestContext(user))
+}
+
+func requestContext(user string) domain.RequestContext {
+	return domain.RequestContext{
+		Authenticated: true,
+		Editor:        true,
+		OrgID:         orgID,
+		UserID:        user,
+		AppURL:        "localhost",
+		Expires:       time.Now().Add(time.Hour),
+	}
+}
+
+// newRequest builds a request like those sent to the section command endpoint.
+func newRequest(contentType, user string, c Command) *http.Request {
+	url := fmt.Sprintf("/api/sections?documentID=providertest-document&section=%s&method=%s", contentType, c.Method)
+	r := httptest.NewRequest(http.MethodPost, url, strings.NewReader(c.Body))
+	return r.WithContext(context.WithValue(r.Context(), domain.DocumizeContextKey, requestContext(user)))
+}
+
+// call runs fn, reporting panics and calls that do not return in time.
+func call(t *testing.T, timeout time.Duration, name string, fn func()) bool {
+	type result struct {
+		recovered interface{}
+		stack     []byte
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			r := recover()
+			var stack []byte
+			if r != nil {
+				stack = debug.Stack()
+			}
+			done <- result{recovered: r, stack: stack}
+		}()
+		fn()
+	}()
+
+	select {
+	case r := <-done:
+		if r.recovered != nil {
+			t.Errorf("%s panicked: %v\n%s", name, r.recovered, r.stack)
+			return false
+		}
+		return true
+	case <-time.After(timeout):
+		t.Errorf("%s did not return within %s", name, timeout)
+		return false
+	}
+}
+
+// recorder notes whether the provider wrote a response at all.
+type recorder struct {
+	*httptest.ResponseRecorder
+	wrote bool
+}
+
+func (r *recorder) WriteHeader(code int) {
+	r.wrote = true
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.wrote = true
+	return r.ResponseRecorder.Write(b)
+}
+
+// offline refuses all requests so checks never depend on remote services.
+type offline struct{}
+
+func (offline) RoundTrip(r *http.Request) (*http.Response, error) {
+	return nil, errors.New("providertest: network access disabled for " + r.URL.Host)
+}
@@ -0,0 +1,253 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package providertest
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/attachment"
+	"github.com/documize/community/model/datasource"
+	"github.com/pkg/errors"
+)
+
+// NewRuntime returns a runtime that logs through t and has no database.
+func NewRuntime(t *testing.T) *env.Runtime {
+	rt := new(env.Runtime)
+	rt.Log = logger{t: t}
+	rt.Product = env.ProdInfo{Major: "0", Minor: "0", Patch: "0", Version: "0.0.0", Edition: "Test"}
+	return rt
+}
+
+// NewStore returns a store whose Setting, Attachment and DataSource members
+// are kept in memory. Other members are nil, so a provider that reaches
+// for them fails the conformance checks with a recovered panic.
+func NewStore() *domain.Store {
+	s := new(domain.Store)
+	s.Setting = &settings{global: map[string]string{}, user: map[string]string{}}
+	s.Attachment = &attachments{items: map[string]attachment.Attachment{}}
+	s.DataSource = &dataSources{items: map[string]datasource.DataSource{}}
+	return s
+}
+
+// logger sends runtime logging to the test log.
+type logger struct {
+	t *testing.T
+}
+
+func (l logger) Info(message string) {
+	l.t.Log(message)
+}
+
+func (l logger) Error(message string, err error) {
+	l.t.Logf("%s: %v", message, err)
+}
+
+// settings mirrors the JSON path behaviour of the MySQL setting store.
+type settings struct {
+	sync.Mutex
+	global map[string]string
+	user   map[string]string
+}
+
+func (s *settings) Get(area, path string) (val string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	return extract(s.global[area], path)
+}
+
+func (s *settings) Set(area, value string) error {
+	if area == "" {
+		return errors.New("no area")
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.global[area] = value
+	return nil
+}
+
+func (s *settings) GetUser(orgID, userID, area, path string) (val string, err error) {
+	s.Lock()
+	defer s.Unlock()
+	return extract(s.user[userKey(orgID, userID, area)], path)
+}
+
+func (s *settings) SetUser(orgID, userID, area, value string) error {
+	if area == "" {
+		return errors.New("no area")
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.user[userKey(orgID, userID, area)] = value
+	return nil
+}
+
+func userKey(orgID, userID, area string) string {
+	return fmt.Sprintf("%s/%s/%s", orgID, userID, area)
+}
+
+// extract returns the value at a dotted path within JSON config,
+// without surrounding quotes, as JSON_EXTRACT does for the MySQL store.
+// Missing config or paths return the empty string.
+func extract(config, path string) (val string, err error) {
+	if len(config) == 0 {
+		return "", nil
+	}
+
+	var v interface{}
+	if err = json.Unmarshal([]byte(config), &v); err != nil {
+		return "", errors.Wrap(err, "invalid config JSON")
+	}
+
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return "", nil
+			}
+			if v, ok = m[strings.Trim(key, `"`)]; !ok {
+				return "", nil
+			}
+		}
+	}
+
+	j, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimPrefix(strings.TrimSuffix(string(j), `"`), `"`), nil
+}
+
+// attachments holds document attachments by RefID.
+type attachments struct {
+	sync.Mutex
+	items map[string]attachment.Attachment
+}
+
+func (s *attachments) Add(ctx domain.RequestContext, a attachment.Attachment) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	a.OrgID = ctx.OrgID
+	s.items[a.RefID] = a
+	return nil
+}
+
+func (s *attachments) GetAttachment(ctx domain.RequestContext, orgID, attachmentID string) (a attachment.Attachment, err error) {
+	s.Lock()
+	defer s.Unlock()
+	a, ok := s.items[attachmentID]
+	if !ok || a.OrgID != orgID {
+		return a, errors.Wrap(sql.ErrNoRows, "execute select attachment")
+	}
+	return a, nil
+}
+
+func (s *attachments) GetAttachments(ctx domain.RequestContext, docID string) (a []attachment.Attachment, err error) {
+	a, err = s.GetAttachmentsWithData(ctx, docID)
+	for i := range a {
+		a[i].Data = nil
+	}
+	return
+}
+
+func (s *attachments) GetAttachmentsWithData(ctx domain.RequestContext, docID string) (a []attachment.Attachment, err error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, x := range s.items {
+		if x.OrgID == ctx.OrgID && x.DocumentID == docID {
+			a = append(a, x)
+		}
+	}
+	sort.Slice(a, func(i, j int) bool { return a[i].Filename < a[j].Filename })
+	return a, nil
+}
+
+func (s *attachments) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.items[id]; ok {
+		delete(s.items, id)
+		rows = 1
+	}
+	return
+}
+
+// dataSources holds query data sources by RefID.
+type dataSources struct {
+	sync.Mutex
+	items map[string]datasource.DataSource
+}
+
+func (s *dataSources) Add(ctx domain.RequestContext, d datasource.DataSource) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	d.OrgID = ctx.OrgID
+	s.items[d.RefID] = d
+	return nil
+}
+
+func (s *dataSources) Get(ctx domain.RequestContext, id string) (d datasource.DataSource, err error) {
+	s.Lock()
+	defer s.Unlock()
+	d, ok := s.items[id]
+	if !ok || d.OrgID != ctx.OrgID {
+		return d, errors.Wrap(sql.ErrNoRows, "unable to get data source")
+	}
+	return d, nil
+}
+
+func (s *dataSources) GetAll(ctx domain.RequestContext) (d []datasource.DataSource, err error) {
+	s.Lock()
+	defer s.Unlock()
+	d = []datasource.DataSource{}
+	for _, x := range s.items {
+		if x.OrgID == ctx.OrgID {
+			x.DSN = ""
+			d = append(d, x)
+		}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i].Name < d[j].Name })
+	return d, nil
+}
+
+func (s *dataSources) Update(ctx domain.RequestContext, d datasource.DataSource) (err error) {
+	s.Lock()
+	defer s.Unlock()
+	current, ok := s.items[d.RefID]
+	if !ok || current.OrgID != ctx.OrgID {
+		return errors.Wrap(sql.ErrNoRows, "unable to update data source")
+	}
+	if len(d.DSN) == 0 {
+		d.DSN = current.DSN
+	}
+	d.OrgID = current.OrgID
+	s.items[d.RefID] = d
+	return nil
+}
+
+func (s *dataSources) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if d, ok := s.items[id]; ok && d.OrgID == ctx.OrgID {
+		delete(s.items, id)
+		rows = 1
+	}
+	return
+}
@@ -17,12 +17,16 @@ import (
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/section/airtable"
+	"github.com/documize/community/domain/section/chart"
 	"github.com/documize/community/domain/section/code"
+	"github.com/documize/community/domain/section/datasource"
 	"github.com/documize/community/domain/section/gemini"
 	"github.com/documize/community/domain/section/github"
 	"github.com/documize/community/domain/section/markdown"
+	"github.com/documize/community/domain/section/openapi"
 	"github.com/documize/community/domain/section/papertrail"
 	"github.com/documize/community/domain/section/provider"
+	"github.com/documize/community/domain/section/rest"
 	"github.com/documize/community/domain/section/table"
 	"github.com/documize/community/domain/section/trello"
 	"github.com/documize/community/domain/section/wysiwyg"
@@ -30,13 +34,16 @@ import (
 
 // Register sections
 func Register(rt *env.Runtime, s *domain.Store) {
+	provider.Register("chart", &chart.Provider{Runtime: rt, Store: s})
 	provider.Register("code", &code.Provider{Runtime: rt, Store: s})
+	provider.Register("datasource", &datasource.Provider{Runtime: rt, Store: s})
 	provider.Register("gemini", &gemini.Provider{Runtime: rt, Store: s})
 	provider.Register("github", &github.Provider{Runtime: rt, Store: s})
 	provider.Register("markdown", &markdown.Provider{Runtime: rt, Store: s})
+	provider.Register("openapi", &openapi.Provider{Runtime: rt, Store: s})
 	provider.Register("papertrail", &papertrail.Provider{Runtime: rt, Store: s})
+	provider.Register("rest", &rest.Provider{Runtime: rt, Store: s})
 	provider.Register("table", &table.Provider{Runtime: rt, Store: s})
-	provider.Register("code", &code.Provider{Runtime: rt, Store: s})
 	provider.Register("trello", &trello.Provider{Runtime: rt, Store: s})
 	provider.Register("wysiwyg", &wysiwyg.Provider{Runtime: rt, Store: s})
 	provider.Register("airtable", &airtable.Provider{Runtime: rt, Store: s})
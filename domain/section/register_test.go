@@ -0,0 +1,78 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package section
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/documize/community/domain/section/provider"
+	"github.com/documize/community/domain/section/provider/providertest"
+)
+
+// TestProviders runs the conformance checks over every built-in section.
+func TestProviders(t *testing.T) {
+	s := providertest.NewStore()
+	Register(providertest.NewRuntime(t), s)
+
+	providertest.Unique(t, provider.List())
+
+	spec := "openapi: 3.0.0\ninfo:\n  title: Pets\n  version: '1'\npaths:\n  /pets:\n    get:\n      summary: List pets\n      responses:\n        '200':\n          description: OK\n"
+
+	options := map[string]providertest.Options{
+		"airtable": {ID: "3cfa411e-73bf-474c-841a-effd6b00fdd8"},
+		"chart": {
+			ID:       "c3f6eb53-2ba7-467d-b464-1b37b8640587",
+			Cases:    []providertest.Case{{Name: "bar", Config: `{"type":"bar","title":"Sales"}`, Data: "month,sales\nJan,10\nFeb,20"}},
+			Commands: []providertest.Command{{Method: "preview", Body: `{"config":{"type":"pie"},"data":"a,b\nx,1"}`}},
+		},
+		"code": {
+			ID:       "4f6f2b02-8397-483d-9bb9-eea1fef13304",
+			Cases:    []providertest.Case{{Name: "go", Config: `{"lang":"go","lineNumbers":true,"highlight":"1"}`, Data: "x := 1"}},
+			Commands: []providertest.Command{{Method: "options"}},
+		},
+		"datasource": {
+			ID:       "d2c59bb5-52ee-4fae-85d6-0884e95fdb31",
+			Cases:    []providertest.Case{{Name: "missing source", Config: `{"dataSourceId":"none","query":"SELECT 1"}`}},
+			Commands: []providertest.Command{{Method: "sources"}, {Method: "preview", Body: `{"dataSourceId":"none","query":"SELECT 1"}`}},
+		},
+		"gemini":   {ID: "23b133f9-4020-4616-9291-a98fb939735f"},
+		"github":   {ID: "38c0e4c5-291c-415e-8a4d-262ee80ba5df"},
+		"markdown": {ID: "1470bb4a-36c6-4a98-a443-096f5658378b", Cases: []providertest.Case{{Name: "heading", Data: "# Title\n\n[[toc]]"}}},
+		"openapi": {
+			ID:       "4bb1a81b-8c95-4939-8c38-a487bb4f97aa",
+			Cases:    []providertest.Case{{Name: "spec", Data: spec}},
+			Commands: []providertest.Command{{Method: "validate", Body: fmt.Sprintf(`{"spec":%q}`, spec)}},
+		},
+		"papertrail": {ID: "db0a3a0a-b5d4-4d00-bfac-ee28abba451d"},
+		"rest": {
+			ID:       "94efc6bf-9ea6-4a44-8fe7-b6c591e521ed",
+			Cases:    []providertest.Case{{Name: "secret header", Config: `{"url":"https://example.invalid/api","method":"GET","headers":[{"name":"Authorization","secret":true}],"secretKey":"k"}`}},
+			Commands: []providertest.Command{{Method: "preview", Body: `{"url":"ftp://example.invalid","secretKey":"k"}`}},
+			Secrets:  fmt.Sprintf(`{"k":{"Authorization":%q}}`, providertest.Canary),
+		},
+		"table":   {ID: "81a2ea93-2dfc-434d-841e-54b832492c92", Cases: []providertest.Case{{Name: "table", Data: "<table><tr><td>1</td></tr></table>"}}},
+		"trello":  {ID: "c455a552-202e-441c-ad79-397a8152920b"},
+		"wysiwyg": {ID: "0f024fa0-d017-4bad-a094-2c13ce6edad7", Cases: []providertest.Case{{Name: "paragraph", Data: "<p>Hello</p>"}}},
+	}
+
+	for name, p := range provider.List() {
+		opts, ok := options[name]
+		if !ok {
+			t.Errorf("no conformance options for section %s", name)
+		}
+
+		t.Run(name, func(t *testing.T) {
+			providertest.Run(t, p, s, opts)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package rest
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultTemplate is used when no template is configured.
+const defaultTemplate = `<pre>{{json .}}</pre>`
+
+// restConfig is saved as page meta config by the section editor.
+// Secret header values are never saved here, see restSecrets.
+type restConfig struct {
+	URL       string       `json:"url"`
+	Method    string       `json:"method"`    // GET or POST
+	Body      string       `json:"body"`      // POST payload
+	Headers   []restHeader `json:"headers"`   // sent with each request
+	Query     string       `json:"query"`     // JMESPath expression applied to response JSON
+	Template  string       `json:"template"`  // html/template applied to query result
+	SecretKey string       `json:"secretKey"` // identifies this section's secrets
+}
+
+// restHeader is an HTTP request header.
+// Secret values are kept in user secrets and replaced in config.
+type restHeader struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Secret bool   `json:"secret"`
+}
+
+// restSecrets maps section secret keys to header name/value pairs.
+type restSecrets map[string]map[string]string
+
+// Clean removes surrounding whitespace and applies defaults.
+func (c *restConfig) Clean() {
+	c.URL = strings.TrimSpace(c.URL)
+	c.Method = strings.ToUpper(strings.TrimSpace(c.Method))
+	c.Query = strings.TrimSpace(c.Query)
+	c.SecretKey = strings.TrimSpace(c.SecretKey)
+
+	if c.Method != http.MethodPost {
+		c.Method = http.MethodGet
+	}
+	if len(strings.TrimSpace(c.Template)) == 0 {
+		c.Template = defaultTemplate
+	}
+
+	var headers []restHeader
+	for _, h := range c.Headers {
+		h.Name = strings.TrimSpace(h.Name)
+		if len(h.Name) > 0 {
+			headers = append(headers, h)
+		}
+	}
+	c.Headers = headers
+}
+
+// restData is saved as page meta raw body on each refresh.
+type restData struct {
+	Status int         `json:"status"` // HTTP status code
+	Result interface{} `json:"result"` // query result
+	Error  string      `json:"error"`
+}
+
+// restPreview is sent back to the section editor.
+type restPreview struct {
+	Data restData `json:"data"`
+	HTML string   `json:"html"`
+}
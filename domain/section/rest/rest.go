@@ -0,0 +1,276 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package rest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/section/provider"
+	"github.com/jmespath/go-jmespath"
+)
+
+const me = "rest"
+
+// maxResponse caps how much of a response body is read.
+const maxResponse = 5 << 20
+
+var client = &http.Client{Timeout: 20 * time.Second}
+
+// Provider represents a generic REST/JSON endpoint.
+type Provider struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// Meta describes us.
+func (*Provider) Meta() provider.TypeMeta {
+	section := provider.TypeMeta{}
+	section.ID = "94efc6bf-9ea6-4a44-8fe7-b6c591e521ed"
+	section.Title = "REST"
+	section.Description = "Data from any JSON web service"
+	section.ContentType = "rest"
+	section.PageType = "tab"
+
+	return section
+}
+
+// Command previews configured request, storing any new secret header values.
+func (p *Provider) Command(ctx *provider.Context, w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+
+	if len(method) == 0 {
+		provider.WriteMessage(w, me, "missing method name")
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		provider.WriteMessage(w, me, "Bad payload")
+		return
+	}
+
+	c := restConfig{}
+	err = json.Unmarshal(body, &c)
+	if err != nil {
+		provider.WriteMessage(w, me, "Bad config")
+		return
+	}
+
+	c.Clean()
+
+	switch method {
+	case "preview":
+		err = p.saveSecrets(ctx, &c)
+		if err != nil {
+			p.Runtime.Log.Error("unable to save REST section secrets", err)
+			provider.WriteMessage(w, me, err.Error())
+			return
+		}
+
+		d := fetch(c)
+		provider.WriteJSON(w, restPreview{Data: d, HTML: render(c, d)})
+	default:
+		provider.WriteMessage(w, me, "unknown method name")
+	}
+}
+
+// Render applies configured template to the last fetched result.
+func (p *Provider) Render(ctx *provider.Context, config, data string) string {
+	c := restConfig{}
+	json.Unmarshal([]byte(config), &c)
+	c.Clean()
+
+	d := restData{}
+	json.Unmarshal([]byte(data), &d)
+
+	return render(c, d)
+}
+
+// Refresh requests fresh data using the section owner's secrets.
+func (p *Provider) Refresh(ctx *provider.Context, config, data string) string {
+	c := restConfig{}
+	err := json.Unmarshal([]byte(config), &c)
+	if err != nil {
+		p.Runtime.Log.Error("unable to read REST section config", err)
+		return data
+	}
+
+	c.Clean()
+	p.loadSecrets(ctx, &c)
+
+	j, err := json.Marshal(fetch(c))
+	if err != nil {
+		p.Runtime.Log.Error("unable to marshal REST section data", err)
+		return data
+	}
+
+	return string(j)
+}
+
+// saveSecrets stores secret header values typed into the editor and
+// fills in previously stored values the editor only sees as placeholders.
+func (p *Provider) saveSecrets(ctx *provider.Context, c *restConfig) error {
+	changed := false
+	for _, h := range c.Headers {
+		if h.Secret && h.Value != provider.SecretReplacement {
+			changed = true
+		}
+	}
+
+	if !changed {
+		p.loadSecrets(ctx, c)
+		return nil
+	}
+
+	if len(c.SecretKey) == 0 {
+		return errors.New("missing secret key")
+	}
+
+	s := restSecrets{}
+	ctx.UnmarshalSecrets(&s, p.Store) // none saved yet is fine
+
+	stored := s[c.SecretKey]
+	values := map[string]string{}
+	for i, h := range c.Headers {
+		if !h.Secret {
+			continue
+		}
+		if h.Value == provider.SecretReplacement {
+			c.Headers[i].Value = stored[h.Name]
+		}
+		values[h.Name] = c.Headers[i].Value
+	}
+
+	s[c.SecretKey] = values
+
+	return ctx.MarshalSecrets(s, p.Store)
+}
+
+// loadSecrets replaces secret header placeholders with stored values.
+func (p *Provider) loadSecrets(ctx *provider.Context, c *restConfig) {
+	s := restSecrets{}
+	if err := ctx.UnmarshalSecrets(&s, p.Store); err != nil {
+		return
+	}
+
+	stored := s[c.SecretKey]
+	for i, h := range c.Headers {
+		if h.Secret {
+			c.Headers[i].Value = stored[h.Name]
+		}
+	}
+}
+
+// fetch calls the endpoint and extracts the configured part of the response.
+func fetch(c restConfig) (d restData) {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		d.Error = "URL must be an absolute http or https address"
+		return
+	}
+
+	var payload io.Reader
+	if c.Method == http.MethodPost {
+		payload = strings.NewReader(c.Body)
+	}
+
+	req, err := http.NewRequest(c.Method, u.String(), payload)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.Method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, h := range c.Headers {
+		req.Header.Set(h.Name, h.Value)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		d.Error = err.Error()
+		return
+	}
+	defer res.Body.Close()
+
+	d.Status = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		d.Error = fmt.Sprintf("request failed with status %s", res.Status)
+		return
+	}
+
+	var result interface{}
+	err = json.NewDecoder(io.LimitReader(res.Body, maxResponse)).Decode(&result)
+	if err != nil {
+		d.Error = "response is not valid JSON: " + err.Error()
+		return
+	}
+
+	if len(c.Query) > 0 {
+		result, err = jmespath.Search(c.Query, result)
+		if err != nil {
+			d.Error = "query failed: " + err.Error()
+			return
+		}
+	}
+
+	d.Result = result
+
+	return
+}
+
+// templateFuncs are available to section templates.
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) string {
+		b := new(bytes.Buffer)
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false) // template escapes output
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return strings.TrimSpace(b.String())
+	},
+}
+
+// render executes section template against fetched data.
+func render(c restConfig, d restData) string {
+	if len(d.Error) > 0 {
+		return fmt.Sprintf(`<p class="color-red">%s</p>`, template.HTMLEscapeString(d.Error))
+	}
+
+	t, err := template.New(me).Funcs(templateFuncs).Parse(c.Template)
+	if err != nil {
+		return fmt.Sprintf(`<p class="color-red">Template error: %s</p>`, template.HTMLEscapeString(err.Error()))
+	}
+
+	buffer := new(bytes.Buffer)
+	err = t.Execute(buffer, d.Result)
+	if err != nil {
+		return fmt.Sprintf(`<p class="color-red">Template error: %s</p>`, template.HTMLEscapeString(err.Error()))
+	}
+
+	return buffer.String()
+}
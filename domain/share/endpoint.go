@@ -0,0 +1,380 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package share provides read-only links to single documents
+// for people without an account.
+package share
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/secrets"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/mail"
+	"github.com/documize/community/domain/organization"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/attachment"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/share"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// maxMessage matches the share.message column.
+const maxMessage = 500
+
+// Add creates a share link for a document, emailing it when an address is given.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	method := "share.add"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+
+	if ctx.Guest || !space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	m := share.NewShare{}
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	m.Email = strings.TrimSpace(m.Email)
+	m.Message = strings.TrimSpace(m.Message)
+
+	if len(m.Message) > maxMessage {
+		response.WriteBadRequestError(w, method, fmt.Sprintf("message exceeds %d characters", maxMessage))
+		return
+	}
+	if m.Expires != nil && !m.Expires.After(time.Now()) {
+		response.WriteBadRequestError(w, method, "expiry must be in the future")
+		return
+	}
+
+	sh := share.Share{}
+	sh.RefID = uniqueid.Generate()
+	sh.DocumentID = documentID
+	sh.UserID = ctx.UserID
+	sh.Email = m.Email
+	sh.Message = m.Message
+	sh.Secret = secrets.GenerateRandom(32)
+	if m.Expires != nil {
+		sh.Expires = m.Expires.UTC().Format(share.TimeFormat)
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Share.Add(ctx, sh)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeShareAdd)
+
+	sh, err = h.Store.Share.Get(ctx, sh.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	sh.URL = link(ctx, sh)
+
+	if len(sh.Email) > 0 {
+		mailer := mail.Mailer{Runtime: h.Runtime, Store: h.Store, Context: ctx}
+		go mailer.ShareDocument(sh.Email, ctx.Fullname, sh.URL, d.Title, sh.Message, sh.Expires)
+	}
+
+	response.WriteJSON(w, sh)
+}
+
+// GetByDocument lists share links for a document, including revoked ones.
+func (h *Handler) GetByDocument(w http.ResponseWriter, r *http.Request) {
+	method := "share.getByDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	shares, err := h.Store.Share.GetByDocument(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(shares) == 0 {
+		shares = []share.Share{}
+	}
+
+	for i := range shares {
+		shares[i].URL = link(ctx, shares[i])
+	}
+
+	response.WriteJSON(w, shares)
+}
+
+// Revoke stops a share link from working.
+func (h *Handler) Revoke(w http.ResponseWriter, r *http.Request) {
+	method := "share.revoke"
+	ctx := domain.GetRequestContext(r)
+
+	shareID := request.Param(r, "shareID")
+	if len(shareID) == 0 {
+		response.WriteMissingDataError(w, method, "shareID")
+		return
+	}
+
+	sh, err := h.Store.Share.Get(ctx, shareID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, shareID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	// links to documents since deleted can still be revoked by their creator
+	allowed := sh.UserID == ctx.UserID
+	if d, err := h.Store.Document.Get(ctx, sh.DocumentID); err == nil {
+		allowed = space.CanChangeSpaceDocuments(ctx, *h.Store, d.LabelID)
+	}
+
+	if ctx.Guest || !allowed {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Share.Revoke(ctx, shareID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeShareRevoke)
+
+	response.WriteEmpty(w)
+}
+
+// View returns the shared document, its sections and attachments to anyone holding the secret.
+func (h *Handler) View(w http.ResponseWriter, r *http.Request) {
+	method := "share.view"
+	ctx := domain.GetRequestContext(r)
+
+	sh, ok := h.resolve(w, r, &ctx, method)
+	if !ok {
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, sh.DocumentID)
+	if err != nil {
+		response.WriteNotFoundError(w, method, sh.DocumentID)
+		return
+	}
+
+	pages, err := h.Store.Page.GetPages(ctx, sh.DocumentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	files, err := h.Store.Attachment.GetAttachments(ctx, sh.DocumentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(pages) == 0 {
+		pages = []page.Page{}
+	}
+	if len(files) == 0 {
+		files = []attachment.Attachment{}
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Share.RecordView(ctx, sh.RefID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeShareView)
+
+	response.WriteJSON(w, share.Document{Document: d, Pages: pages, Attachments: files})
+}
+
+// Download sends an attachment of the shared document to anyone holding the secret.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	method := "share.download"
+	ctx := domain.GetRequestContext(r)
+
+	sh, ok := h.resolve(w, r, &ctx, method)
+	if !ok {
+		return
+	}
+
+	attachmentID := request.Param(r, "attachmentID")
+
+	a, err := h.Store.Attachment.GetAttachment(ctx, ctx.OrgID, attachmentID)
+	if err != nil || a.DocumentID != sh.DocumentID {
+		response.WriteNotFoundError(w, method, attachmentID)
+		return
+	}
+
+	typ := mime.TypeByExtension("." + a.Extension)
+	if typ == "" {
+		typ = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", typ)
+	w.Header().Set("Content-Disposition", `Attachment; filename="`+a.Filename+`" ; `+`filename*="`+a.Filename+`"`)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", len(a.Data)))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(a.Data)
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeAttachmentDownload)
+}
+
+// resolve finds the organization from the request host and the share
+// link from its secret, rejecting links that are revoked or expired.
+// Share links do not authenticate so the context is set up here.
+func (h *Handler) resolve(w http.ResponseWriter, r *http.Request, ctx *domain.RequestContext, method string) (sh share.Share, ok bool) {
+	ctx.Subdomain = organization.GetSubdomainFromHost(r)
+
+	secret := request.Param(r, "secret")
+	if len(secret) == 0 {
+		response.WriteMissingDataError(w, method, "secret")
+		return
+	}
+
+	org, err := h.Store.Organization.GetOrganizationByDomain(ctx.Subdomain)
+	if err != nil {
+		response.WriteNotFoundError(w, method, secret)
+		return
+	}
+
+	ctx.OrgID = org.RefID
+
+	sh, err = h.Store.Share.GetBySecret(*ctx, secret)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, secret)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if !sh.Active || sh.Expired(time.Now()) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	return sh, true
+}
+
+// link is the address recipients open to read the shared document.
+func link(ctx domain.RequestContext, sh share.Share) string {
+	return ctx.GetAppURL(fmt.Sprintf("shared/%s", sh.Secret))
+}
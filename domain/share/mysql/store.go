@@ -0,0 +1,112 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/share"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+const selectShare = `SELECT id, refid, orgid, documentid, coalesce(userid,'') as userid, email, message, viewed, views,
+	secret, coalesce(expires,'') as expires, active, created FROM share`
+
+// Add records a new share link.
+func (s Scope) Add(ctx domain.RequestContext, sh share.Share) (err error) {
+	sh.OrgID = ctx.OrgID
+	sh.Created = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO share (refid, orgid, documentid, userid, email, message, secret, expires, active, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert share")
+		return
+	}
+
+	_, err = stmt.Exec(sh.RefID, sh.OrgID, sh.DocumentID, sh.UserID, sh.Email, sh.Message, sh.Secret, sh.Expires, true, sh.Created)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert share")
+		return
+	}
+
+	return
+}
+
+// Get returns a share link.
+func (s Scope) Get(ctx domain.RequestContext, id string) (sh share.Share, err error) {
+	err = s.Runtime.Db.Get(&sh, selectShare+" WHERE orgid=? AND refid=?", ctx.OrgID, id)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select share %s", id))
+		return
+	}
+
+	return
+}
+
+// GetBySecret returns the share link holding the given secret.
+func (s Scope) GetBySecret(ctx domain.RequestContext, secret string) (sh share.Share, err error) {
+	err = s.Runtime.Db.Get(&sh, selectShare+" WHERE orgid=? AND secret=?", ctx.OrgID, secret)
+
+	if err != nil {
+		err = errors.Wrap(err, "select share by secret")
+		return
+	}
+
+	return
+}
+
+// GetByDocument returns all share links for a document, newest first.
+func (s Scope) GetByDocument(ctx domain.RequestContext, documentID string) (sh []share.Share, err error) {
+	err = s.Runtime.Db.Select(&sh, selectShare+" WHERE orgid=? AND documentid=? ORDER BY id DESC", ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select shares for document %s", documentID))
+		return
+	}
+
+	return
+}
+
+// RecordView notes that a share link was opened.
+func (s Scope) RecordView(ctx domain.RequestContext, id string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE share SET viewed=?, views=views+1 WHERE orgid=? AND refid=?",
+		time.Now().UTC().Format(share.TimeFormat), ctx.OrgID, id)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update share view %s", id))
+	}
+
+	return
+}
+
+// Revoke deactivates a share link so it can no longer be used.
+func (s Scope) Revoke(ctx domain.RequestContext, id string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE share SET active=0 WHERE orgid=? AND refid=?", ctx.OrgID, id)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("revoke share %s", id))
+	}
+
+	return
+}
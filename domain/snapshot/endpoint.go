@@ -0,0 +1,424 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package snapshot
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	pagediff "github.com/documize/community/domain/page"
+	indexer "github.com/documize/community/domain/search"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/snapshot"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+	Indexer indexer.Indexer
+}
+
+// current identifies the live document when comparing snapshots.
+const current = "current"
+
+// Add captures the document as a named snapshot.
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.add"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	sn := snapshot.Snapshot{}
+	err = json.Unmarshal(body, &sn)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	sn.Name = strings.TrimSpace(sn.Name)
+	if len(sn.Name) == 0 {
+		response.WriteMissingDataError(w, method, "name")
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	sn, err = h.save(ctx, documentID, sn.Name, sn.Note)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSnapshotAdd)
+
+	ctx.Transaction.Commit()
+
+	sn, err = h.Store.Snapshot.Get(ctx, sn.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, sn)
+}
+
+// GetByDocument lists snapshots of a document, newest first.
+func (h *Handler) GetByDocument(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.list"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	sn, err := h.Store.Snapshot.GetByDocument(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(sn) == 0 {
+		sn = []snapshot.Snapshot{}
+	}
+
+	response.WriteJSON(w, sn)
+}
+
+// Get returns a snapshot with the document content it captured.
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.get"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	snapshotID := request.Param(r, "snapshotID")
+	if len(snapshotID) == 0 {
+		response.WriteMissingDataError(w, method, "snapshotID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	sn, ok := h.get(w, ctx, method, documentID, snapshotID)
+	if !ok {
+		return
+	}
+
+	c, err := content(sn)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for i := range c.Attachments {
+		c.Attachments[i].Data = nil
+	}
+
+	response.WriteJSON(w, snapshot.View{Snapshot: sn, Content: c})
+}
+
+// Diff compares two snapshots, or a snapshot with the live document
+// when otherID is "current".
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.diff"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	snapshotID := request.Param(r, "snapshotID")
+	if len(snapshotID) == 0 {
+		response.WriteMissingDataError(w, method, "snapshotID")
+		return
+	}
+
+	otherID := request.Param(r, "otherID")
+	if len(otherID) == 0 {
+		response.WriteMissingDataError(w, method, "otherID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	before, ok := h.pages(w, ctx, method, documentID, snapshotID)
+	if !ok {
+		return
+	}
+
+	after, ok := h.pages(w, ctx, method, documentID, otherID)
+	if !ok {
+		return
+	}
+
+	response.WriteJSON(w, pagediff.Compare(before, after, true))
+}
+
+// Restore makes the document match a snapshot. The document is
+// snapshotted first so the restore itself can be undone, and sections
+// added since the snapshot are moved to trash.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.restore"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	snapshotID := request.Param(r, "snapshotID")
+	if len(snapshotID) == 0 {
+		response.WriteMissingDataError(w, method, "snapshotID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	locks, err := h.Store.Page.GetDocumentLocks(ctx, documentID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	for _, l := range locks {
+		if l.UserID != ctx.UserID {
+			response.WriteLockedError(w, method, l)
+			return
+		}
+	}
+
+	sn, ok := h.get(w, ctx, method, documentID, snapshotID)
+	if !ok {
+		return
+	}
+
+	c, err := content(sn)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.save(ctx, documentID, fmt.Sprintf("Before restoring %s", sn.Name), "")
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	changes, err := h.restore(ctx, documentID, c)
+	if errors.Cause(err) == domain.ErrVersionConflict {
+		ctx.Transaction.Rollback()
+		response.WriteConflictError(w, method, sn)
+		return
+	}
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      d.LabelID,
+		SourceID:     documentID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activity.TypeReverted})
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSnapshotRestore)
+
+	ctx.Transaction.Commit()
+
+	for _, p := range changes.Indexed {
+		go h.Indexer.IndexContent(ctx, p)
+	}
+	for _, id := range changes.Removed {
+		go h.Indexer.DeleteContent(ctx, id)
+	}
+
+	a, _ := h.Store.Attachment.GetAttachments(ctx, documentID)
+	go h.Indexer.IndexDocument(ctx, d, a)
+
+	response.WriteJSON(w, d)
+}
+
+// Delete removes a snapshot.
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
+	method := "snapshot.delete"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	snapshotID := request.Param(r, "snapshotID")
+	if len(snapshotID) == 0 {
+		response.WriteMissingDataError(w, method, "snapshotID")
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	if _, ok := h.get(w, ctx, method, documentID, snapshotID); !ok {
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Snapshot.Delete(ctx, snapshotID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeSnapshotDelete)
+
+	ctx.Transaction.Commit()
+
+	response.WriteEmpty(w)
+}
+
+// get loads a snapshot belonging to the document, writing the error response if it cannot.
+func (h *Handler) get(w http.ResponseWriter, ctx domain.RequestContext, method, documentID, snapshotID string) (sn snapshot.Snapshot, ok bool) {
+	sn, err := h.Store.Snapshot.Get(ctx, snapshotID)
+	if errors.Cause(err) == sql.ErrNoRows || (err == nil && sn.DocumentID != documentID) {
+		response.WriteNotFoundError(w, method, snapshotID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	return sn, true
+}
+
+// pages returns the sections of a snapshot, or of the live document for "current".
+func (h *Handler) pages(w http.ResponseWriter, ctx domain.RequestContext, method, documentID, snapshotID string) (p []page.Page, ok bool) {
+	if snapshotID == current {
+		var err error
+		p, err = h.Store.Page.GetPages(ctx, documentID)
+		if err != nil && errors.Cause(err) != sql.ErrNoRows {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+		return p, true
+	}
+
+	sn, ok := h.get(w, ctx, method, documentID, snapshotID)
+	if !ok {
+		return
+	}
+
+	c, err := content(sn)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	return sections(c), true
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/snapshot"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// Add saves document snapshot.
+func (s Scope) Add(ctx domain.RequestContext, sn snapshot.Snapshot) (err error) {
+	sn.OrgID = ctx.OrgID
+	sn.UserID = ctx.UserID
+	sn.Created = time.Now().UTC()
+	sn.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO snapshot (refid, orgid, documentid, userid, name, note, data, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert snapshot")
+		return
+	}
+
+	_, err = stmt.Exec(sn.RefID, sn.OrgID, sn.DocumentID, sn.UserID, sn.Name, sn.Note, sn.Data, sn.Created, sn.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert snapshot")
+		return
+	}
+
+	return
+}
+
+// Get returns requested snapshot including captured content.
+func (s Scope) Get(ctx domain.RequestContext, id string) (sn snapshot.Snapshot, err error) {
+	stmt, err := s.Runtime.Db.Preparex("SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.name, coalesce(a.note,'') as note, a.data, a.created, a.revised, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM snapshot a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare select snapshot")
+		return
+	}
+
+	err = stmt.Get(&sn, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, "execute select snapshot")
+		return
+	}
+
+	return
+}
+
+// GetByDocument returns snapshots of a document without their content, newest first.
+func (s Scope) GetByDocument(ctx domain.RequestContext, documentID string) (sn []snapshot.Snapshot, err error) {
+	err = s.Runtime.Db.Select(&sn, "SELECT a.id, a.refid, a.orgid, a.documentid, a.userid, a.name, coalesce(a.note,'') as note, a.created, a.revised, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM snapshot a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.documentid=? ORDER BY a.id DESC", ctx.OrgID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, "select snapshots")
+		return
+	}
+
+	return
+}
+
+// Delete removes document snapshot.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "snapshot", ctx.OrgID, id)
+}
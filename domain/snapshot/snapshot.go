@@ -0,0 +1,300 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package snapshot captures whole documents as named versions that can
+// be viewed, compared and restored.
+package snapshot
+
+import (
+	"encoding/json"
+
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/comment"
+	"github.com/documize/community/domain/trash"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/snapshot"
+	"github.com/pkg/errors"
+)
+
+// capture reads the current state of a document.
+func (h *Handler) capture(ctx domain.RequestContext, documentID string) (c snapshot.Content, err error) {
+	c.Document, err = h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	pages, err := h.Store.Page.GetPages(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	meta, err := h.Store.Page.GetDocumentPageMeta(ctx, documentID, false)
+	if err != nil {
+		return
+	}
+
+	c.Pages = []page.NewPage{}
+	for _, p := range pages {
+		np := page.NewPage{Page: p}
+		for _, m := range meta {
+			if m.PageID == p.RefID {
+				np.Meta = m
+				break
+			}
+		}
+		c.Pages = append(c.Pages, np)
+	}
+
+	files, err := h.Store.Attachment.GetAttachmentsWithData(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	c.Attachments = []snapshot.Attachment{}
+	for _, a := range files {
+		c.Attachments = append(c.Attachments, snapshot.Attachment{Attachment: a, Data: a.Data})
+	}
+
+	return
+}
+
+// save captures a document and stores it as a snapshot within ctx.Transaction.
+func (h *Handler) save(ctx domain.RequestContext, documentID, name, note string) (sn snapshot.Snapshot, err error) {
+	c, err := h.capture(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	sn.RefID = uniqueid.Generate()
+	sn.DocumentID = documentID
+	sn.Name = name
+	sn.Note = note
+	sn.Data, err = json.Marshal(c)
+	if err != nil {
+		err = errors.Wrap(err, "encode snapshot")
+		return
+	}
+
+	err = h.Store.Snapshot.Add(ctx, sn)
+
+	return
+}
+
+// content decodes what was captured by a snapshot.
+func content(sn snapshot.Snapshot) (c snapshot.Content, err error) {
+	err = json.Unmarshal(sn.Data, &c)
+	if err != nil {
+		err = errors.Wrap(err, "decode snapshot")
+	}
+
+	return
+}
+
+// sections returns the pages held by captured content.
+func sections(c snapshot.Content) (p []page.Page) {
+	p = []page.Page{}
+	for _, np := range c.Pages {
+		p = append(p, np.Page)
+	}
+
+	return
+}
+
+// restored lists what a restore changed so search can be brought up to date.
+type restored struct {
+	Indexed []page.Page
+	Removed []string
+}
+
+// restore makes the document match captured content within ctx.Transaction.
+// Sections keep their IDs so links and comments follow them; edited
+// sections get a revision entry like any other change.
+func (h *Handler) restore(ctx domain.RequestContext, documentID string, c snapshot.Content) (r restored, err error) {
+	current, err := h.Store.Page.GetPages(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	meta, err := h.Store.Page.GetDocumentPageMeta(ctx, documentID, false)
+	if err != nil {
+		return
+	}
+
+	live := make(map[string]page.Page)
+	for _, p := range current {
+		live[p.RefID] = p
+	}
+
+	liveMeta := make(map[string]page.Meta)
+	for _, m := range meta {
+		liveMeta[m.PageID] = m
+	}
+
+	kept := make(map[string]bool)
+
+	for _, np := range c.Pages {
+		p := np.Page
+		m := np.Meta
+		p.OrgID = ctx.OrgID
+		p.DocumentID = documentID
+		m.OrgID = ctx.OrgID
+		m.DocumentID = documentID
+		m.PageID = p.RefID
+		kept[p.RefID] = true
+
+		existing, ok := live[p.RefID]
+		if !ok {
+			p.Revisions = 0
+			p.Version = 0
+
+			err = h.Store.Page.Add(ctx, page.NewPage{Page: p, Meta: m})
+			if err != nil {
+				return
+			}
+
+			if len(p.BlockID) > 0 {
+				h.Store.Block.IncrementUsage(ctx, p.BlockID)
+			}
+
+			r.Indexed = append(r.Indexed, p)
+			continue
+		}
+
+		edited := existing.Title != p.Title || existing.Body != p.Body
+		if edited || existing.Level != p.Level || existing.Sequence != p.Sequence {
+			p.Version = existing.Version
+			p.Revisions = existing.Revisions
+
+			err = h.Store.Page.Update(ctx, p, uniqueid.Generate(), ctx.UserID, !edited)
+			if err != nil {
+				return
+			}
+
+			if edited {
+				err = comment.Reanchor(ctx, *h.Store, p)
+				if err != nil {
+					return
+				}
+			}
+
+			r.Indexed = append(r.Indexed, p)
+		}
+
+		old := liveMeta[p.RefID]
+		if old.RawBody != m.RawBody || old.Config != m.Config || old.ExternalSource != m.ExternalSource {
+			err = h.Store.Page.UpdateMeta(ctx, m, true)
+			if err != nil {
+				return
+			}
+		}
+	}
+
+	// sections added since the snapshot go to trash so they can be recovered
+	removed := []string{}
+	for _, p := range current {
+		if !kept[p.RefID] {
+			removed = append(removed, p.RefID)
+		}
+	}
+
+	if len(removed) > 0 {
+		var d doc.Document
+		d, err = h.Store.Document.Get(ctx, documentID)
+		if err != nil {
+			return
+		}
+
+		err = trash.Pages(ctx, *h.Store, d, removed)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, p := range current {
+		if kept[p.RefID] {
+			continue
+		}
+
+		if len(p.BlockID) > 0 {
+			h.Store.Block.DecrementUsage(ctx, p.BlockID)
+		}
+
+		_, err = h.Store.Page.Delete(ctx, documentID, p.RefID)
+		if err != nil {
+			return
+		}
+
+		h.Store.Link.DeleteSourcePageLinks(ctx, p.RefID)
+		h.Store.Link.MarkOrphanPageLink(ctx, p.RefID)
+		h.Store.Page.DeletePageRevisions(ctx, p.RefID)
+		h.Store.Page.DeleteLock(ctx, p.RefID)
+		h.Store.Comment.MarkPageOutdated(ctx, p.RefID)
+
+		r.Removed = append(r.Removed, p.RefID)
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	d.Title = c.Document.Title
+	d.Excerpt = c.Document.Excerpt
+
+	err = h.Store.Document.Update(ctx, d)
+	if err != nil {
+		return
+	}
+
+	files, err := h.Store.Attachment.GetAttachments(ctx, documentID)
+	if err != nil {
+		return
+	}
+
+	present := make(map[string]bool)
+	wanted := make(map[string]bool)
+	for _, a := range c.Attachments {
+		wanted[a.RefID] = true
+	}
+
+	for _, a := range files {
+		present[a.RefID] = true
+		if wanted[a.RefID] {
+			continue
+		}
+
+		_, err = h.Store.Attachment.Delete(ctx, a.RefID)
+		if err != nil {
+			return
+		}
+
+		h.Store.Link.MarkOrphanAttachmentLink(ctx, a.RefID)
+	}
+
+	for _, a := range c.Attachments {
+		if present[a.RefID] {
+			continue
+		}
+
+		file := a.Attachment
+		file.DocumentID = documentID
+		file.Data = a.Data
+
+		err = h.Store.Attachment.Add(ctx, file)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
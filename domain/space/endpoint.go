@@ -297,6 +297,24 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Failing to record the visit must not stop the space being shown.
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		h.Runtime.Log.Error(method, err)
+		response.WriteJSON(w, sp)
+		return
+	}
+
+	err = h.Store.Watch.UpdateLastViewed(ctx, sp.RefID, "")
+	if err != nil {
+		ctx.Transaction.Rollback()
+		h.Runtime.Log.Error(method, err)
+		response.WriteJSON(w, sp)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
 	response.WriteJSON(w, sp)
 }
 
@@ -57,3 +57,37 @@ func CanViewSpaceDocuments(ctx domain.RequestContext, s domain.Store, spaceID st
 
 	return false
 }
+
+// CanChangeSpaceDocuments returns if the user has permission to change documents within the specified space.
+func CanChangeSpaceDocuments(ctx domain.RequestContext, s domain.Store, spaceID string) (hasPermission bool) {
+	roles, err := s.Space.GetUserRoles(ctx)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		return false
+	}
+
+	for _, role := range roles {
+		if role.LabelID == spaceID && role.CanEdit {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanManageSpace returns if the user can administer the space,
+// being either its owner or an organization administrator.
+func CanManageSpace(ctx domain.RequestContext, s domain.Store, spaceID string) (hasPermission bool) {
+	if ctx.Administrator {
+		return true
+	}
+
+	sp, err := s.Space.Get(ctx, spaceID)
+	if err != nil {
+		return false
+	}
+
+	return sp.UserID == ctx.UserID
+}
@@ -13,37 +13,62 @@
 package domain
 
 import (
+	"errors"
+	"time"
+
 	"github.com/documize/community/model/account"
+	"github.com/documize/community/model/action"
 	"github.com/documize/community/model/activity"
 	"github.com/documize/community/model/attachment"
 	"github.com/documize/community/model/audit"
 	"github.com/documize/community/model/block"
+	"github.com/documize/community/model/comment"
+	"github.com/documize/community/model/datasource"
 	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/feedback"
 	"github.com/documize/community/model/link"
+	"github.com/documize/community/model/metadata"
 	"github.com/documize/community/model/org"
 	"github.com/documize/community/model/page"
 	"github.com/documize/community/model/pin"
 	"github.com/documize/community/model/search"
+	"github.com/documize/community/model/share"
+	"github.com/documize/community/model/snapshot"
 	"github.com/documize/community/model/space"
+	"github.com/documize/community/model/tag"
+	"github.com/documize/community/model/template"
+	"github.com/documize/community/model/trash"
 	"github.com/documize/community/model/user"
+	"github.com/documize/community/model/watch"
 )
 
 // Store provides access to data store (database)
 type Store struct {
 	Account      AccountStorer
+	Action       ActionStorer
 	Activity     ActivityStorer
 	Attachment   AttachmentStorer
 	Audit        AuditStorer
 	Block        BlockStorer
+	Comment      CommentStorer
+	DataSource   DataSourceStorer
 	Document     DocumentStorer
+	Feedback     FeedbackStorer
 	Link         LinkStorer
+	Metadata     MetadataStorer
 	Organization OrganizationStorer
 	Page         PageStorer
 	Pin          PinStorer
 	Search       SearchStorer
 	Setting      SettingStorer
+	Share        ShareStorer
+	Snapshot     SnapshotStorer
 	Space        SpaceStorer
+	Tag          TagStorer
+	Template     TemplateStorer
+	Trash        TrashStorer
 	User         UserStorer
+	Watch        WatchStorer
 }
 
 // SpaceStorer defines required methods for space management
@@ -118,6 +143,7 @@ type PinStorer interface {
 	DeletePin(ctx RequestContext, id string) (rows int64, err error)
 	DeletePinnedSpace(ctx RequestContext, spaceID string) (rows int64, err error)
 	DeletePinnedDocument(ctx RequestContext, documentID string) (rows int64, err error)
+	GetDocumentPins(ctx RequestContext, documentID string) (pins []pin.Pin, err error)
 }
 
 // AuditStorer defines required methods for audit trails
@@ -139,6 +165,8 @@ type DocumentStorer interface {
 	PublicDocuments(ctx RequestContext, orgID string) (documents []doc.SitemapDocument, err error)
 	Update(ctx RequestContext, document doc.Document) (err error)
 	ChangeDocumentSpace(ctx RequestContext, document, space string) (err error)
+	UpdateLifecycle(ctx RequestContext, documentID string, lifecycle doc.Lifecycle) (err error)
+	UpdateApprovals(ctx RequestContext, documentID string, approvals int) (err error)
 	MoveDocumentSpace(ctx RequestContext, id, move string) (err error)
 	Delete(ctx RequestContext, documentID string) (rows int64, err error)
 }
@@ -172,12 +200,30 @@ type LinkStorer interface {
 	DeleteSourcePageLinks(ctx RequestContext, pageID string) (rows int64, err error)
 	DeleteSourceDocumentLinks(ctx RequestContext, documentID string) (rows int64, err error)
 	DeleteLink(ctx RequestContext, id string) (rows int64, err error)
+	GetInboundLinks(ctx RequestContext, linkType, targetID string) (links []link.Link, err error)
+	MarkLinked(ctx RequestContext, id string) (err error)
+	GetDocumentInboundLinks(ctx RequestContext, documentID string) (links []link.Link, err error)
+	UpdateTargetSpace(ctx RequestContext, documentID, spaceID string) (err error)
+}
+
+// MetadataStorer defines required methods for persisting space metadata fields and document values
+type MetadataStorer interface {
+	AddField(ctx RequestContext, f metadata.Field) (err error)
+	GetField(ctx RequestContext, id string) (f metadata.Field, err error)
+	GetFields(ctx RequestContext, spaceID string) (f []metadata.Field, err error)
+	UpdateField(ctx RequestContext, f metadata.Field) (err error)
+	DeleteField(ctx RequestContext, id string) (err error)
+	GetValues(ctx RequestContext, documentID string) (v []metadata.Value, err error)
+	GetSpaceValues(ctx RequestContext, spaceID string) (v []metadata.Value, err error)
+	GetDocumentsValues(ctx RequestContext, documentIDs []string) (v []metadata.Value, err error)
+	SetValues(ctx RequestContext, documentID string, v []metadata.Value) (err error)
 }
 
 // ActivityStorer defines required methods for persisting document activity
 type ActivityStorer interface {
 	RecordUserActivity(ctx RequestContext, activity activity.UserActivity) (err error)
 	GetDocumentActivity(ctx RequestContext, id string) (a []activity.DocumentActivity, err error)
+	GetLastRead(ctx RequestContext, documentID string, before time.Time) (read time.Time, err error)
 }
 
 // SearchStorer defines required methods for persisting search queries
@@ -185,6 +231,7 @@ type SearchStorer interface {
 	IndexDocument(ctx RequestContext, doc doc.Document, a []attachment.Attachment) (err error)
 	DeleteDocument(ctx RequestContext, ID string) (err error)
 	IndexContent(ctx RequestContext, p page.Page) (err error)
+	IndexItems(ctx RequestContext, p page.Page, items []string) (err error)
 	DeleteContent(ctx RequestContext, pageID string) (err error)
 	Documents(ctx RequestContext, q search.QueryOptions) (results []search.QueryResult, err error)
 }
@@ -205,10 +252,130 @@ type BlockStorer interface {
 	IncrementUsage(ctx RequestContext, id string) (err error)
 	DecrementUsage(ctx RequestContext, id string) (err error)
 	RemoveReference(ctx RequestContext, id string) (err error)
+	GetLinkedPages(ctx RequestContext, id string) (p []page.Page, err error)
+	Unlink(ctx RequestContext, pageID string) (err error)
+	SetStale(ctx RequestContext, pageID string, stale bool) (err error)
+	GetStalePages(ctx RequestContext, documentID string) (p []page.Page, err error)
 	Update(ctx RequestContext, b block.Block) (err error)
 	Delete(ctx RequestContext, id string) (rows int64, err error)
 }
 
+// DataSourceStorer defines required methods for persisting query data sources
+type DataSourceStorer interface {
+	Add(ctx RequestContext, d datasource.DataSource) (err error)
+	Get(ctx RequestContext, id string) (d datasource.DataSource, err error)
+	GetAll(ctx RequestContext) (d []datasource.DataSource, err error)
+	Update(ctx RequestContext, d datasource.DataSource) (err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+}
+
+// ActionStorer defines required methods for persisting actions users are asked to take
+type ActionStorer interface {
+	Add(ctx RequestContext, a action.UserAction) (err error)
+	Get(ctx RequestContext, id string) (a action.UserAction, err error)
+	GetByDocument(ctx RequestContext, documentID string) (a []action.UserAction, err error)
+	GetPending(ctx RequestContext, userID string) (a []action.UserAction, err error)
+	Complete(ctx RequestContext, id string, outcome action.Outcome, reply string) (err error)
+	CountApprovals(ctx RequestContext, documentID string) (count int, err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+}
+
+// CommentStorer defines required methods for persisting section comments
+type CommentStorer interface {
+	Add(ctx RequestContext, c comment.Comment) (err error)
+	Get(ctx RequestContext, id string) (c comment.Comment, err error)
+	GetByDocument(ctx RequestContext, documentID string) (c []comment.Comment, err error)
+	GetAnchored(ctx RequestContext, pageID string) (c []comment.Comment, err error)
+	Update(ctx RequestContext, c comment.Comment) (err error)
+	UpdateAnchor(ctx RequestContext, id string, start int, outdated bool) (err error)
+	MarkPageOutdated(ctx RequestContext, pageID string) (err error)
+	Resolve(ctx RequestContext, id string, resolved bool) (err error)
+	AddRevision(ctx RequestContext, r comment.Revision) (err error)
+	GetRevisions(ctx RequestContext, commentID string) (r []comment.Revision, err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+	Restore(ctx RequestContext, c comment.Comment) (err error)
+	RestoreRevision(ctx RequestContext, r comment.Revision) (err error)
+}
+
+// FeedbackStorer defines required methods for persisting reader feedback
+type FeedbackStorer interface {
+	Add(ctx RequestContext, f feedback.Feedback) (err error)
+	GetByDocument(ctx RequestContext, documentID string) (f []feedback.Feedback, err error)
+	GetSpaceSummary(ctx RequestContext, spaceID string) (f []feedback.Summary, err error)
+}
+
+// SnapshotStorer defines required methods for persisting named document versions
+type SnapshotStorer interface {
+	Add(ctx RequestContext, s snapshot.Snapshot) (err error)
+	Get(ctx RequestContext, id string) (s snapshot.Snapshot, err error)
+	GetByDocument(ctx RequestContext, documentID string) (s []snapshot.Snapshot, err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+}
+
+// TrashStorer defines required methods for keeping deleted content until purged
+type TrashStorer interface {
+	Add(ctx RequestContext, t trash.Item) (err error)
+	Get(ctx RequestContext, id string) (t trash.Item, err error)
+	GetBySpace(ctx RequestContext, spaceID string) (t []trash.Item, err error)
+	GetDeletedSections(ctx RequestContext, documentID string, since time.Time) (t []trash.Item, err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+	PurgeOrphans(ctx RequestContext) (err error)
+	Purge(ctx RequestContext, before time.Time) (rows int64, err error)
+}
+
+// WatchStorer defines required methods for persisting document and space subscriptions
+type WatchStorer interface {
+	Add(ctx RequestContext, w watch.Watcher) (err error)
+	Get(ctx RequestContext, spaceID, documentID string) (w watch.Watcher, err error)
+	GetByUser(ctx RequestContext) (w []watch.Watcher, err error)
+	GetAll(ctx RequestContext) (w []watch.Watcher, err error)
+	UpdateLastViewed(ctx RequestContext, spaceID, documentID string) (err error)
+	UpdateLastNotified(ctx RequestContext, id string, notified time.Time) (err error)
+	GetChanges(ctx RequestContext, spaceID, documentID string, since time.Time) (c []watch.Change, err error)
+	Delete(ctx RequestContext, id string) (rows int64, err error)
+}
+
+// ShareStorer defines required methods for persisting document share links
+type ShareStorer interface {
+	Add(ctx RequestContext, sh share.Share) (err error)
+	Get(ctx RequestContext, id string) (sh share.Share, err error)
+	GetBySecret(ctx RequestContext, secret string) (sh share.Share, err error)
+	GetByDocument(ctx RequestContext, documentID string) (sh []share.Share, err error)
+	RecordView(ctx RequestContext, id string) (err error)
+	Revoke(ctx RequestContext, id string) (err error)
+}
+
+// TagStorer defines required methods for persisting tag vocabularies and retagging documents
+type TagStorer interface {
+	Add(ctx RequestContext, t tag.Tag) (err error)
+	Get(ctx RequestContext, id string) (t tag.Tag, err error)
+	GetBySpace(ctx RequestContext, spaceID string) (t []tag.Tag, err error)
+	Delete(ctx RequestContext, id string) (err error)
+	Replace(ctx RequestContext, spaceID string, from []string, to string) (err error)
+	GetTagged(ctx RequestContext, spaceID string, tags []string) (d []doc.Document, err error)
+	UpdateDocumentTags(ctx RequestContext, documentID, tags string) (err error)
+}
+
+// TemplateStorer defines required methods for persisting template variables, versions and origins
+type TemplateStorer interface {
+	GetVariables(ctx RequestContext, templateID string) (v []template.Variable, err error)
+	SetVariables(ctx RequestContext, templateID string, v []template.Variable) (err error)
+	AddOrigin(ctx RequestContext, o template.Origin) (err error)
+	GetOrigin(ctx RequestContext, documentID string) (o template.Origin, err error)
+	UpdateOrigin(ctx RequestContext, documentID string, version int) (err error)
+	GetVersion(ctx RequestContext, templateID string) (version int, err error)
+	AddVersion(ctx RequestContext, v template.Version) (err error)
+	GetVersions(ctx RequestContext, templateID string) (v []template.Version, err error)
+	GetVersionData(ctx RequestContext, templateID string, version int) (data []byte, err error)
+	GetDerived(ctx RequestContext, templateID string, version int) (d []template.Derived, err error)
+	AddSection(ctx RequestContext, documentID string, sec template.Section) (err error)
+	GetSections(ctx RequestContext, documentID string) (sec []template.Section, err error)
+}
+
+// ErrVersionConflict is returned when a versioned record was changed
+// since the caller read it.
+var ErrVersionConflict = errors.New("record changed since it was read")
+
 // PageStorer defines required methods for persisting document pages
 type PageStorer interface {
 	Add(ctx RequestContext, model page.NewPage) (err error)
@@ -218,14 +385,20 @@ type PageStorer interface {
 	GetPagesWithoutContent(ctx RequestContext, documentID string) (pages []page.Page, err error)
 	Update(ctx RequestContext, page page.Page, refID, userID string, skipRevision bool) (err error)
 	UpdateMeta(ctx RequestContext, meta page.Meta, updateUserID bool) (err error)
-	UpdateSequence(ctx RequestContext, documentID, pageID string, sequence float64) (err error)
-	UpdateLevel(ctx RequestContext, documentID, pageID string, level int) (err error)
+	UpdateSequence(ctx RequestContext, documentID, pageID string, sequence float64, version uint64) (err error)
+	UpdateLevel(ctx RequestContext, documentID, pageID string, level int, version uint64) (err error)
+	AddLock(ctx RequestContext, l page.Lock) (err error)
+	GetLock(ctx RequestContext, pageID string) (l page.Lock, err error)
+	GetDocumentLocks(ctx RequestContext, documentID string) (l []page.Lock, err error)
+	DeleteLock(ctx RequestContext, pageID string) (rows int64, err error)
 	Delete(ctx RequestContext, documentID, pageID string) (rows int64, err error)
 	GetPageMeta(ctx RequestContext, pageID string) (meta page.Meta, err error)
 	GetPageRevision(ctx RequestContext, revisionID string) (revision page.Revision, err error)
 	GetPageRevisions(ctx RequestContext, pageID string) (revisions []page.Revision, err error)
 	GetDocumentRevisions(ctx RequestContext, documentID string) (revisions []page.Revision, err error)
+	GetDocumentRevisionsSince(ctx RequestContext, documentID string, since time.Time) (revisions []page.Revision, err error)
 	GetDocumentPageMeta(ctx RequestContext, documentID string, externalSourceOnly bool) (meta []page.Meta, err error)
 	DeletePageRevisions(ctx RequestContext, pageID string) (rows int64, err error)
+	AddRevision(ctx RequestContext, r page.Revision) (err error)
 	GetNextPageSequence(ctx RequestContext, documentID string) (maxSeq float64, err error)
 }
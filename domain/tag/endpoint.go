@@ -0,0 +1,425 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package tag
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/tag"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// GetUsage returns tags on documents the user can see, with how many
+// documents carry each, optionally limited to a space. Archived documents
+// are not counted. Tags in the space's vocabulary are included even when unused.
+func (h *Handler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	method := "tag.getUsage"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Query(r, "folder")
+	if len(folderID) > 0 && !space.CanViewSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	documents, err := h.Store.Document.DocumentList(ctx)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	usage := make(map[string]*tag.Usage)
+	for _, d := range documents {
+		if d.Lifecycle == doc.LifecycleArchived || (len(folderID) > 0 && d.LabelID != folderID) {
+			continue
+		}
+		for _, t := range Parse(d.Tags) {
+			if _, ok := usage[t]; !ok {
+				usage[t] = &tag.Usage{Name: t}
+			}
+			usage[t].Count++
+		}
+	}
+
+	if len(folderID) > 0 {
+		vocabulary, err := h.Store.Tag.GetBySpace(ctx, folderID)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		for _, v := range vocabulary {
+			if _, ok := usage[v.Name]; !ok {
+				usage[v.Name] = &tag.Usage{Name: v.Name}
+			}
+			usage[v.Name].Curated = true
+		}
+	}
+
+	u := []tag.Usage{}
+	for _, t := range usage {
+		u = append(u, *t)
+	}
+
+	sort.Slice(u, func(i, j int) bool {
+		if u[i].Count != u[j].Count {
+			return u[i].Count > u[j].Count
+		}
+		return u[i].Name < u[j].Name
+	})
+
+	response.WriteJSON(w, u)
+}
+
+// Rename changes a tag on every document carrying it, or only those in a space.
+func (h *Handler) Rename(w http.ResponseWriter, r *http.Request) {
+	method := "tag.rename"
+	ctx := domain.GetRequestContext(r)
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	rq := tag.RenameRequest{}
+	err = json.Unmarshal(body, &rq)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	if !h.canRetag(ctx, rq.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	from, err := Normalize(rq.From)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	to, err := Normalize(rq.To)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	if from == to {
+		response.WriteBadRequestError(w, method, "tag names must differ")
+		return
+	}
+
+	c, err := h.retag(ctx, rq.LabelID, []string{from}, to)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTagRename)
+
+	response.WriteJSON(w, c)
+}
+
+// Merge replaces several tags with one on every document carrying them,
+// or only those in a space.
+func (h *Handler) Merge(w http.ResponseWriter, r *http.Request) {
+	method := "tag.merge"
+	ctx := domain.GetRequestContext(r)
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	rq := tag.MergeRequest{}
+	err = json.Unmarshal(body, &rq)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	if !h.canRetag(ctx, rq.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	into, err := Normalize(rq.Into)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	from := []string{}
+	for _, t := range rq.Tags {
+		t, err = Normalize(t)
+		if err != nil {
+			response.WriteBadRequestError(w, method, err.Error())
+			return
+		}
+		if t != into {
+			from = append(from, t)
+		}
+	}
+
+	if len(from) == 0 {
+		response.WriteMissingDataError(w, method, "tags")
+		return
+	}
+
+	c, err := h.retag(ctx, rq.LabelID, from, into)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTagMerge)
+
+	response.WriteJSON(w, c)
+}
+
+// GetVocabulary returns the tags documents in a space can be given.
+// An empty vocabulary means any tag is allowed.
+func (h *Handler) GetVocabulary(w http.ResponseWriter, r *http.Request) {
+	method := "tag.getVocabulary"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanViewSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	t, err := h.Store.Tag.GetBySpace(ctx, folderID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, t)
+}
+
+// AddVocabulary adds a tag to a space's vocabulary.
+func (h *Handler) AddVocabulary(w http.ResponseWriter, r *http.Request) {
+	method := "tag.addVocabulary"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanManageSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	t := tag.Tag{}
+	err = json.Unmarshal(body, &t)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	t.Name, err = Normalize(t.Name)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	existing, err := h.Store.Tag.GetBySpace(ctx, folderID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	for _, e := range existing {
+		if e.Name == t.Name {
+			response.WriteBadRequestError(w, method, "tag "+t.Name+" already exists")
+			return
+		}
+	}
+
+	t.RefID = uniqueid.Generate()
+	t.LabelID = folderID
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Tag.Add(ctx, t)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTagAdd)
+
+	t, err = h.Store.Tag.Get(ctx, t.RefID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, t)
+}
+
+// DeleteVocabulary removes a tag from a space's vocabulary.
+// Documents already carrying the tag keep it.
+func (h *Handler) DeleteVocabulary(w http.ResponseWriter, r *http.Request) {
+	method := "tag.deleteVocabulary"
+	ctx := domain.GetRequestContext(r)
+
+	folderID := request.Param(r, "folderID")
+	if len(folderID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	tagID := request.Param(r, "tagID")
+	if len(tagID) == 0 {
+		response.WriteMissingDataError(w, method, "tagID")
+		return
+	}
+
+	if !space.CanManageSpace(ctx, *h.Store, folderID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	t, err := h.Store.Tag.Get(ctx, tagID)
+	if err != nil || t.LabelID != folderID {
+		response.WriteNotFoundError(w, method, tagID)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Tag.Delete(ctx, tagID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTagDelete)
+
+	response.WriteEmpty(w)
+}
+
+// canRetag reports whether the user can rename or merge tags across the
+// organization, or within a space when spaceID is given.
+func (h *Handler) canRetag(ctx domain.RequestContext, spaceID string) bool {
+	if len(spaceID) == 0 {
+		return ctx.Administrator
+	}
+
+	return space.CanManageSpace(ctx, *h.Store, spaceID)
+}
+
+// retag replaces the from tags with to on matching documents, their
+// search index entries and space vocabularies, in a single transaction.
+func (h *Handler) retag(ctx domain.RequestContext, spaceID string, from []string, to string) (c tag.Change, err error) {
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		return
+	}
+
+	documents, err := h.Store.Tag.GetTagged(ctx, spaceID, from)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		return
+	}
+
+	for _, d := range documents {
+		tags, changed := Replace(d.Tags, from, to)
+		if !changed {
+			continue
+		}
+
+		err = h.Store.Tag.UpdateDocumentTags(ctx, d.RefID, tags)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			return
+		}
+
+		c.Documents++
+	}
+
+	err = h.Store.Tag.Replace(ctx, spaceID, from, to)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	return
+}
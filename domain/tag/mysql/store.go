@@ -0,0 +1,194 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/tag"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// Add adds a tag to a space's vocabulary.
+func (s Scope) Add(ctx domain.RequestContext, t tag.Tag) (err error) {
+	t.OrgID = ctx.OrgID
+	t.Created = time.Now().UTC()
+	t.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO tag (refid, orgid, labelid, name, created, revised) VALUES (?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert tag")
+		return
+	}
+
+	_, err = stmt.Exec(t.RefID, t.OrgID, t.LabelID, t.Name, t.Created, t.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert tag")
+		return
+	}
+
+	return
+}
+
+// Get returns a vocabulary tag.
+func (s Scope) Get(ctx domain.RequestContext, id string) (t tag.Tag, err error) {
+	err = s.Runtime.Db.Get(&t, "SELECT id, refid, orgid, labelid, name, created, revised FROM tag WHERE orgid=? AND refid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select tag %s", id))
+		return
+	}
+
+	return
+}
+
+// GetBySpace returns a space's tag vocabulary in name order.
+func (s Scope) GetBySpace(ctx domain.RequestContext, spaceID string) (t []tag.Tag, err error) {
+	err = s.Runtime.Db.Select(&t, "SELECT id, refid, orgid, labelid, name, created, revised FROM tag WHERE orgid=? AND labelid=? ORDER BY name", ctx.OrgID, spaceID)
+	if err == sql.ErrNoRows || len(t) == 0 {
+		err = nil
+		t = []tag.Tag{}
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select tags for space %s", spaceID))
+		return
+	}
+
+	return
+}
+
+// Delete removes a tag from a space's vocabulary.
+// Documents keep the tag.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (err error) {
+	_, err = ctx.Transaction.Exec("DELETE FROM tag WHERE orgid=? AND refid=?", ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete tag %s", id))
+		return
+	}
+
+	return
+}
+
+// Replace renames vocabulary entries for the given tags, in one space or
+// all spaces when spaceID is empty. Entries that would duplicate one
+// already in the vocabulary are removed.
+func (s Scope) Replace(ctx domain.RequestContext, spaceID string, from []string, to string) (err error) {
+	where := "orgid=? AND name IN (?)"
+	args := []interface{}{ctx.OrgID, from}
+	if len(spaceID) > 0 {
+		where += " AND labelid=?"
+		args = append(args, spaceID)
+	}
+
+	query, qargs, err := sqlx.In("UPDATE IGNORE tag SET name=?, revised=? WHERE "+where, append([]interface{}{to, time.Now().UTC()}, args...)...)
+	if err != nil {
+		err = errors.Wrap(err, "expand rename tag query")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(ctx.Transaction.Rebind(query), qargs...)
+	if err != nil {
+		err = errors.Wrap(err, "rename vocabulary tags")
+		return
+	}
+
+	query, qargs, err = sqlx.In("DELETE FROM tag WHERE "+where, args...)
+	if err != nil {
+		err = errors.Wrap(err, "expand remove duplicate tag query")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(ctx.Transaction.Rebind(query), qargs...)
+	if err != nil {
+		err = errors.Wrap(err, "remove duplicate vocabulary tags")
+		return
+	}
+
+	return
+}
+
+// GetTagged returns documents, including templates, carrying any of the
+// given tags, in one space or all spaces when spaceID is empty.
+// Only the document ID, space and tags are returned. Rows are locked
+// until the transaction ends so they can be retagged safely.
+func (s Scope) GetTagged(ctx domain.RequestContext, spaceID string, tags []string) (d []doc.Document, err error) {
+	if len(tags) == 0 {
+		return
+	}
+
+	match := []string{}
+	args := []interface{}{ctx.OrgID}
+	for _, t := range tags {
+		match = append(match, "tags LIKE ?")
+		args = append(args, "%#"+t+"#%")
+	}
+
+	query := "SELECT refid, labelid, tags FROM document WHERE orgid=? AND (" + strings.Join(match, " OR ") + ")"
+	if len(spaceID) > 0 {
+		query += " AND labelid=?"
+		args = append(args, spaceID)
+	}
+
+	err = ctx.Transaction.Select(&d, query+" FOR UPDATE", args...)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, "select tagged documents")
+		return
+	}
+
+	return
+}
+
+// UpdateDocumentTags replaces a document's tags and their search index entries.
+func (s Scope) UpdateDocumentTags(ctx domain.RequestContext, documentID, tags string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE document SET tags=? WHERE orgid=? AND refid=?", tags, ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update tags for document %s", documentID))
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("DELETE FROM search WHERE orgid=? AND documentid=? AND itemtype='tag'", ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("delete tag index entries for document %s", documentID))
+		return
+	}
+
+	for _, t := range strings.Split(tags, "#") {
+		if len(t) == 0 {
+			continue
+		}
+
+		_, err = ctx.Transaction.Exec("INSERT INTO search (orgid, documentid, itemid, itemtype, content) VALUES (?, ?, ?, ?, ?)", ctx.OrgID, documentID, "", "tag", t)
+		if err != nil {
+			err = errors.Wrap(err, fmt.Sprintf("insert tag index entry for document %s", documentID))
+			return
+		}
+	}
+
+	return
+}
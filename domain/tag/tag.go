@@ -0,0 +1,142 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package tag handles document tags: usage counts, renaming and merging
+// across documents, and the curated vocabularies spaces can define.
+package tag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/tag"
+)
+
+// maxName is the longest tag accepted.
+const maxName = 50
+
+// tagName is what tags can contain, matching the rules the editor applies.
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Normalize returns a tag in the form it is stored.
+func Normalize(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !tagName.MatchString(name) || len(name) > maxName {
+		return "", fmt.Errorf("tag %q must start with a letter or digit and contain only letters, digits and hyphens", name)
+	}
+
+	return name, nil
+}
+
+// Parse splits a document's #tag1#tag2# string into tags.
+func Parse(tags string) (t []string) {
+	for _, s := range strings.Split(tags, "#") {
+		if len(s) > 0 {
+			t = append(t, s)
+		}
+	}
+
+	return
+}
+
+// Format joins tags into the #tag1#tag2# form documents hold.
+func Format(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+
+	return "#" + strings.Join(tags, "#") + "#"
+}
+
+// Replace swaps any of the from tags for to, keeping the order of the
+// remaining tags and dropping duplicates. It reports whether anything changed.
+func Replace(tags string, from []string, to string) (string, bool) {
+	replace := make(map[string]bool)
+	for _, f := range from {
+		replace[f] = true
+	}
+
+	changed := false
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for _, t := range Parse(tags) {
+		if replace[t] {
+			t = to
+			changed = true
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+
+	if !changed {
+		return tags, false
+	}
+
+	return Format(result), true
+}
+
+// Disallowed returns the tags a space's vocabulary does not allow.
+// Spaces without a vocabulary allow any tag.
+func Disallowed(ctx domain.RequestContext, s domain.Store, spaceID, tags string) (t []string, err error) {
+	vocabulary, err := s.Tag.GetBySpace(ctx, spaceID)
+	if err != nil {
+		return
+	}
+
+	_, t = filter(tags, vocabulary)
+
+	return
+}
+
+// Fit drops tags a space's vocabulary does not allow, for documents
+// arriving in the space.
+func Fit(ctx domain.RequestContext, s domain.Store, spaceID, tags string) (string, error) {
+	vocabulary, err := s.Tag.GetBySpace(ctx, spaceID)
+	if err != nil {
+		return tags, err
+	}
+
+	allowed, disallowed := filter(tags, vocabulary)
+	if len(disallowed) == 0 {
+		return tags, nil
+	}
+
+	return Format(allowed), nil
+}
+
+// filter splits tags into those a vocabulary allows and those it does not.
+// An empty vocabulary allows every tag.
+func filter(tags string, vocabulary []tag.Tag) (allowed, disallowed []string) {
+	if len(vocabulary) == 0 {
+		return Parse(tags), nil
+	}
+
+	known := make(map[string]bool)
+	for _, v := range vocabulary {
+		known[v.Name] = true
+	}
+
+	for _, t := range Parse(tags) {
+		if known[t] {
+			allowed = append(allowed, t)
+		} else {
+			disallowed = append(disallowed, t)
+		}
+	}
+
+	return
+}
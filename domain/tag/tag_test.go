@@ -0,0 +1,85 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package tag
+
+import (
+	"testing"
+
+	"github.com/documize/community/model/tag"
+)
+
+func TestNormalize(t *testing.T) {
+	n, err := Normalize("  Release-Notes ")
+	if err != nil || n != "release-notes" {
+		t.Errorf("expected release-notes got %q %v", n, err)
+	}
+
+	for _, bad := range []string{"", "-draft", "two words", "a#b", "x_y"} {
+		if _, err := Normalize(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tags := Parse("#api#draft#")
+	if len(tags) != 2 || tags[0] != "api" || tags[1] != "draft" {
+		t.Errorf("unexpected tags %v", tags)
+	}
+	if f := Format(tags); f != "#api#draft#" {
+		t.Errorf("unexpected format %s", f)
+	}
+	if len(Parse("")) != 0 || Format(nil) != "" {
+		t.Error("expected empty tags")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	cases := []struct {
+		tags    string
+		from    []string
+		to      string
+		want    string
+		changed bool
+	}{
+		{"#api#draft#", []string{"api"}, "rest", "#rest#draft#", true},
+		{"#api#rest#draft#", []string{"api"}, "rest", "#rest#draft#", true},
+		{"#v1#draft#v2#", []string{"v1", "v2"}, "legacy", "#legacy#draft#", true},
+		{"#draft#", []string{"api"}, "rest", "#draft#", false},
+		{"", []string{"api"}, "rest", "", false},
+	}
+
+	for _, c := range cases {
+		got, changed := Replace(c.tags, c.from, c.to)
+		if got != c.want || changed != c.changed {
+			t.Errorf("Replace(%q, %v, %q) = %q %v, want %q %v", c.tags, c.from, c.to, got, changed, c.want, c.changed)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	allowed, disallowed := filter("#api#draft#", nil)
+	if len(allowed) != 2 || len(disallowed) != 0 {
+		t.Errorf("expected every tag allowed without a vocabulary, got %v %v", allowed, disallowed)
+	}
+
+	vocabulary := []tag.Tag{{Name: "api"}, {Name: "runbook"}}
+	allowed, disallowed = filter("#api#draft#", vocabulary)
+	if Format(allowed) != "#api#" || len(disallowed) != 1 || disallowed[0] != "draft" {
+		t.Errorf("unexpected split %v %v", allowed, disallowed)
+	}
+
+	allowed, _ = filter("#draft#", vocabulary)
+	if Format(allowed) != "" {
+		t.Errorf("expected no tags left, got %v", allowed)
+	}
+}
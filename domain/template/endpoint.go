@@ -16,6 +16,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 
 	"github.com/documize/community/core/env"
@@ -29,12 +30,15 @@ import (
 	"github.com/documize/community/domain"
 	"github.com/documize/community/domain/document"
 	indexer "github.com/documize/community/domain/search"
+	"github.com/documize/community/domain/tag"
+	"github.com/documize/community/domain/watch"
 	"github.com/documize/community/model/attachment"
 	"github.com/documize/community/model/audit"
 	"github.com/documize/community/model/doc"
 	"github.com/documize/community/model/page"
 	"github.com/documize/community/model/template"
 	uuid "github.com/nu7hatch/gouuid"
+	"github.com/pkg/errors"
 )
 
 // Handler contains the runtime information such as logging and database.
@@ -126,7 +130,7 @@ func (h *Handler) SaveAs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Duplicate document
-	doc, err := h.Store.Document.Get(ctx, model.DocumentID)
+	d, err := h.Store.Document.Get(ctx, model.DocumentID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
 		h.Runtime.Log.Error(method, err)
@@ -134,12 +138,13 @@ func (h *Handler) SaveAs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	docID := uniqueid.Generate()
-	doc.Template = true
-	doc.Title = model.Name
-	doc.Excerpt = model.Excerpt
-	doc.RefID = docID
-	doc.ID = 0
-	doc.Template = true
+	d.Template = true
+	d.Title = model.Name
+	d.Excerpt = model.Excerpt
+	d.RefID = docID
+	d.ID = 0
+	d.Template = true
+	d.Lifecycle = doc.LifecycleLive // whatever state the source document is in
 
 	// Duplicate pages and associated meta
 	pages, err := h.Store.Page.GetPages(ctx, model.DocumentID)
@@ -184,7 +189,7 @@ func (h *Handler) SaveAs(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Now create the template: document, attachments, pages and their meta
-	err = h.Store.Document.Add(ctx, doc)
+	err = h.Store.Document.Add(ctx, d)
 	if err != nil {
 		ctx.Transaction.Rollback()
 		response.WriteServerError(w, method, err)
@@ -219,14 +224,14 @@ func (h *Handler) SaveAs(w http.ResponseWriter, r *http.Request) {
 	// Commit and return new document template
 	ctx.Transaction.Commit()
 
-	doc, err = h.Store.Document.Get(ctx, docID)
+	d, err = h.Store.Document.Get(ctx, docID)
 	if err != nil {
 		response.WriteServerError(w, method, err)
 		h.Runtime.Log.Error(method, err)
 		return
 	}
 
-	response.WriteJSON(w, doc)
+	response.WriteJSON(w, d)
 }
 
 // Use creates new document using a saved document as a template.
@@ -255,7 +260,18 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	docTitle := string(body)
+	// Body is either the new document title or, when sent as JSON because
+	// the template declares variables, a UseRequest carrying title and values.
+	model := template.UseRequest{Title: string(body)}
+	if isJSON(r) {
+		err = json.Unmarshal(body, &model)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "Bad payload")
+			return
+		}
+	}
+
+	docTitle := model.Title
 
 	// Define an empty document just in case user wanted one.
 	var d = doc.Document{}
@@ -289,6 +305,32 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 		attachments, _ = h.Store.Attachment.GetAttachmentsWithData(ctx, templateID)
 	}
 
+	var stored, shown map[string]string
+	var version int
+	if templateID != "0" {
+		vars, err := h.Store.Template.GetVariables(ctx, templateID)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		version, err = h.Store.Template.GetVersion(ctx, templateID)
+		if err != nil {
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		stored, shown, err = resolveValues(ctx, *h.Store, vars, model.Values)
+		if err != nil {
+			response.WriteBadRequestError(w, method, err.Error())
+			return
+		}
+
+		docTitle = substitute(docTitle, shown, plain)
+	}
+
 	ctx.Transaction, err = h.Runtime.Db.Beginx()
 	if err != nil {
 		response.WriteServerError(w, method, err)
@@ -303,6 +345,16 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 	d.LabelID = folderID
 	d.UserID = ctx.UserID
 	d.Title = docTitle
+	d.Lifecycle = doc.LifecycleLive
+
+	// tags outside the space's vocabulary are not copied from the template
+	d.Tags, err = tag.Fit(ctx, *h.Store, folderID, d.Tags)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
 
 	err = h.Store.Document.Add(ctx, d)
 	if err != nil {
@@ -312,6 +364,14 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err = watch.Author(ctx, *h.Store, documentID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
 	for _, p := range pages {
 		meta, err2 := h.Store.Page.GetPageMeta(ctx, p.RefID)
 		if err2 != nil {
@@ -321,12 +381,14 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		templatePageID := p.RefID
 		p.DocumentID = documentID
 		pageID := uniqueid.Generate()
 		p.RefID = pageID
 
 		meta.PageID = pageID
 		meta.DocumentID = documentID
+		instantiate(&p, &meta, shown)
 
 		model := page.NewPage{}
 		model.Page = p
@@ -340,6 +402,16 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 			h.Runtime.Log.Error(method, err)
 			return
 		}
+
+		if templateID != "0" {
+			err = h.Store.Template.AddSection(ctx, documentID, template.Section{PageID: pageID, TemplatePageID: templatePageID})
+			if err != nil {
+				ctx.Transaction.Rollback()
+				response.WriteServerError(w, method, err)
+				h.Runtime.Log.Error(method, err)
+				return
+			}
+		}
 	}
 
 	newUUID, _ := uuid.NewV4()
@@ -361,6 +433,16 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if templateID != "0" {
+		err = h.Store.Template.AddOrigin(ctx, template.Origin{DocumentID: documentID, TemplateID: templateID, Version: version, Values: stored})
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+	}
+
 	h.Store.Audit.Record(ctx, audit.EventTypeTemplateUse)
 
 	ctx.Transaction.Commit()
@@ -378,3 +460,127 @@ func (h *Handler) Use(w http.ResponseWriter, r *http.Request) {
 
 	response.WriteJSON(w, nd)
 }
+
+// GetVariables returns the variables a template prompts for when used.
+func (h *Handler) GetVariables(w http.ResponseWriter, r *http.Request) {
+	method := "template.getVariables"
+	ctx := domain.GetRequestContext(r)
+
+	templateID := request.Param(r, "templateID")
+	if len(templateID) == 0 {
+		response.WriteMissingDataError(w, method, "templateID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, templateID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	vars, err := h.Store.Template.GetVariables(ctx, templateID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, vars)
+}
+
+// SetVariables replaces the variables a template prompts for when used.
+func (h *Handler) SetVariables(w http.ResponseWriter, r *http.Request) {
+	method := "template.setVariables"
+	ctx := domain.GetRequestContext(r)
+
+	templateID := request.Param(r, "templateID")
+	if len(templateID) == 0 {
+		response.WriteMissingDataError(w, method, "templateID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, templateID)
+	if err != nil || !d.Template {
+		response.WriteNotFoundError(w, method, templateID)
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, templateID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	vars := []template.Variable{}
+	err = json.Unmarshal(body, &vars)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	err = validateVariables(vars)
+	if err != nil {
+		response.WriteBadRequestError(w, method, err.Error())
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Template.SetVariables(ctx, templateID, vars)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	response.WriteJSON(w, vars)
+}
+
+// GetOrigin returns the template a document was created from and the values supplied.
+func (h *Handler) GetOrigin(w http.ResponseWriter, r *http.Request) {
+	method := "template.getOrigin"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	o, err := h.Store.Template.GetOrigin(ctx, documentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, o)
+}
+
+// isJSON reports whether the request body is declared as JSON.
+func isJSON(r *http.Request) bool {
+	t, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && t == "application/json"
+}
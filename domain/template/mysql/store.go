@@ -0,0 +1,271 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/template"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// GetVariables returns the variables a template prompts for, in order.
+func (s Scope) GetVariables(ctx domain.RequestContext, templateID string) (v []template.Variable, err error) {
+	var raw sql.NullString
+
+	err = s.Runtime.Db.Get(&raw, "SELECT variables FROM template WHERE orgid=? AND templateid=?", ctx.OrgID, templateID)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select variables for template %s", templateID))
+		return
+	}
+
+	if raw.Valid && len(raw.String) > 0 {
+		err = json.Unmarshal([]byte(raw.String), &v)
+		if err != nil {
+			err = errors.Wrap(err, fmt.Sprintf("unmarshal variables for template %s", templateID))
+			return
+		}
+	}
+
+	if len(v) == 0 {
+		v = []template.Variable{}
+	}
+
+	return
+}
+
+// SetVariables replaces the variables a template prompts for.
+func (s Scope) SetVariables(ctx domain.RequestContext, templateID string, v []template.Variable) (err error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		err = errors.Wrap(err, "marshal template variables")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO template (orgid, templateid, variables, created, revised) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE variables=VALUES(variables), revised=VALUES(revised)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare upsert template variables")
+		return
+	}
+
+	_, err = stmt.Exec(ctx.OrgID, templateID, string(raw), now, now)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute upsert template variables %s", templateID))
+		return
+	}
+
+	return
+}
+
+// AddOrigin records the template a document was created from.
+func (s Scope) AddOrigin(ctx domain.RequestContext, o template.Origin) (err error) {
+	raw, err := json.Marshal(o.Values)
+	if err != nil {
+		err = errors.Wrap(err, "marshal template values")
+		return
+	}
+
+	now := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO documenttemplate (orgid, documentid, templateid, version, variablevalues, synced, created) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert document template")
+		return
+	}
+
+	_, err = stmt.Exec(ctx.OrgID, o.DocumentID, o.TemplateID, o.Version, string(raw), now, now)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute insert document template %s", o.DocumentID))
+		return
+	}
+
+	return
+}
+
+// GetOrigin returns the template a document was created from.
+func (s Scope) GetOrigin(ctx domain.RequestContext, documentID string) (o template.Origin, err error) {
+	row := struct {
+		DocumentID     string
+		TemplateID     string
+		Version        int
+		VariableValues sql.NullString
+		Synced         time.Time
+		Created        time.Time
+	}{}
+
+	err = s.Runtime.Db.Get(&row, "SELECT documentid, templateid, version, variablevalues, COALESCE(synced, created) AS synced, created FROM documenttemplate WHERE orgid=? AND documentid=?", ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select template origin for document %s", documentID))
+		return
+	}
+
+	o.DocumentID = row.DocumentID
+	o.TemplateID = row.TemplateID
+	o.Version = row.Version
+	o.Created = row.Created
+	o.Synced = row.Synced
+	o.Values = make(map[string]string)
+
+	if row.VariableValues.Valid && len(row.VariableValues.String) > 0 {
+		err = json.Unmarshal([]byte(row.VariableValues.String), &o.Values)
+		if err != nil {
+			err = errors.Wrap(err, fmt.Sprintf("unmarshal template values for document %s", documentID))
+			return
+		}
+	}
+
+	return
+}
+
+// UpdateOrigin records that a document now matches the given template version.
+func (s Scope) UpdateOrigin(ctx domain.RequestContext, documentID string, version int) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE documenttemplate SET version=?, synced=? WHERE orgid=? AND documentid=?", version, time.Now().UTC(), ctx.OrgID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update template origin for document %s", documentID))
+	}
+
+	return
+}
+
+// GetVersion returns the latest version of a template.
+// Templates start at version 1.
+func (s Scope) GetVersion(ctx domain.RequestContext, templateID string) (version int, err error) {
+	err = s.Runtime.Db.Get(&version, "SELECT version FROM template WHERE orgid=? AND templateid=?", ctx.OrgID, templateID)
+	if err == sql.ErrNoRows {
+		return 1, nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select version for template %s", templateID))
+	}
+
+	return
+}
+
+// AddVersion releases a new template version.
+func (s Scope) AddVersion(ctx domain.RequestContext, v template.Version) (err error) {
+	now := time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO templateversion (orgid, templateid, version, userid, note, data, created) VALUES (?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert template version")
+		return
+	}
+
+	_, err = stmt.Exec(ctx.OrgID, v.TemplateID, v.Version, v.UserID, v.Note, v.Data, now)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("execute insert template version %s", v.TemplateID))
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("INSERT INTO template (orgid, templateid, version, created, revised) VALUES (?, ?, ?, ?, ?) ON DUPLICATE KEY UPDATE version=VALUES(version), revised=VALUES(revised)",
+		ctx.OrgID, v.TemplateID, v.Version, now, now)
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update version for template %s", v.TemplateID))
+	}
+
+	return
+}
+
+// GetVersions returns released versions of a template, newest first,
+// with how many documents are at each.
+func (s Scope) GetVersions(ctx domain.RequestContext, templateID string) (v []template.Version, err error) {
+	err = s.Runtime.Db.Select(&v, `SELECT a.templateid, a.version, a.userid, a.note, a.created,
+		(SELECT COUNT(*) FROM documenttemplate b, document c WHERE b.orgid=a.orgid AND b.templateid=a.templateid AND b.version=a.version
+			AND c.orgid=b.orgid AND c.refid=b.documentid) AS documents
+		FROM templateversion a WHERE a.orgid=? AND a.templateid=? ORDER BY a.version DESC`, ctx.OrgID, templateID)
+
+	if err == sql.ErrNoRows || len(v) == 0 {
+		err = nil
+		v = []template.Version{}
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select versions for template %s", templateID))
+	}
+
+	return
+}
+
+// GetVersionData returns the template sections recorded when a version
+// was released. Versions released before sections were recorded have none.
+func (s Scope) GetVersionData(ctx domain.RequestContext, templateID string, version int) (data []byte, err error) {
+	err = s.Runtime.Db.Get(&data, "SELECT data FROM templateversion WHERE orgid=? AND templateid=? AND version=? AND data IS NOT NULL", ctx.OrgID, templateID, version)
+	if err != nil && err != sql.ErrNoRows {
+		err = errors.Wrap(err, fmt.Sprintf("select data for template %s version %d", templateID, version))
+	}
+
+	return
+}
+
+// GetDerived returns documents created from a template. A version
+// of zero or less returns documents at any version.
+func (s Scope) GetDerived(ctx domain.RequestContext, templateID string, version int) (d []template.Derived, err error) {
+	err = s.Runtime.Db.Select(&d, `SELECT a.documentid, b.labelid, b.title, a.version, COALESCE(a.synced, a.created) AS synced
+		FROM documenttemplate a, document b
+		WHERE a.orgid=? AND a.templateid=? AND (?<=0 OR a.version=?) AND b.orgid=a.orgid AND b.refid=a.documentid
+		ORDER BY b.title`, ctx.OrgID, templateID, version, version)
+
+	if err == sql.ErrNoRows || len(d) == 0 {
+		err = nil
+		d = []template.Derived{}
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select documents derived from template %s", templateID))
+	}
+
+	return
+}
+
+// AddSection records the template section a document section came from.
+func (s Scope) AddSection(ctx domain.RequestContext, documentID string, sec template.Section) (err error) {
+	_, err = ctx.Transaction.Exec("INSERT INTO templatesection (orgid, documentid, pageid, templatepageid, created) VALUES (?, ?, ?, ?, ?)",
+		ctx.OrgID, documentID, sec.PageID, sec.TemplatePageID, time.Now().UTC())
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("insert template section for page %s", sec.PageID))
+	}
+
+	return
+}
+
+// GetSections returns the template sections a document's sections came from.
+func (s Scope) GetSections(ctx domain.RequestContext, documentID string) (sec []template.Section, err error) {
+	err = s.Runtime.Db.Select(&sec, "SELECT pageid, templatepageid FROM templatesection WHERE orgid=? AND documentid=?", ctx.OrgID, documentID)
+	if err == sql.ErrNoRows {
+		err = nil
+	}
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select template sections for document %s", documentID))
+	}
+
+	return
+}
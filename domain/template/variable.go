@@ -0,0 +1,213 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/template"
+)
+
+// placeholder matches {{ .Name }} in template content.
+var placeholder = regexp.MustCompile(`\{\{\s*\.([A-Za-z][A-Za-z0-9_]*)\s*\}\}`)
+
+// variableName is what placeholders can refer to.
+var variableName = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// validateVariables checks variable declarations before they are saved.
+func validateVariables(vars []template.Variable) (err error) {
+	seen := make(map[string]bool)
+
+	for _, v := range vars {
+		if !variableName.MatchString(v.Name) {
+			return fmt.Errorf("variable name %q must start with a letter and contain only letters, digits and underscores", v.Name)
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable %s is declared twice", v.Name)
+		}
+		seen[v.Name] = true
+
+		switch v.Type {
+		case template.VariableText, template.VariableUser:
+		case template.VariableDate:
+			if len(v.Default) > 0 {
+				if _, err = time.Parse(template.DateFormat, v.Default); err != nil {
+					return fmt.Errorf("default for %s must be a date formatted as YYYY-MM-DD", v.Name)
+				}
+			}
+		case template.VariableChoice:
+			if len(v.Choices) == 0 {
+				return fmt.Errorf("choice variable %s has no choices", v.Name)
+			}
+			if len(v.Default) > 0 && !contains(v.Choices, v.Default) {
+				return fmt.Errorf("default for %s is not one of its choices", v.Name)
+			}
+		default:
+			return fmt.Errorf("variable %s has unknown type %q", v.Name, v.Type)
+		}
+	}
+
+	return nil
+}
+
+// resolveValues checks supplied values against the declared variables,
+// returning what to store on the document and what to substitute into
+// content. User variables are stored as IDs but shown as names.
+func resolveValues(ctx domain.RequestContext, s domain.Store, vars []template.Variable, supplied map[string]string) (stored, shown map[string]string, err error) {
+	stored = make(map[string]string)
+	shown = make(map[string]string)
+
+	for _, v := range vars {
+		value := strings.TrimSpace(supplied[v.Name])
+		if len(value) == 0 {
+			value = v.Default
+		}
+
+		if len(value) == 0 {
+			if v.Required {
+				return nil, nil, fmt.Errorf("%s is required", label(v))
+			}
+
+			stored[v.Name] = ""
+			shown[v.Name] = ""
+			continue
+		}
+
+		display := value
+
+		switch v.Type {
+		case template.VariableDate:
+			if _, err = time.Parse(template.DateFormat, value); err != nil {
+				return nil, nil, fmt.Errorf("%s must be a date formatted as YYYY-MM-DD", label(v))
+			}
+		case template.VariableChoice:
+			if !contains(v.Choices, value) {
+				return nil, nil, fmt.Errorf("%s must be one of %s", label(v), strings.Join(v.Choices, ", "))
+			}
+		case template.VariableUser:
+			acc, err2 := s.Account.GetUserAccount(ctx, value)
+			if err2 != nil || !acc.Active {
+				return nil, nil, fmt.Errorf("%s must be a user in this organization", label(v))
+			}
+
+			u, err2 := s.User.Get(ctx, value)
+			if err2 != nil {
+				return nil, nil, err2
+			}
+
+			display = u.Fullname()
+		}
+
+		stored[v.Name] = value
+		shown[v.Name] = display
+	}
+
+	return
+}
+
+// displayValues turns values stored on a document back into what was
+// substituted into its content, showing user variables as names.
+func displayValues(ctx domain.RequestContext, s domain.Store, vars []template.Variable, stored map[string]string) (shown map[string]string) {
+	shown = make(map[string]string)
+
+	for k, v := range stored {
+		shown[k] = v
+	}
+
+	for _, v := range vars {
+		if v.Type != template.VariableUser || len(stored[v.Name]) == 0 {
+			continue
+		}
+
+		u, err := s.User.Get(ctx, stored[v.Name])
+		if err == nil {
+			shown[v.Name] = u.Fullname()
+		}
+	}
+
+	return
+}
+
+// instantiate substitutes values into a section copied from a template.
+func instantiate(p *page.Page, meta *page.Meta, shown map[string]string) {
+	p.Title = substitute(p.Title, shown, plain)
+	p.Body = substitute(p.Body, shown, html.EscapeString)
+
+	meta.Config = substitute(meta.Config, shown, escapeJSON)
+	if p.ContentType == "wysiwyg" {
+		meta.RawBody = substitute(meta.RawBody, shown, html.EscapeString)
+	} else {
+		meta.RawBody = substitute(meta.RawBody, shown, plain)
+	}
+}
+
+// substitute replaces placeholders for known variables, leaving others untouched.
+// escape prepares values for the kind of content being substituted into.
+func substitute(content string, values map[string]string, escape func(string) string) string {
+	if len(values) == 0 {
+		return content
+	}
+
+	return placeholder.ReplaceAllStringFunc(content, func(p string) string {
+		name := placeholder.FindStringSubmatch(p)[1]
+
+		value, ok := values[name]
+		if !ok {
+			return p
+		}
+
+		return escape(value)
+	})
+}
+
+// plain leaves values as they are, for titles and markdown.
+func plain(s string) string {
+	return s
+}
+
+// escapeJSON makes values safe inside JSON strings such as section config.
+func escapeJSON(s string) string {
+	var b bytes.Buffer
+
+	e := json.NewEncoder(&b)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+
+	quoted := strings.TrimSuffix(b.String(), "\n")
+	return quoted[1 : len(quoted)-1]
+}
+
+func label(v template.Variable) string {
+	if len(v.Label) > 0 {
+		return v.Label
+	}
+
+	return v.Name
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+
+	return false
+}
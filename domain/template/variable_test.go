@@ -0,0 +1,88 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package template
+
+import (
+	"html"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/documize/community/model/template"
+)
+
+func TestSubstitute(t *testing.T) {
+	values := map[string]string{"ServiceName": "Billing & Payments", "Date": "2018-03-01"}
+
+	tests := []struct {
+		content string
+		escape  func(string) string
+		want    string
+	}{
+		{"{{ .ServiceName }} outage", plain, "Billing & Payments outage"},
+		{"{{.ServiceName}} on {{ .Date }}", plain, "Billing & Payments on 2018-03-01"},
+		{"<p>{{ .ServiceName }}</p>", html.EscapeString, "<p>Billing &amp; Payments</p>"},
+		{`{"title":"{{ .ServiceName }}"}`, escapeJSON, `{"title":"Billing & Payments"}`},
+		{"{{ .Unknown }} stays", plain, "{{ .Unknown }} stays"},
+		{"{{ ServiceName }} needs a dot", plain, "{{ ServiceName }} needs a dot"},
+	}
+
+	for _, test := range tests {
+		got := substitute(test.content, values, test.escape)
+		if got != test.want {
+			t.Errorf("substitute(%q) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
+
+func TestValidateVariables(t *testing.T) {
+	tests := []struct {
+		vars []template.Variable
+		ok   bool
+	}{
+		{[]template.Variable{{Name: "ServiceName", Type: template.VariableText}}, true},
+		{[]template.Variable{{Name: "Severity", Type: template.VariableChoice, Choices: []string{"low", "high"}, Default: "low"}}, true},
+		{[]template.Variable{{Name: "Severity", Type: template.VariableChoice}}, false},
+		{[]template.Variable{{Name: "Severity", Type: template.VariableChoice, Choices: []string{"low"}, Default: "high"}}, false},
+		{[]template.Variable{{Name: "When", Type: template.VariableDate, Default: "01/03/2018"}}, false},
+		{[]template.Variable{{Name: "Service Name", Type: template.VariableText}}, false},
+		{[]template.Variable{{Name: "Owner", Type: "person"}}, false},
+		{[]template.Variable{{Name: "A", Type: template.VariableText}, {Name: "A", Type: template.VariableText}}, false},
+	}
+
+	for _, test := range tests {
+		err := validateVariables(test.vars)
+		if (err == nil) != test.ok {
+			t.Errorf("validateVariables(%v) = %v, want ok %v", test.vars, err, test.ok)
+		}
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/json", true},
+		{"application/json; charset=UTF-8", true},
+		{"application/x-www-form-urlencoded; charset=UTF-8", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader("{Draft} Q3 plan"))
+		r.Header.Set("Content-Type", test.contentType)
+		if got := isJSON(r); got != test.want {
+			t.Errorf("isJSON(%q) = %v, want %v", test.contentType, got, test.want)
+		}
+	}
+}
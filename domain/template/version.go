@@ -0,0 +1,701 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package template
+
+import (
+	"database/sql"
+	"encoding/json"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/comment"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/template"
+	"github.com/pkg/errors"
+)
+
+// GetVersions returns the released versions of a template.
+func (h *Handler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	method := "template.getVersions"
+	ctx := domain.GetRequestContext(r)
+
+	templateID := request.Param(r, "templateID")
+	if len(templateID) == 0 {
+		response.WriteMissingDataError(w, method, "templateID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, templateID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	v, err := h.Store.Template.GetVersions(ctx, templateID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, v)
+}
+
+// AddVersion releases a new version of a template, recording its sections
+// as they are now. Documents created from earlier versions are then offered
+// the changes between the version they match and this one.
+func (h *Handler) AddVersion(w http.ResponseWriter, r *http.Request) {
+	method := "template.addVersion"
+	ctx := domain.GetRequestContext(r)
+
+	templateID := request.Param(r, "templateID")
+	if len(templateID) == 0 {
+		response.WriteMissingDataError(w, method, "templateID")
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, templateID)
+	if err != nil || !d.Template {
+		response.WriteNotFoundError(w, method, templateID)
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, templateID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := template.VersionRequest{}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &model)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "Bad payload")
+			return
+		}
+	}
+
+	current, err := h.Store.Template.GetVersion(ctx, templateID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	sections, err := templatePages(ctx, *h.Store, templateID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	data, err := json.Marshal(sections)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	v := template.Version{
+		TemplateID: templateID,
+		Version:    current + 1,
+		UserID:     ctx.UserID,
+		Note:       strings.TrimSpace(model.Note),
+		Created:    time.Now().UTC(),
+		Data:       data,
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Template.AddVersion(ctx, v)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTemplateVersion)
+
+	response.WriteJSON(w, v)
+}
+
+// GetDerived returns documents the user can see that were created from a
+// template, optionally only those at the version given by ?version=.
+func (h *Handler) GetDerived(w http.ResponseWriter, r *http.Request) {
+	method := "template.getDerived"
+	ctx := domain.GetRequestContext(r)
+
+	templateID := request.Param(r, "templateID")
+	if len(templateID) == 0 {
+		response.WriteMissingDataError(w, method, "templateID")
+		return
+	}
+
+	version := 0
+	if q := request.Query(r, "version"); len(q) > 0 {
+		var err error
+		version, err = strconv.Atoi(q)
+		if err != nil {
+			response.WriteBadRequestError(w, method, "version")
+			return
+		}
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, templateID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	derived, err := h.Store.Template.GetDerived(ctx, templateID, version)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	roles, err := h.Store.Space.GetUserRoles(ctx)
+	if err != nil && err != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	viewable := make(map[string]bool)
+	for _, role := range roles {
+		if role.CanView || role.CanEdit {
+			viewable[role.LabelID] = true
+		}
+	}
+
+	visible := []template.Derived{}
+	for _, d := range derived {
+		if viewable[d.LabelID] {
+			visible = append(visible, d)
+		}
+	}
+
+	response.WriteJSON(w, visible)
+}
+
+// GetPatches proposes changes that bring a document in line with the
+// latest version of the template it was created from.
+func (h *Handler) GetPatches(w http.ResponseWriter, r *http.Request) {
+	method := "template.getPatches"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	o, err := h.Store.Template.GetOrigin(ctx, documentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	p, _, err := proposePatches(ctx, *h.Store, o)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, p)
+}
+
+// ApplyPatches applies the chosen patches to a document and marks it as
+// matching the latest template version. Patches not chosen are dismissed.
+// Updated sections keep their previous content as a revision.
+// Archived documents, or chosen sections locked by someone else, are
+// reported as conflicts and nothing is applied.
+func (h *Handler) ApplyPatches(w http.ResponseWriter, r *http.Request) {
+	method := "template.applyPatches"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	d, err := h.Store.Document.Get(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	if d.Lifecycle == doc.LifecycleArchived {
+		response.WriteConflictError(w, method, []template.Conflict{{Reason: "archived"}})
+		return
+	}
+
+	if !document.CanChangeDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	model := template.PatchRequest{}
+	err = json.Unmarshal(body, &model)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	o, err := h.Store.Template.GetOrigin(ctx, documentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, documentID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	proposed, released, err := proposePatches(ctx, *h.Store, o)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	apply := make(map[string]bool)
+	for _, id := range model.Apply {
+		apply[id] = true
+	}
+
+	conflicts := []template.Conflict{}
+	for _, patch := range proposed.Patches {
+		if !apply[patch.TemplatePageID] || patch.Kind != template.PatchUpdate {
+			continue
+		}
+		if l, err2 := h.Store.Page.GetLock(ctx, patch.PageID); err2 == nil && l.UserID != ctx.UserID {
+			conflicts = append(conflicts, template.Conflict{TemplatePageID: patch.TemplatePageID, PageID: patch.PageID, Reason: "locked"})
+		}
+	}
+	if len(conflicts) > 0 {
+		response.WriteConflictError(w, method, conflicts)
+		return
+	}
+
+	vars, err := h.Store.Template.GetVariables(ctx, o.TemplateID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+	shown := displayValues(ctx, *h.Store, vars, o.Values)
+
+	// Additions are placed using the document's layout, which is tracked
+	// here as sections are added within the transaction.
+	tpages := []page.Page{}
+	fromTemplate := make(map[string]page.NewPage)
+	for _, np := range released {
+		tpages = append(tpages, np.Page)
+		fromTemplate[np.Page.RefID] = np
+	}
+
+	dpages, err := h.Store.Page.GetPages(ctx, o.DocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	sections, err := h.Store.Template.GetSections(ctx, o.DocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	changed := []page.Page{}
+
+	for _, patch := range proposed.Patches {
+		if !apply[patch.TemplatePageID] {
+			continue
+		}
+
+		var p page.Page
+		switch patch.Kind {
+		case template.PatchAdd:
+			sequence := insertSequence(tpages, dpages, sections, patch.TemplatePageID)
+			p, err = h.addSection(ctx, o, fromTemplate[patch.TemplatePageID], sequence, shown)
+			if err == nil {
+				dpages = insertPage(dpages, p)
+				sections = append(sections, template.Section{PageID: p.RefID, TemplatePageID: patch.TemplatePageID})
+			}
+		case template.PatchUpdate:
+			p, err = h.updateSection(ctx, o, patch, fromTemplate[patch.TemplatePageID], shown)
+		}
+
+		if err != nil {
+			ctx.Transaction.Rollback()
+			response.WriteServerError(w, method, err)
+			h.Runtime.Log.Error(method, err)
+			return
+		}
+
+		changed = append(changed, p)
+	}
+
+	err = h.Store.Template.UpdateOrigin(ctx, documentID, proposed.TemplateVersion)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTemplatePatch)
+
+	for _, p := range changed {
+		go h.Indexer.IndexContent(ctx, p)
+	}
+
+	o, err = h.Store.Template.GetOrigin(ctx, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, o)
+}
+
+// addSection copies a released template section into the document at the given sequence.
+func (h *Handler) addSection(ctx domain.RequestContext, o template.Origin, t page.NewPage, sequence float64, shown map[string]string) (p page.Page, err error) {
+	p = t.Page
+	meta := t.Meta
+	templatePageID := p.RefID
+
+	pageID := uniqueid.Generate()
+	p.RefID = pageID
+	p.DocumentID = o.DocumentID
+	p.UserID = ctx.UserID
+	p.Sequence = sequence
+
+	meta.PageID = pageID
+	meta.DocumentID = o.DocumentID
+	meta.UserID = ctx.UserID
+	instantiate(&p, &meta, shown)
+
+	err = h.Store.Page.Add(ctx, page.NewPage{Page: p, Meta: meta})
+	if err != nil {
+		return
+	}
+
+	err = h.Store.Template.AddSection(ctx, o.DocumentID, template.Section{PageID: pageID, TemplatePageID: templatePageID})
+
+	return
+}
+
+// updateSection replaces a document section with its released template
+// section, keeping the section's place in the document.
+func (h *Handler) updateSection(ctx domain.RequestContext, o template.Origin, patch template.Patch, t page.NewPage, shown map[string]string) (p page.Page, err error) {
+	meta := t.Meta
+
+	p, err = h.Store.Page.Get(ctx, patch.PageID)
+	if err != nil {
+		return
+	}
+
+	p.Title = t.Page.Title
+	p.Body = t.Page.Body
+	p.ContentType = t.Page.ContentType
+
+	meta.PageID = p.RefID
+	meta.DocumentID = o.DocumentID
+	meta.UserID = ctx.UserID
+	instantiate(&p, &meta, shown)
+
+	err = h.Store.Page.Update(ctx, p, uniqueid.Generate(), ctx.UserID, false)
+	if err != nil {
+		return
+	}
+
+	err = h.Store.Page.UpdateMeta(ctx, meta, true)
+	if err != nil {
+		return
+	}
+
+	err = comment.Reanchor(ctx, *h.Store, p)
+
+	return
+}
+
+// proposePatches compares a document with the template it came from.
+// Sections in the latest released version that are missing from the
+// version the document matches are proposed as additions and sections
+// that differ between the two as updates. Versions released before
+// sections were recorded fall back to the template's current sections
+// and to what changed since the document was last synced. Nothing is
+// proposed until the template has released a version newer than the
+// one the document matches. The released sections are returned so
+// patches are applied as they were proposed.
+func proposePatches(ctx domain.RequestContext, s domain.Store, o template.Origin) (p template.Patches, tpages []page.NewPage, err error) {
+	p.DocumentID = o.DocumentID
+	p.TemplateID = o.TemplateID
+	p.Version = o.Version
+	p.Patches = []template.Patch{}
+
+	p.TemplateVersion, err = s.Template.GetVersion(ctx, o.TemplateID)
+	if err != nil || p.TemplateVersion <= o.Version {
+		return
+	}
+
+	tpages, ok, err := releasedPages(ctx, s, o.TemplateID, p.TemplateVersion)
+	if err != nil {
+		return
+	}
+	if !ok {
+		tpages, err = templatePages(ctx, s, o.TemplateID)
+		if err != nil {
+			return
+		}
+	}
+
+	bpages, known, err := releasedPages(ctx, s, o.TemplateID, o.Version)
+	if err != nil {
+		return
+	}
+	base := make(map[string]page.NewPage)
+	for _, b := range bpages {
+		base[b.Page.RefID] = b
+	}
+
+	dpages, err := s.Page.GetPages(ctx, o.DocumentID)
+	if err != nil {
+		return
+	}
+
+	sections, err := s.Template.GetSections(ctx, o.DocumentID)
+	if err != nil {
+		return
+	}
+
+	vars, err := s.Template.GetVariables(ctx, o.TemplateID)
+	if err != nil {
+		return
+	}
+	shown := displayValues(ctx, s, vars, o.Values)
+
+	current := make(map[string]page.Page)
+	for _, d := range dpages {
+		current[d.RefID] = d
+	}
+
+	mapped := make(map[string]page.Page)
+	for _, sec := range sections {
+		if d, ok := current[sec.PageID]; ok {
+			mapped[sec.TemplatePageID] = d
+		}
+	}
+
+	for _, np := range tpages {
+		t := np.Page
+		d, ok := mapped[t.RefID]
+
+		added := t.Created.After(o.Synced)
+		changed := t.Revised.After(o.Synced)
+		if known {
+			b, was := base[t.RefID]
+			added = !was
+			changed = !was || b.Page.Title != t.Title || b.Page.Body != t.Body || b.Meta.RawBody != np.Meta.RawBody || b.Meta.Config != np.Meta.Config
+		}
+
+		switch {
+		case ok && changed:
+			p.Patches = append(p.Patches, template.Patch{
+				TemplatePageID: t.RefID,
+				PageID:         d.RefID,
+				Kind:           template.PatchUpdate,
+				Title:          substitute(t.Title, shown, plain),
+				Body:           substitute(t.Body, shown, html.EscapeString),
+				CurrentTitle:   d.Title,
+				CurrentBody:    d.Body,
+				Edited:         d.Revised.After(o.Synced),
+			})
+		case !ok && added:
+			p.Patches = append(p.Patches, template.Patch{
+				TemplatePageID: t.RefID,
+				Kind:           template.PatchAdd,
+				Title:          substitute(t.Title, shown, plain),
+				Body:           substitute(t.Body, shown, html.EscapeString),
+			})
+		}
+	}
+
+	return
+}
+
+// releasedPages returns the template sections recorded for a released
+// version, or false if the version was released before they were recorded.
+func releasedPages(ctx domain.RequestContext, s domain.Store, templateID string, version int) (pages []page.NewPage, ok bool, err error) {
+	data, err := s.Template.GetVersionData(ctx, templateID, version)
+	if errors.Cause(err) == sql.ErrNoRows || (err == nil && len(data) == 0) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return
+	}
+
+	err = json.Unmarshal(data, &pages)
+	if err != nil {
+		err = errors.Wrap(err, "decode template version")
+		return
+	}
+
+	return pages, true, nil
+}
+
+// templatePages returns a template's current sections with their meta,
+// in sequence order.
+func templatePages(ctx domain.RequestContext, s domain.Store, templateID string) (pages []page.NewPage, err error) {
+	tpages, err := s.Page.GetPages(ctx, templateID)
+	if err != nil {
+		return
+	}
+
+	meta, err := s.Page.GetDocumentPageMeta(ctx, templateID, false)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	err = nil
+
+	pages = []page.NewPage{}
+	for _, p := range tpages {
+		np := page.NewPage{Page: p}
+		for _, m := range meta {
+			if m.PageID == p.RefID {
+				np.Meta = m
+				break
+			}
+		}
+		pages = append(pages, np)
+	}
+
+	return
+}
+
+// insertSequence places a new section after the document section that came
+// from the closest preceding template section, or first if there is none.
+func insertSequence(tpages, dpages []page.Page, sections []template.Section, templatePageID string) float64 {
+	current := make(map[string]bool)
+	for _, d := range dpages {
+		current[d.RefID] = true
+	}
+
+	fromTemplate := make(map[string]string)
+	for _, sec := range sections {
+		if current[sec.PageID] {
+			fromTemplate[sec.TemplatePageID] = sec.PageID
+		}
+	}
+
+	anchor := ""
+	for _, t := range tpages {
+		if t.RefID == templatePageID {
+			break
+		}
+		if id, ok := fromTemplate[t.RefID]; ok {
+			anchor = id
+		}
+	}
+
+	// dpages are in sequence order.
+	prev := 0.0
+	found := len(anchor) == 0
+	for _, d := range dpages {
+		if found {
+			return (prev + d.Sequence) / 2
+		}
+		if d.RefID == anchor {
+			found = true
+		}
+		prev = d.Sequence
+	}
+
+	return prev + 1024
+}
+
+// insertPage adds a section to pages, keeping them in sequence order.
+func insertPage(pages []page.Page, p page.Page) []page.Page {
+	i := 0
+	for i < len(pages) && pages[i].Sequence <= p.Sequence {
+		i++
+	}
+
+	pages = append(pages, page.Page{})
+	copy(pages[i+1:], pages[i:])
+	pages[i] = p
+
+	return pages
+}
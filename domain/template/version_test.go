@@ -0,0 +1,61 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package template
+
+import (
+	"testing"
+
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/template"
+)
+
+func pages(ids ...string) (p []page.Page) {
+	for i, id := range ids {
+		n := page.Page{Sequence: float64((i + 1) * 1024)}
+		n.RefID = id
+		p = append(p, n)
+	}
+	return
+}
+
+func TestInsertSequence(t *testing.T) {
+	tpages := pages("t1", "t2", "t3")
+	sections := []template.Section{{PageID: "d1", TemplatePageID: "t1"}, {PageID: "d3", TemplatePageID: "t3"}}
+
+	// d1, local section, d3
+	dpages := pages("d1", "local", "d3")
+
+	if seq := insertSequence(tpages, dpages, sections, "t2"); seq != 1536 {
+		t.Errorf("expected section after d1, got %v", seq)
+	}
+
+	// nothing precedes the first template section
+	if seq := insertSequence(tpages, dpages, sections[1:], "t1"); seq != 512 {
+		t.Errorf("expected section first, got %v", seq)
+	}
+
+	// after the last document section
+	if seq := insertSequence(tpages, pages("d1"), sections[:1], "t3"); seq != 2048 {
+		t.Errorf("expected section last, got %v", seq)
+	}
+}
+
+func TestInsertPage(t *testing.T) {
+	n := page.Page{Sequence: 1536}
+	n.RefID = "n"
+
+	p := insertPage(pages("a", "b"), n)
+
+	if len(p) != 3 || p[0].RefID != "a" || p[1].RefID != "n" || p[2].RefID != "b" {
+		t.Errorf("unexpected order %v", p)
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package trash
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	indexer "github.com/documize/community/domain/search"
+	"github.com/documize/community/domain/space"
+	"github.com/documize/community/model/activity"
+	"github.com/documize/community/model/audit"
+	"github.com/documize/community/model/trash"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+	Indexer indexer.Indexer
+}
+
+// GetBySpace lists deleted documents and sections of a space, most recent first.
+func (h *Handler) GetBySpace(w http.ResponseWriter, r *http.Request) {
+	method := "trash.list"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, spaceID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	items, err := h.Store.Trash.GetBySpace(ctx, spaceID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(items) == 0 {
+		items = []trash.Item{}
+	}
+
+	response.WriteJSON(w, items)
+}
+
+// Restore brings back deleted content and removes it from trash.
+// Sections can only be restored into a document that exists.
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	method := "trash.restore"
+	ctx := domain.GetRequestContext(r)
+
+	itemID := request.Param(r, "itemID")
+	if len(itemID) == 0 {
+		response.WriteMissingDataError(w, method, "itemID")
+		return
+	}
+
+	item, ok := h.get(w, ctx, method, itemID)
+	if !ok {
+		return
+	}
+
+	c, err := content(item)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Document.Get(ctx, item.DocumentID)
+	exists := err == nil
+
+	if c.Document != nil && exists {
+		response.WriteDuplicateError(w, method, "document")
+		return
+	}
+	if c.Document == nil && !exists {
+		response.WriteBadRequestError(w, method, "document was deleted, restore it first")
+		return
+	}
+	if c.Document != nil {
+		if _, err = h.Store.Space.Get(ctx, c.Document.LabelID); err != nil {
+			response.WriteBadRequestError(w, method, "space no longer exists")
+			return
+		}
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.restore(ctx, c)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Trash.Delete(ctx, itemID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Activity.RecordUserActivity(ctx, activity.UserActivity{
+		LabelID:      item.LabelID,
+		SourceID:     item.DocumentID,
+		SourceType:   activity.SourceTypeDocument,
+		ActivityType: activity.TypeRestored})
+
+	if c.Document != nil {
+		h.Store.Audit.Record(ctx, audit.EventTypeDocumentRestore)
+	} else {
+		h.Store.Audit.Record(ctx, audit.EventTypeSectionRestore)
+	}
+
+	ctx.Transaction.Commit()
+
+	d, err := h.Store.Document.Get(ctx, item.DocumentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if c.Document != nil {
+		a, _ := h.Store.Attachment.GetAttachments(ctx, d.RefID)
+		go h.Indexer.IndexDocument(ctx, d, a)
+	}
+	for _, np := range c.Pages {
+		go h.Indexer.IndexContent(ctx, np.Page)
+	}
+
+	response.WriteJSON(w, d)
+}
+
+// Purge permanently removes an item from trash.
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	method := "trash.purge"
+	ctx := domain.GetRequestContext(r)
+
+	itemID := request.Param(r, "itemID")
+	if len(itemID) == 0 {
+		response.WriteMissingDataError(w, method, "itemID")
+		return
+	}
+
+	if _, ok := h.get(w, ctx, method, itemID); !ok {
+		return
+	}
+
+	var err error
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Trash.Delete(ctx, itemID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Trash.PurgeOrphans(ctx)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTrashPurge)
+
+	ctx.Transaction.Commit()
+
+	response.WriteEmpty(w)
+}
+
+// Config returns installation-wide trash settings.
+func (h *Handler) Config(w http.ResponseWriter, r *http.Request) {
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Global {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	response.WriteJSON(w, config(*h.Store))
+}
+
+// SetConfig persists installation-wide trash settings.
+func (h *Handler) SetConfig(w http.ResponseWriter, r *http.Request) {
+	method := "trash.setConfig"
+	ctx := domain.GetRequestContext(r)
+
+	if !ctx.Global {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	cfg := trash.Config{}
+	err = json.Unmarshal(body, &cfg)
+	if err != nil || cfg.Retention < 0 {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	j, err := json.Marshal(cfg)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	err = h.Store.Setting.Set(configKey, string(j))
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	h.Store.Audit.Record(ctx, audit.EventTypeTrashConfig)
+
+	response.WriteJSON(w, cfg)
+}
+
+// get loads a trash item the user may manage, writing the error response if it cannot.
+func (h *Handler) get(w http.ResponseWriter, ctx domain.RequestContext, method, itemID string) (item trash.Item, ok bool) {
+	item, err := h.Store.Trash.Get(ctx, itemID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteNotFoundError(w, method, itemID)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if !space.CanChangeSpaceDocuments(ctx, *h.Store, item.LabelID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	return item, true
+}
@@ -0,0 +1,170 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/trash"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// Add puts deleted content in trash.
+func (s Scope) Add(ctx domain.RequestContext, t trash.Item) (err error) {
+	t.OrgID = ctx.OrgID
+	t.UserID = ctx.UserID
+	t.Created = time.Now().UTC()
+	t.Revised = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO trash (refid, orgid, labelid, documentid, pageid, userid, title, data, created, revised) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert trash")
+		return
+	}
+
+	_, err = stmt.Exec(t.RefID, t.OrgID, t.LabelID, t.DocumentID, t.PageID, t.UserID, t.Title, t.Data, t.Created, t.Revised)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert trash")
+		return
+	}
+
+	return
+}
+
+// Get returns trash item including deleted content.
+func (s Scope) Get(ctx domain.RequestContext, id string) (t trash.Item, err error) {
+	stmt, err := s.Runtime.Db.Preparex("SELECT a.id, a.refid, a.orgid, a.labelid, a.documentid, a.pageid, a.userid, a.title, a.data, a.created, a.revised, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM trash a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.refid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare select trash")
+		return
+	}
+
+	err = stmt.Get(&t, ctx.OrgID, id)
+	if err != nil {
+		err = errors.Wrap(err, "execute select trash")
+		return
+	}
+
+	return
+}
+
+// GetBySpace returns trash items for a space without their content, most recently deleted first.
+func (s Scope) GetBySpace(ctx domain.RequestContext, spaceID string) (t []trash.Item, err error) {
+	err = s.Runtime.Db.Select(&t, "SELECT a.id, a.refid, a.orgid, a.labelid, a.documentid, a.pageid, a.userid, a.title, a.created, a.revised, coalesce(b.firstname,'') as firstname, coalesce(b.lastname,'') as lastname FROM trash a LEFT JOIN user b ON a.userid=b.refid WHERE a.orgid=? AND a.labelid=? ORDER BY a.id DESC", ctx.OrgID, spaceID)
+
+	if err != nil {
+		err = errors.Wrap(err, "select trash")
+		return
+	}
+
+	return
+}
+
+// GetDeletedSections returns trash items, including content, for sections
+// of a document deleted after since, oldest first.
+func (s Scope) GetDeletedSections(ctx domain.RequestContext, documentID string, since time.Time) (t []trash.Item, err error) {
+	err = s.Runtime.Db.Select(&t, "SELECT id, refid, orgid, labelid, documentid, pageid, userid, title, data, created, revised FROM trash WHERE orgid=? AND documentid=? AND pageid<>'' AND created>? ORDER BY id", ctx.OrgID, documentID, since.UTC())
+
+	if err != nil {
+		err = errors.Wrap(err, "select deleted sections")
+		return
+	}
+
+	return
+}
+
+// Delete removes trash item.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "trash", ctx.OrgID, id)
+}
+
+// Purge removes items deleted before the given time, across all organizations,
+// along with anything left behind by documents that are now gone for good.
+func (s Scope) Purge(ctx domain.RequestContext, before time.Time) (rows int64, err error) {
+	result, err := ctx.Transaction.Exec("DELETE FROM trash WHERE created<?", before.UTC())
+	if err != nil {
+		err = errors.Wrap(err, "purge trash")
+		return
+	}
+
+	rows, err = result.RowsAffected()
+	if err != nil {
+		err = errors.Wrap(err, "purge trash")
+		return
+	}
+
+	err = s.PurgeOrphans(ctx)
+
+	return
+}
+
+// PurgeOrphans removes comments and template records, across all organizations,
+// of documents and sections that are neither present nor held in trash.
+func (s Scope) PurgeOrphans(ctx domain.RequestContext) (err error) {
+	_, err = ctx.Transaction.Exec(`DELETE FROM comment WHERE documentid NOT IN (SELECT refid FROM document)
+		AND documentid NOT IN (SELECT documentid FROM trash WHERE pageid='')`)
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned comments")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec("DELETE FROM commentrevision WHERE commentid NOT IN (SELECT refid FROM comment)")
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned comment revisions")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(`DELETE FROM documenttemplate WHERE documentid NOT IN (SELECT refid FROM document)
+		AND documentid NOT IN (SELECT documentid FROM trash WHERE pageid='')`)
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned document templates")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(`DELETE FROM templatesection WHERE pageid NOT IN (SELECT refid FROM page)
+		AND pageid NOT IN (SELECT pageid FROM trash WHERE pageid<>'')
+		AND documentid NOT IN (SELECT documentid FROM trash WHERE pageid='')`)
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned template sections")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(`DELETE FROM templateversion WHERE templateid NOT IN (SELECT refid FROM document)
+		AND templateid NOT IN (SELECT documentid FROM trash WHERE pageid='')`)
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned template versions")
+		return
+	}
+
+	_, err = ctx.Transaction.Exec(`DELETE FROM template WHERE templateid NOT IN (SELECT refid FROM document)
+		AND templateid NOT IN (SELECT documentid FROM trash WHERE pageid='')`)
+	if err != nil {
+		err = errors.Wrap(err, "purge orphaned templates")
+		return
+	}
+
+	return
+}
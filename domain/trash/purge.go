@@ -0,0 +1,82 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package trash
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/trash"
+)
+
+// configKey is where trash settings are kept in the config table.
+const configKey = "TRASH"
+
+// Schedule purges trash past its retention period every hour, in the background.
+func Schedule(rt *env.Runtime, s *domain.Store) {
+	go func() {
+		for {
+			purge(rt, s)
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// purge removes items deleted longer ago than the retention period.
+func purge(rt *env.Runtime, s *domain.Store) {
+	cfg := config(*s)
+	if cfg.Retention <= 0 {
+		return
+	}
+
+	var err error
+	ctx := domain.RequestContext{}
+
+	ctx.Transaction, err = rt.Db.Beginx()
+	if err != nil {
+		rt.Log.Error("trash.purge", err)
+		return
+	}
+
+	rows, err := s.Trash.Purge(ctx, time.Now().UTC().AddDate(0, 0, -cfg.Retention))
+	if err != nil {
+		ctx.Transaction.Rollback()
+		rt.Log.Error("trash.purge", err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	if rows > 0 {
+		rt.Log.Info(fmt.Sprintf("Purged %d items from trash", rows))
+	}
+}
+
+// config returns trash settings, defaulting retention when not configured.
+func config(s domain.Store) (c trash.Config) {
+	c.Retention = trash.DefaultRetention
+
+	raw, err := s.Setting.Get(configKey, "")
+	if err != nil || len(raw) == 0 {
+		return
+	}
+
+	err = json.Unmarshal([]byte(raw), &c)
+	if err != nil {
+		c.Retention = trash.DefaultRetention
+	}
+
+	return
+}
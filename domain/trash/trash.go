@@ -0,0 +1,294 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package trash keeps deleted documents and sections so they can be
+// restored until purged.
+package trash
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/comment"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/snapshot"
+	"github.com/documize/community/model/trash"
+	"github.com/pkg/errors"
+)
+
+// Document puts a document and everything belonging to it in trash
+// within ctx.Transaction. Callers go on to delete the document.
+func Document(ctx domain.RequestContext, s domain.Store, d doc.Document) (err error) {
+	c := trash.Content{Document: &d}
+
+	pages, err := s.Page.GetPages(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	c.Pages, err = withMeta(ctx, s, d.RefID, pages)
+	if err != nil {
+		return
+	}
+
+	c.Revisions, err = s.Page.GetDocumentRevisionsSince(ctx, d.RefID, time.Time{})
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	files, err := s.Attachment.GetAttachmentsWithData(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	for _, a := range files {
+		c.Attachments = append(c.Attachments, snapshot.Attachment{Attachment: a, Data: a.Data})
+	}
+
+	c.Links, err = s.Link.GetDocumentOutboundLinks(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	inbound, err := s.Link.GetInboundLinks(ctx, "document", d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	for _, l := range inbound {
+		c.Inbound = append(c.Inbound, l.RefID)
+	}
+
+	c.Pins, err = s.Pin.GetDocumentPins(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+
+	c.Comments, err = s.Comment.GetByDocument(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	for _, cm := range c.Comments {
+		var r []comment.Revision
+		r, err = s.Comment.GetRevisions(ctx, cm.RefID)
+		if err != nil && errors.Cause(err) != sql.ErrNoRows {
+			return
+		}
+		c.CommentRevisions = append(c.CommentRevisions, r...)
+	}
+
+	c.Fields, err = s.Metadata.GetValues(ctx, d.RefID)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	err = nil
+
+	return add(ctx, s, trash.Item{LabelID: d.LabelID, DocumentID: d.RefID, Title: d.Title}, c)
+}
+
+// Pages puts document sections in trash as one item within ctx.Transaction.
+// Callers go on to delete the sections.
+func Pages(ctx domain.RequestContext, s domain.Store, d doc.Document, pageIDs []string) (err error) {
+	if len(pageIDs) == 0 {
+		return
+	}
+
+	c := trash.Content{}
+	deleted := make(map[string]bool)
+
+	pages := []page.Page{}
+	for _, id := range pageIDs {
+		var p page.Page
+		p, err = s.Page.Get(ctx, id)
+		if err != nil {
+			return
+		}
+		pages = append(pages, p)
+		deleted[id] = true
+	}
+
+	c.Pages, err = withMeta(ctx, s, d.RefID, pages)
+	if err != nil {
+		return
+	}
+
+	revisions, err := s.Page.GetDocumentRevisionsSince(ctx, d.RefID, time.Time{})
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	for _, r := range revisions {
+		if deleted[r.PageID] {
+			c.Revisions = append(c.Revisions, r)
+		}
+	}
+
+	for _, id := range pageIDs {
+		links, lerr := s.Link.GetPageLinks(ctx, d.RefID, id)
+		if lerr != nil && errors.Cause(lerr) != sql.ErrNoRows {
+			return lerr
+		}
+		c.Links = append(c.Links, links...)
+
+		inbound, lerr := s.Link.GetInboundLinks(ctx, "section", id)
+		if lerr != nil && errors.Cause(lerr) != sql.ErrNoRows {
+			return lerr
+		}
+		for _, l := range inbound {
+			c.Inbound = append(c.Inbound, l.RefID)
+		}
+	}
+
+	return add(ctx, s, trash.Item{LabelID: d.LabelID, DocumentID: d.RefID, PageID: pages[0].RefID, Title: pages[0].Title}, c)
+}
+
+// withMeta pairs pages with their meta.
+func withMeta(ctx domain.RequestContext, s domain.Store, documentID string, pages []page.Page) (np []page.NewPage, err error) {
+	meta, err := s.Page.GetDocumentPageMeta(ctx, documentID, false)
+	if err != nil && errors.Cause(err) != sql.ErrNoRows {
+		return
+	}
+	err = nil
+
+	np = []page.NewPage{}
+	for _, p := range pages {
+		n := page.NewPage{Page: p}
+		for _, m := range meta {
+			if m.PageID == p.RefID {
+				n.Meta = m
+				break
+			}
+		}
+		np = append(np, n)
+	}
+
+	return
+}
+
+// add stores deleted content.
+func add(ctx domain.RequestContext, s domain.Store, item trash.Item, c trash.Content) (err error) {
+	item.RefID = uniqueid.Generate()
+	item.Data, err = json.Marshal(c)
+	if err != nil {
+		err = errors.Wrap(err, "encode trash")
+		return
+	}
+
+	return s.Trash.Add(ctx, item)
+}
+
+// content decodes what was deleted.
+func content(item trash.Item) (c trash.Content, err error) {
+	err = json.Unmarshal(item.Data, &c)
+	if err != nil {
+		err = errors.Wrap(err, "decode trash")
+	}
+
+	return
+}
+
+// restore puts deleted content back within ctx.Transaction, keeping
+// original IDs so links, pins and bookmarks work again.
+func (h *Handler) restore(ctx domain.RequestContext, c trash.Content) (err error) {
+	if c.Document != nil {
+		d := *c.Document
+
+		err = h.Store.Document.Add(ctx, d)
+		if err != nil {
+			return
+		}
+
+		// layout is not part of insert
+		err = h.Store.Document.Update(ctx, d)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, np := range c.Pages {
+		if _, gerr := h.Store.Page.Get(ctx, np.Page.RefID); gerr == nil {
+			continue // already back
+		}
+
+		np.Meta.PageID = np.Page.RefID
+
+		err = h.Store.Page.Add(ctx, np)
+		if err != nil {
+			return
+		}
+
+		if len(np.Page.BlockID) > 0 {
+			h.Store.Block.IncrementUsage(ctx, np.Page.BlockID)
+		}
+	}
+
+	for _, r := range c.Revisions {
+		err = h.Store.Page.AddRevision(ctx, r)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, a := range c.Attachments {
+		file := a.Attachment
+		file.Data = a.Data
+
+		err = h.Store.Attachment.Add(ctx, file)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, l := range c.Links {
+		err = h.Store.Link.Add(ctx, l)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, id := range c.Inbound {
+		err = h.Store.Link.MarkLinked(ctx, id)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, p := range c.Pins {
+		err = h.Store.Pin.Add(ctx, p)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, cm := range c.Comments {
+		err = h.Store.Comment.Restore(ctx, cm)
+		if err != nil {
+			return
+		}
+	}
+
+	for _, r := range c.CommentRevisions {
+		err = h.Store.Comment.RestoreRevision(ctx, r)
+		if err != nil {
+			return
+		}
+	}
+
+	if c.Document != nil && len(c.Fields) > 0 {
+		err = h.Store.Metadata.SetValues(ctx, c.Document.RefID, c.Fields)
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,244 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package watch
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/request"
+	"github.com/documize/community/core/response"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/model/watch"
+	"github.com/pkg/errors"
+)
+
+// Handler contains the runtime information such as logging and database.
+type Handler struct {
+	Runtime *env.Runtime
+	Store   *domain.Store
+}
+
+// WatchDocument subscribes the current user to changes in a document.
+func (h *Handler) WatchDocument(w http.ResponseWriter, r *http.Request) {
+	method := "watch.watchDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	if ctx.Guest || !document.CanViewDocument(ctx, *h.Store, documentID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	h.watch(w, ctx, method, "", documentID)
+}
+
+// UnwatchDocument stops notifications for a document.
+func (h *Handler) UnwatchDocument(w http.ResponseWriter, r *http.Request) {
+	method := "watch.unwatchDocument"
+	ctx := domain.GetRequestContext(r)
+
+	documentID := request.Param(r, "documentID")
+	if len(documentID) == 0 {
+		response.WriteMissingDataError(w, method, "documentID")
+		return
+	}
+
+	h.unwatch(w, ctx, method, "", documentID)
+}
+
+// WatchSpace subscribes the current user to changes in every document of a space.
+func (h *Handler) WatchSpace(w http.ResponseWriter, r *http.Request) {
+	method := "watch.watchSpace"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	if ctx.Guest || !document.CanViewDocumentInFolder(ctx, *h.Store, spaceID) {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	h.watch(w, ctx, method, spaceID, "")
+}
+
+// UnwatchSpace stops notifications for a space.
+func (h *Handler) UnwatchSpace(w http.ResponseWriter, r *http.Request) {
+	method := "watch.unwatchSpace"
+	ctx := domain.GetRequestContext(r)
+
+	spaceID := request.Param(r, "folderID")
+	if len(spaceID) == 0 {
+		response.WriteMissingDataError(w, method, "folderID")
+		return
+	}
+
+	h.unwatch(w, ctx, method, spaceID, "")
+}
+
+// GetByUser returns documents and spaces the current user watches.
+func (h *Handler) GetByUser(w http.ResponseWriter, r *http.Request) {
+	method := "watch.getByUser"
+	ctx := domain.GetRequestContext(r)
+
+	watches, err := h.Store.Watch.GetByUser(ctx)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	if len(watches) == 0 {
+		watches = []watch.Watcher{}
+	}
+
+	response.WriteJSON(w, watches)
+}
+
+// GetPreference returns how often the current user is notified.
+func (h *Handler) GetPreference(w http.ResponseWriter, r *http.Request) {
+	ctx := domain.GetRequestContext(r)
+
+	response.WriteJSON(w, preference(*h.Store, ctx.OrgID, ctx.UserID))
+}
+
+// SetPreference chooses between immediate notifications and a daily digest.
+func (h *Handler) SetPreference(w http.ResponseWriter, r *http.Request) {
+	method := "watch.setPreference"
+	ctx := domain.GetRequestContext(r)
+
+	if ctx.Guest {
+		response.WriteForbiddenError(w)
+		return
+	}
+
+	defer streamutil.Close(r.Body)
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	p := watch.Preference{}
+	err = json.Unmarshal(body, &p)
+	if err != nil {
+		response.WriteBadRequestError(w, method, "Bad payload")
+		return
+	}
+
+	data, _ := json.Marshal(p)
+
+	err = h.Store.Setting.SetUser(ctx.OrgID, ctx.UserID, configKey, string(data))
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, p)
+}
+
+// watch adds a subscription unless the user already has one.
+func (h *Handler) watch(w http.ResponseWriter, ctx domain.RequestContext, method, spaceID, documentID string) {
+	existing, err := h.Store.Watch.Get(ctx, spaceID, documentID)
+	if err == nil {
+		response.WriteJSON(w, existing)
+		return
+	}
+	if errors.Cause(err) != sql.ErrNoRows {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	n := watch.Watcher{}
+	n.RefID = uniqueid.Generate()
+	n.LabelID = spaceID
+	n.DocumentID = documentID
+	n.UserID = ctx.UserID
+	n.RoleType = watch.RoleWatcher
+	n.AppURL = ctx.GetAppURL("")
+
+	err = h.Store.Watch.Add(ctx, n)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	n, err = h.Store.Watch.Get(ctx, spaceID, documentID)
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	response.WriteJSON(w, n)
+}
+
+// unwatch removes the current user's subscription, if any.
+func (h *Handler) unwatch(w http.ResponseWriter, ctx domain.RequestContext, method, spaceID, documentID string) {
+	existing, err := h.Store.Watch.Get(ctx, spaceID, documentID)
+	if errors.Cause(err) == sql.ErrNoRows {
+		response.WriteEmpty(w)
+		return
+	}
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction, err = h.Runtime.Db.Beginx()
+	if err != nil {
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	_, err = h.Store.Watch.Delete(ctx, existing.RefID)
+	if err != nil {
+		ctx.Transaction.Rollback()
+		response.WriteServerError(w, method, err)
+		h.Runtime.Log.Error(method, err)
+		return
+	}
+
+	ctx.Transaction.Commit()
+
+	response.WriteEmpty(w)
+}
@@ -0,0 +1,167 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package mysql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/streamutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/store/mysql"
+	"github.com/documize/community/model/watch"
+	"github.com/pkg/errors"
+)
+
+// Scope provides data access to MySQL.
+type Scope struct {
+	Runtime *env.Runtime
+}
+
+// selectWatcher includes the document or space name.
+const selectWatcher = `SELECT a.id, a.refid, a.orgid, a.labelid, a.documentid, a.userid, a.roletype, a.lastviewed, a.lastnotified, a.appurl, a.created,
+	coalesce(d.title, l.label, '') as title
+	FROM participant a
+	LEFT JOIN document d ON a.documentid=d.refid AND a.orgid=d.orgid AND a.documentid<>''
+	LEFT JOIN label l ON a.labelid=l.refid AND a.orgid=l.orgid AND a.labelid<>''`
+
+// Add subscribes a user to a document or space.
+func (s Scope) Add(ctx domain.RequestContext, w watch.Watcher) (err error) {
+	w.OrgID = ctx.OrgID
+	w.Created = time.Now().UTC()
+
+	stmt, err := ctx.Transaction.Preparex("INSERT INTO participant (refid, orgid, labelid, documentid, userid, roletype, lastviewed, appurl, created) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare insert watcher")
+		return
+	}
+
+	_, err = stmt.Exec(w.RefID, w.OrgID, w.LabelID, w.DocumentID, w.UserID, w.RoleType, w.Created, w.AppURL, w.Created)
+	if err != nil {
+		err = errors.Wrap(err, "execute insert watcher")
+		return
+	}
+
+	return
+}
+
+// Get returns the current user's subscription to a space or document.
+func (s Scope) Get(ctx domain.RequestContext, spaceID, documentID string) (w watch.Watcher, err error) {
+	stmt, err := s.Runtime.Db.Preparex(selectWatcher + " WHERE a.orgid=? AND a.userid=? AND a.labelid=? AND a.documentid=?")
+	defer streamutil.Close(stmt)
+
+	if err != nil {
+		err = errors.Wrap(err, "prepare select watcher")
+		return
+	}
+
+	err = stmt.Get(&w, ctx.OrgID, ctx.UserID, spaceID, documentID)
+	if err != nil {
+		err = errors.Wrap(err, "execute select watcher")
+		return
+	}
+
+	return
+}
+
+// GetByUser returns everything the current user watches.
+func (s Scope) GetByUser(ctx domain.RequestContext) (w []watch.Watcher, err error) {
+	err = s.Runtime.Db.Select(&w, selectWatcher+" WHERE a.orgid=? AND a.userid=? ORDER BY title", ctx.OrgID, ctx.UserID)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("select watches for user %s", ctx.UserID))
+		return
+	}
+
+	return
+}
+
+// GetAll returns subscriptions of active users across all organizations.
+func (s Scope) GetAll(ctx domain.RequestContext) (w []watch.Watcher, err error) {
+	err = s.Runtime.Db.Select(&w, `SELECT a.id, a.refid, a.orgid, a.labelid, a.documentid, a.userid, a.roletype, a.lastviewed, a.lastnotified, a.appurl, a.created,
+		coalesce(d.title, l.label, '') as title, u.email
+		FROM participant a
+		JOIN user u ON a.userid=u.refid
+		JOIN account c ON a.userid=c.userid AND a.orgid=c.orgid AND c.active=1
+		LEFT JOIN document d ON a.documentid=d.refid AND a.orgid=d.orgid AND a.documentid<>''
+		LEFT JOIN label l ON a.labelid=l.refid AND a.orgid=l.orgid AND a.labelid<>''
+		WHERE a.roletype IN (?, ?)
+		ORDER BY a.orgid, a.userid, title`, watch.RoleWatcher, watch.RoleAuthor)
+
+	if err != nil {
+		err = errors.Wrap(err, "select watchers")
+		return
+	}
+
+	return
+}
+
+// UpdateLastViewed records the current user looking at a watched space or document.
+func (s Scope) UpdateLastViewed(ctx domain.RequestContext, spaceID, documentID string) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE participant SET lastviewed=? WHERE orgid=? AND userid=? AND labelid=? AND documentid=?",
+		time.Now().UTC(), ctx.OrgID, ctx.UserID, spaceID, documentID)
+
+	if err != nil {
+		err = errors.Wrap(err, "update watcher last viewed")
+	}
+
+	return
+}
+
+// UpdateLastNotified records when the user was last sent changes.
+func (s Scope) UpdateLastNotified(ctx domain.RequestContext, id string, notified time.Time) (err error) {
+	_, err = ctx.Transaction.Exec("UPDATE participant SET lastnotified=? WHERE orgid=? AND refid=?", notified, ctx.OrgID, id)
+
+	if err != nil {
+		err = errors.Wrap(err, fmt.Sprintf("update watcher last notified %s", id))
+	}
+
+	return
+}
+
+// GetChanges returns sections added, updated or deleted after since,
+// oldest first, for a document or, when documentID is blank, a space.
+// Additions are credited to the section's author, updates to its last editor.
+func (s Scope) GetChanges(ctx domain.RequestContext, spaceID, documentID string, since time.Time) (c []watch.Change, err error) {
+	pageFilter, trashFilter, id := "d.labelid=?", "t.labelid=?", spaceID
+	if len(documentID) > 0 {
+		pageFilter, trashFilter, id = "p.documentid=?", "t.documentid=?", documentID
+	}
+
+	err = s.Runtime.Db.Select(&c, `SELECT p.documentid, d.title as documenttitle, p.refid as pageid, p.title as pagetitle,
+		IF(p.created>?, 'added', 'updated') as kind, IF(p.created>? OR p.revisedby='', coalesce(p.userid,''), p.revisedby) as userid, p.revised
+		FROM page p JOIN document d ON p.documentid=d.refid AND p.orgid=d.orgid
+		WHERE p.orgid=? AND `+pageFilter+` AND d.template=0 AND p.revised>?
+		UNION ALL
+		SELECT t.documentid, IF(t.pageid='', t.title, coalesce(d.title,'')) as documenttitle, t.pageid, IF(t.pageid='', '', t.title) as pagetitle,
+		'deleted' as kind, coalesce(t.userid,'') as userid, t.created as revised
+		FROM trash t LEFT JOIN document d ON t.documentid=d.refid AND t.orgid=d.orgid
+		WHERE t.orgid=? AND `+trashFilter+` AND t.created>?
+		ORDER BY revised`,
+		since, since, ctx.OrgID, id, since, ctx.OrgID, id, since)
+
+	if err != nil {
+		err = errors.Wrap(err, "select watched changes")
+		return
+	}
+
+	return
+}
+
+// Delete removes a subscription.
+func (s Scope) Delete(ctx domain.RequestContext, id string) (rows int64, err error) {
+	b := mysql.BaseQuery{}
+	return b.DeleteConstrained(ctx.Transaction, "participant", ctx.OrgID, id)
+}
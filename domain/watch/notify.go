@@ -0,0 +1,184 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package watch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/documize/community/core/env"
+	"github.com/documize/community/core/stringutil"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/document"
+	"github.com/documize/community/domain/mail"
+	"github.com/documize/community/model/watch"
+)
+
+// Schedule emails watchers about changes every 15 minutes, in the background.
+func Schedule(rt *env.Runtime, s *domain.Store) {
+	go func() {
+		for {
+			notify(rt, s)
+			time.Sleep(15 * time.Minute)
+		}
+	}()
+}
+
+// notify sends each watcher one email listing changes made by others
+// since they last viewed what they watch. Users who prefer a digest
+// are emailed at most once a day.
+func notify(rt *env.Runtime, s *domain.Store) {
+	watchers, err := s.Watch.GetAll(domain.RequestContext{})
+	if err != nil {
+		rt.Log.Error("watch.notify", err)
+		return
+	}
+
+	// rows are ordered by organization then user
+	for i := 0; i < len(watchers); {
+		j := i
+		for j < len(watchers) && watchers[j].OrgID == watchers[i].OrgID && watchers[j].UserID == watchers[i].UserID {
+			j++
+		}
+
+		notifyUser(rt, s, watchers[i:j])
+		i = j
+	}
+}
+
+// notifyUser emails one user about everything they watch in one organization.
+// A digest covers every subscription, so sending one stamps them all and
+// the next is due a day after that.
+func notifyUser(rt *env.Runtime, s *domain.Store, watchers []watch.Watcher) {
+	ctx := domain.RequestContext{}
+	ctx.OrgID = watchers[0].OrgID
+	ctx.UserID = watchers[0].UserID
+
+	now := time.Now().UTC()
+
+	daily := preference(*s, ctx.OrgID, ctx.UserID).Digest
+	if daily {
+		for _, w := range watchers {
+			if w.LastNotified != nil && now.Sub(*w.LastNotified) < 24*time.Hour {
+				return
+			}
+		}
+	}
+
+	digests := []watch.Digest{}
+	notified := []string{}
+
+	for _, w := range watchers {
+		d, ok := digest(rt, s, ctx, w)
+		if !ok {
+			continue
+		}
+
+		digests = append(digests, d)
+		notified = append(notified, w.RefID)
+	}
+
+	if len(digests) == 0 {
+		return
+	}
+
+	if daily {
+		notified = []string{}
+		for _, w := range watchers {
+			notified = append(notified, w.RefID)
+		}
+	}
+
+	mailer := mail.Mailer{Runtime: rt, Store: s, Context: ctx}
+	mailer.WatchDigest(watchers[0].Email, watchers[0].AppURL, digests)
+
+	var err error
+	ctx.Transaction, err = rt.Db.Beginx()
+	if err != nil {
+		rt.Log.Error("watch.notify", err)
+		return
+	}
+
+	for _, id := range notified {
+		err = s.Watch.UpdateLastNotified(ctx, id, now)
+		if err != nil {
+			ctx.Transaction.Rollback()
+			rt.Log.Error("watch.notify", err)
+			return
+		}
+	}
+
+	ctx.Transaction.Commit()
+}
+
+// digest collects changes for one subscription, reporting false when
+// there is nothing new to tell the user or they can no longer see it.
+func digest(rt *env.Runtime, s *domain.Store, ctx domain.RequestContext, w watch.Watcher) (d watch.Digest, ok bool) {
+	since := w.Created
+	if w.LastViewed != nil {
+		since = *w.LastViewed
+	}
+
+	changes, err := s.Watch.GetChanges(ctx, w.LabelID, w.DocumentID, since)
+	if err != nil {
+		rt.Log.Error("watch.digest", err)
+		return
+	}
+
+	fresh := false
+	for _, c := range changes {
+		if c.UserID == ctx.UserID {
+			continue
+		}
+
+		d.Changes = append(d.Changes, c)
+
+		if w.LastNotified == nil || c.Revised.After(*w.LastNotified) {
+			fresh = true
+		}
+	}
+
+	if !fresh {
+		return
+	}
+
+	spaceID := w.LabelID
+	if len(w.DocumentID) > 0 {
+		if !document.CanViewDocument(ctx, *s, w.DocumentID) {
+			return
+		}
+
+		doc, err := s.Document.Get(ctx, w.DocumentID)
+		if err != nil {
+			rt.Log.Error("watch.digest", err)
+			return
+		}
+
+		spaceID = doc.LabelID
+	} else if !document.CanViewDocumentInFolder(ctx, *s, w.LabelID) {
+		return
+	}
+
+	sp, err := s.Space.Get(ctx, spaceID)
+	if err != nil {
+		rt.Log.Error("watch.digest", err)
+		return
+	}
+
+	d.Title = w.Title
+	d.URL = fmt.Sprintf("%ss/%s/%s", w.AppURL, sp.RefID, stringutil.MakeSlug(sp.Name))
+	if len(w.DocumentID) > 0 {
+		d.URL = fmt.Sprintf("%s/d/%s/%s", d.URL, w.DocumentID, stringutil.MakeSlug(w.Title))
+	}
+
+	return d, true
+}
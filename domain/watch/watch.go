@@ -0,0 +1,50 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+// Package watch lets users follow documents and spaces and be emailed
+// when sections change.
+package watch
+
+import (
+	"encoding/json"
+
+	"github.com/documize/community/core/uniqueid"
+	"github.com/documize/community/domain"
+	"github.com/documize/community/model/watch"
+)
+
+// configKey is where notification preferences are kept in the userconfig table.
+const configKey = "WATCH"
+
+// Author subscribes the current user to a document they created.
+// Runs within ctx.Transaction.
+func Author(ctx domain.RequestContext, s domain.Store, documentID string) (err error) {
+	w := watch.Watcher{}
+	w.RefID = uniqueid.Generate()
+	w.DocumentID = documentID
+	w.UserID = ctx.UserID
+	w.RoleType = watch.RoleAuthor
+	w.AppURL = ctx.GetAppURL("")
+
+	return s.Watch.Add(ctx, w)
+}
+
+// preference returns how often the user wants to be notified.
+func preference(s domain.Store, orgID, userID string) (p watch.Preference) {
+	raw, err := s.Setting.GetUser(orgID, userID, configKey, "")
+	if err != nil || len(raw) == 0 {
+		return
+	}
+
+	json.Unmarshal([]byte(raw), &p)
+
+	return
+}
@@ -16,35 +16,57 @@ import (
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
 	account "github.com/documize/community/domain/account/mysql"
+	action "github.com/documize/community/domain/action/mysql"
 	activity "github.com/documize/community/domain/activity/mysql"
 	attachment "github.com/documize/community/domain/attachment/mysql"
 	audit "github.com/documize/community/domain/audit/mysql"
 	block "github.com/documize/community/domain/block/mysql"
+	comment "github.com/documize/community/domain/comment/mysql"
+	datasource "github.com/documize/community/domain/datasource/mysql"
 	doc "github.com/documize/community/domain/document/mysql"
+	feedback "github.com/documize/community/domain/feedback/mysql"
 	link "github.com/documize/community/domain/link/mysql"
+	metadata "github.com/documize/community/domain/metadata/mysql"
 	org "github.com/documize/community/domain/organization/mysql"
 	page "github.com/documize/community/domain/page/mysql"
 	pin "github.com/documize/community/domain/pin/mysql"
 	search "github.com/documize/community/domain/search/mysql"
 	setting "github.com/documize/community/domain/setting/mysql"
+	share "github.com/documize/community/domain/share/mysql"
+	snapshot "github.com/documize/community/domain/snapshot/mysql"
 	space "github.com/documize/community/domain/space/mysql"
+	tag "github.com/documize/community/domain/tag/mysql"
+	template "github.com/documize/community/domain/template/mysql"
+	trash "github.com/documize/community/domain/trash/mysql"
 	user "github.com/documize/community/domain/user/mysql"
+	watch "github.com/documize/community/domain/watch/mysql"
 )
 
 // StoreMySQL creates MySQL provider
 func StoreMySQL(r *env.Runtime, s *domain.Store) {
 	s.Account = account.Scope{Runtime: r}
+	s.Action = action.Scope{Runtime: r}
 	s.Activity = activity.Scope{Runtime: r}
 	s.Attachment = attachment.Scope{Runtime: r}
 	s.Audit = audit.Scope{Runtime: r}
 	s.Block = block.Scope{Runtime: r}
+	s.Comment = comment.Scope{Runtime: r}
+	s.DataSource = datasource.Scope{Runtime: r}
 	s.Document = doc.Scope{Runtime: r}
+	s.Feedback = feedback.Scope{Runtime: r}
 	s.Link = link.Scope{Runtime: r}
+	s.Metadata = metadata.Scope{Runtime: r}
 	s.Organization = org.Scope{Runtime: r}
 	s.Page = page.Scope{Runtime: r}
 	s.Pin = pin.Scope{Runtime: r}
 	s.Search = search.Scope{Runtime: r}
 	s.Setting = setting.Scope{Runtime: r}
+	s.Share = share.Scope{Runtime: r}
+	s.Snapshot = snapshot.Scope{Runtime: r}
 	s.Space = space.Scope{Runtime: r}
+	s.Tag = tag.Scope{Runtime: r}
+	s.Template = template.Scope{Runtime: r}
+	s.Trash = trash.Scope{Runtime: r}
 	s.User = user.Scope{Runtime: r}
+	s.Watch = watch.Scope{Runtime: r}
 }
@@ -12,6 +12,7 @@
 import Ember from 'ember';
 import NotifierMixin from '../../mixins/notifier';
 import TooltipMixin from '../../mixins/tooltip';
+import netUtil from '../../utils/net';
 
 export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
 	documentService: Ember.inject.service('document'),
@@ -39,8 +40,37 @@ export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
 		});
 	},
 
+	willDestroyElement() {
+		this._super(...arguments);
+		this.releaseLock();
+	},
+
+	// renewLock keeps our claim on the page while it is being edited.
+	renewLock() {
+		let page = this.get('page');
+
+		this.set('lockTimer', Ember.run.later(this, () => {
+			this.get('documentService').renewPageLock(page.get('documentId'), page.get('id')).then(() => {
+				if (this.get('editMode')) {
+					this.renewLock();
+				}
+			});
+		}, 5 * 60 * 1000));
+	},
+
+	releaseLock() {
+		let page = this.get('page');
+
+		Ember.run.cancel(this.get('lockTimer'));
+
+		if (this.get('editMode')) {
+			this.get('documentService').unlockPage(page.get('documentId'), page.get('id'), false);
+		}
+	},
+
 	actions: {
 		onSavePage(page, meta) {
+			Ember.run.cancel(this.get('lockTimer'));
 			this.set('page', page);
 			this.set('meta', meta);
 			this.set('editMode', false);
@@ -80,12 +110,23 @@ export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
 				return;
 			}
 
-			this.get('toEdit', '');
-			// this.set('pageId', this.get('page.id'));
-			this.set('editMode', true);
+			let page = this.get('page');
+
+			this.get('documentService').lockPage(page.get('documentId'), page.get('id')).then(() => {
+				this.get('toEdit', '');
+				// this.set('pageId', this.get('page.id'));
+				this.set('editMode', true);
+				this.renewLock();
+			}).catch((error) => {
+				if (netUtil.isAjaxLockedError(error)) {
+					let lock = error.payload;
+					this.showNotification(`Being edited by ${lock.firstname} ${lock.lastname}`);
+				}
+			});
 		},
 
 		onCancelEdit() {
+			this.releaseLock();
 			this.set('editMode', false);
 		}
 	}
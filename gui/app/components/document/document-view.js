@@ -265,7 +265,8 @@ export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
 			});
 		},
 
-		onInsertBlock(block) {
+		// Linked sections follow later changes to the block, otherwise the block is copied.
+		onInsertBlock(block, linked) {
 			let sectionName = this.get('newSectionName');
 			if (is.empty(sectionName)) {
 				$("#new-section-name").focus();
@@ -280,7 +281,8 @@ export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
 				body: block.get('body'),
 				contentType: block.get('contentType'),
 				pageType: block.get('pageType'),
-				blockId: block.get('id')
+				blockId: block.get('id'),
+				blockLinked: linked === true
 			};
 
 			let meta = {
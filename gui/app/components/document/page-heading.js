@@ -19,6 +19,7 @@ const {
 
 export default Ember.Component.extend(TooltipMixin, {
 	documentService: service('document'),
+	session: service(),
 	deleteChildren: false,
 	menuOpen: false,
 	blockTitle: "",
@@ -27,6 +28,18 @@ export default Ember.Component.extend(TooltipMixin, {
 	documentListOthers: [], //excludes the current document
 	selectedDocument: null,
 
+	lockedBy: computed('page.lock', function () {
+		let lock = this.get('page.lock');
+
+		if (is.empty(lock) || lock.userId === this.get('session.user.id')) {
+			return '';
+		}
+
+		return `${lock.firstname} ${lock.lastname}`;
+	}),
+	canBreakLock: computed('lockedBy', function () {
+		return this.get('lockedBy') !== '' && this.get('session.isAdmin');
+	}),
 	checkId: computed('page', function () {
 		let id = this.get('page.id');
 		return `delete-check-button-${id}`;
@@ -96,6 +109,14 @@ export default Ember.Component.extend(TooltipMixin, {
 			this.attrs.onEdit();
 		},
 
+		onBreakLock() {
+			let page = this.get('page');
+
+			this.get('documentService').unlockPage(page.get('documentId'), page.get('id'), true).then(() => {
+				this.set('page.lock', null);
+			});
+		},
+
 		deletePage() {
 			this.attrs.onDeletePage(this.get('deleteChildren'));
 		},
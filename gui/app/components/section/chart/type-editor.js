@@ -0,0 +1,148 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+import Ember from 'ember';
+import NotifierMixin from '../../../mixins/notifier';
+import TooltipMixin from '../../../mixins/tooltip';
+
+export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
+	sectionService: Ember.inject.service('section'),
+	documentService: Ember.inject.service('document'),
+	isDirty: false,
+	waiting: false,
+	config: {},
+	data: '',
+	preview: null,
+	attachments: [],
+	attachment: null,
+	types: [
+		{ id: 'bar', name: 'Bar' },
+		{ id: 'line', name: 'Line' },
+		{ id: 'pie', name: 'Pie' },
+		{ id: 'stacked', name: 'Stacked bar' }
+	],
+	type: null,
+	valueOptions: Ember.computed('preview.numeric', 'config.valueColumns', function () {
+		let selected = this.get('config.valueColumns') || [];
+		return (this.get('preview.numeric') || []).map((name) => {
+			return { name: name, selected: selected.indexOf(name) !== -1 };
+		});
+	}),
+
+	didReceiveAttrs() {
+		let config = {};
+
+		try {
+			config = JSON.parse(this.get('meta.config'));
+		} catch (e) {} // eslint-disable-line no-empty
+
+		if (is.empty(config)) {
+			config = {
+				type: "bar",
+				title: "",
+				labelColumn: "",
+				valueColumns: [],
+				attachmentId: ""
+			};
+		}
+
+		this.set('config', config);
+		this.set('data', this.get('meta.rawBody') || '');
+		this.set('type', _.findWhere(this.get('types'), { id: config.type }) || this.get('types')[0]);
+
+		this.get('documentService').getAttachments(this.get('document.id')).then((files) => {
+			let data = _.filter(files, (f) => { return f.extension === 'csv' || f.extension === 'json'; });
+			this.set('attachments', data);
+			this.set('attachment', _.findWhere(data, { id: config.attachmentId }) || null);
+		});
+
+		if (this.get('data').length > 0) {
+			this.send('onPreview');
+		}
+	},
+
+	willDestroyElement() {
+		this.destroyTooltips();
+	},
+
+	actions: {
+		isDirty() {
+			return this.get('isDirty');
+		},
+
+		onTypeChange(type) {
+			this.set('isDirty', true);
+			this.set('type', type);
+			this.set('config.type', type.id);
+			this.send('onPreview');
+		},
+
+		onLabelChange(column) {
+			this.set('isDirty', true);
+			this.set('config.labelColumn', column);
+			this.send('onPreview');
+		},
+
+		onToggleValue(column) {
+			let values = this.get('config.valueColumns') || [];
+			values = values.indexOf(column) !== -1 ? _.without(values, column) : values.concat([column]);
+
+			this.set('isDirty', true);
+			this.set('config.valueColumns', values);
+			this.send('onPreview');
+		},
+
+		onAttachmentChange(attachment) {
+			this.set('attachment', attachment);
+			this.set('config.attachmentId', attachment.id);
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(this.get('page'), "attachment", { config: this.get('config') })
+				.then((response) => {
+					this.set('isDirty', true);
+					this.set('data', response.data);
+					this.set('waiting', false);
+					this.send('onPreview');
+				}, () => {
+					this.set('waiting', false);
+					this.showNotification(`Unable to read attachment`);
+				});
+		},
+
+		onPreview() {
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(this.get('page'), "preview", { config: this.get('config'), data: this.get('data') })
+				.then((response) => {
+					this.set('preview', response);
+					this.set('waiting', false);
+				}, () => {
+					this.set('waiting', false);
+					this.showNotification(`Something went wrong, try again!`);
+				});
+		},
+
+		onCancel() {
+			this.attrs.onCancel();
+		},
+
+		onAction(title) {
+			let page = this.get('page');
+			let meta = this.get('meta');
+
+			page.set('title', title);
+			meta.set('config', JSON.stringify(this.get('config')));
+			meta.set('rawBody', this.get('data'));
+
+			this.attrs.onAction(page, meta);
+		}
+	}
+});
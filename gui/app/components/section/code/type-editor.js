@@ -17,6 +17,7 @@ export default Ember.Component.extend(TooltipMixin, {
     pageBody: "",
     syntaxOptions: [],
     codeSyntax: null,
+    codeConfig: null,
 	codeEditor: null,
 	editorId: Ember.computed('page', function () {
 		let page = this.get('page');
@@ -32,20 +33,30 @@ export default Ember.Component.extend(TooltipMixin, {
 
         let self = this;
         let rawBody = this.get('meta.rawBody');
-        let cleanBody = rawBody.replace("</pre>", "");
-
-        cleanBody = cleanBody.replace('<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="', "");
-        let startPos = cleanBody.indexOf('">');
-        let syntax = {
-            mode: "htmlmixed",
-            name: "HTML"
-        };
-
-        if (startPos !== -1) {
-            syntax = cleanBody.substring(0, startPos);
-            cleanBody = cleanBody.substring(startPos + 2);
+        let config = {};
+
+        try {
+            config = JSON.parse(this.get('meta.config'));
+        } catch (e) {
+            config = {};
         }
 
+        let cleanBody = rawBody;
+        let syntax = is.not.undefined(config.lang) ? config.lang : "htmlmixed";
+
+        // sections saved before server-side rendering wrap code in PRE tag
+        if (rawBody.indexOf('<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="') === 0) {
+            cleanBody = rawBody.replace("</pre>", "").replace('<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="', "");
+            let startPos = cleanBody.indexOf('">');
+
+            if (startPos !== -1) {
+                syntax = cleanBody.substring(0, startPos);
+                cleanBody = cleanBody.substring(startPos + 2);
+            }
+        }
+
+        this.set('codeConfig', config);
+
         this.set('pageBody', cleanBody);
 
         let opts = [];
@@ -104,12 +115,12 @@ export default Ember.Component.extend(TooltipMixin, {
 
     },
 
-    // Wrap code in PRE tag with language identifier for subsequent rendering.
-    getPRE() {
-        let codeSyntax = this.get("codeSyntax.mode");
-        let body = this.get('codeEditor').getDoc().getValue();
+    // Server renders highlighted HTML from raw code plus config.
+    getConfig() {
+        let config = this.get('codeConfig');
+        config.lang = this.get("codeSyntax.mode");
 
-        return `<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="${codeSyntax}">${body}</pre>`;
+        return JSON.stringify(config);
     },
 
     actions: {
@@ -133,9 +144,9 @@ export default Ember.Component.extend(TooltipMixin, {
         onAction(title) {
             let page = this.get('page');
             let meta = this.get('meta');
-            meta.set('rawBody', this.getPRE());
+            meta.set('rawBody', this.get('codeEditor').getDoc().getValue());
+            meta.set('config', this.getConfig());
             page.set('title', title);
-            page.set('body', meta.get('rawBody'));
 
             this.attrs.onAction(page, meta);
         }
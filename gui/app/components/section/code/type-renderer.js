@@ -11,75 +11,19 @@
 
 import Ember from 'ember';
 
-export default Ember.Component.extend({
-    codeBody: "",
-    codeSyntax: "htmlmixed",
-
-    didReceiveAttrs() {
-        this._super(...arguments);
-        
-        if (this.get('isDestroyed') || this.get('isDestroying')) {
-            return;
-        }
+const legacyPrefix = '<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="';
 
-        let page = this.get('page');
-        let rawBody = page.get('body');
-        let cleanBody = rawBody.replace("</pre>", "").replace('<pre class="code-mirror cm-s-solarized cm-s-dark" data-lang="', "");
-        let startPos = cleanBody.indexOf('">');
-
-        if (startPos !== -1) {
-            this.set('codeSyntax', cleanBody.substring(0, startPos));
-            this.set('codeBody', cleanBody.substring(startPos + 2));
-        }
-
-        _.each(_.sortBy(CodeMirror.modeInfo, 'name'), (item) => {
-            let i = { mode: item.mode, name: item.name };
-
-            if (item.mode === this.get('codeSyntax')) {
-                this.set('codeSyntax', i);
-            }
-        });
-    },
-
-    didInsertElement() {
-        this._super(...arguments);
-        
-        if (this.get('isDestroyed') || this.get('isDestroying')) {
-            return;
-        }
-
-        let page = this.get('page');
-        let elem = `page-${page.id}-code`;
-
-        var editor = CodeMirror.fromTextArea(document.getElementById(elem), {
-            theme: "material",
-            lineNumbers: true,
-            lineWrapping: true,
-            indentUnit: 4,
-            tabSize: 4,
-            value: "",
-            dragDrop: false,
-            readOnly: true
-        });
-
-        this.set('codeEditor', editor);
-
-        let syntax = this.get("codeSyntax");
-        if (is.not.undefined(syntax)) {
-            CodeMirror.autoLoadMode(editor, syntax.mode);
-            editor.setOption("mode", syntax.mode);
-        }
-    },
-
-    willDestroyElement() {
-        this._super(...arguments);
-
-		let editor = this.get('codeEditor');
-		if (is.not.null(editor)) {
-			editor.toTextArea();
-			editor = null;
+export default Ember.Component.extend({
+    // Sections saved before server-side rendering hold raw code
+    // wrapped in PRE tag, shown as plain text until next edit.
+    codeBody: Ember.computed('page.body', function () {
+        let body = this.get('page.body');
+
+        if (body.indexOf(legacyPrefix) === 0) {
+            let code = body.substring(body.indexOf('">') + 2).replace("</pre>", "");
+            return Ember.String.htmlSafe(`<pre>${Ember.Handlebars.Utils.escapeExpression(code)}</pre>`);
         }
 
-        this.set('codeEditor', null);
-    }
+        return Ember.String.htmlSafe(body);
+    })
 });
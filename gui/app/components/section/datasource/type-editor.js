@@ -0,0 +1,149 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+import Ember from 'ember';
+import NotifierMixin from '../../../mixins/notifier';
+import TooltipMixin from '../../../mixins/tooltip';
+import SectionMixin from '../../../mixins/section';
+
+export default Ember.Component.extend(SectionMixin, NotifierMixin, TooltipMixin, {
+	sectionService: Ember.inject.service('section'),
+	isDirty: false,
+	waiting: false,
+	config: {},
+	sources: [],
+	source: null,
+	params: '',
+	result: null,
+	displays: [
+		{ id: 'table', name: 'Table' },
+		{ id: 'bar', name: 'Bar chart' },
+		{ id: 'line', name: 'Line chart' }
+	],
+	display: null,
+
+	didReceiveAttrs() {
+		let config = {};
+
+		try {
+			config = JSON.parse(this.get('meta.config'));
+		} catch (e) {} // eslint-disable-line no-empty
+
+		if (is.empty(config)) {
+			config = {
+				dataSourceId: "",
+				query: "",
+				params: [],
+				maxRows: 50,
+				display: "table",
+				title: "",
+				labelColumn: "",
+				valueColumns: []
+			};
+		}
+
+		this.set('config', config);
+		this.set('params', (config.params || []).join('\n'));
+		this.set('display', _.findWhere(this.get('displays'), { id: config.display }) || this.get('displays')[0]);
+
+		let self = this;
+		this.set('waiting', true);
+
+		this.get('sectionService').fetch(this.get('page'), "sources", {})
+			.then(function (response) {
+				self.set('sources', response);
+				self.set('source', _.findWhere(response, { id: config.dataSourceId }) || response[0]);
+				self.set('waiting', false);
+			}, function (reason) { // eslint-disable-line no-unused-vars
+				self.set('waiting', false);
+				self.showNotification(`Unable to load data sources`);
+			});
+	},
+
+	willDestroyElement() {
+		this.destroyTooltips();
+	},
+
+	// prepareConfig copies form values into config.
+	prepareConfig() {
+		let config = this.get('config');
+		let source = this.get('source');
+		let params = this.get('params').split('\n').filter(function (p) { return p.length > 0; });
+
+		Ember.set(config, 'dataSourceId', is.null(source) || is.undefined(source) ? '' : source.id);
+		Ember.set(config, 'params', params);
+		Ember.set(config, 'display', this.get('display').id);
+		Ember.set(config, 'maxRows', parseInt(config.maxRows) || 0);
+
+		if (is.string(config.valueColumns)) {
+			Ember.set(config, 'valueColumns', config.valueColumns.split(',').map(function (c) { return c.trim(); }));
+		}
+
+		return config;
+	},
+
+	actions: {
+		isDirty() {
+			return this.get('isDirty');
+		},
+
+		onSourceChange(source) {
+			this.set('isDirty', true);
+			this.set('source', source);
+		},
+
+		onDisplayChange(display) {
+			this.set('isDirty', true);
+			this.set('display', display);
+		},
+
+		onPreview() {
+			let self = this;
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(this.get('page'), "preview", this.prepareConfig())
+				.then(function (response) {
+					self.set('result', response);
+					self.set('waiting', false);
+				}, function (reason) { // eslint-disable-line no-unused-vars
+					self.set('waiting', false);
+					self.showNotification(`Something went wrong, try again!`);
+				});
+		},
+
+		onCancel() {
+			this.attrs.onCancel();
+		},
+
+		onAction(title) {
+			let self = this;
+			let page = this.get('page');
+			let meta = this.get('meta');
+			let config = this.prepareConfig();
+
+			page.set('title', title);
+			meta.set('externalSource', true);
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(page, "preview", config)
+				.then(function (response) {
+					meta.set('config', JSON.stringify(config));
+					meta.set('rawBody', JSON.stringify(response));
+
+					self.set('waiting', false);
+					self.attrs.onAction(page, meta);
+				}, function (reason) { // eslint-disable-line no-unused-vars
+					self.set('waiting', false);
+					self.showNotification(`Something went wrong, try again!`);
+				});
+		}
+	}
+});
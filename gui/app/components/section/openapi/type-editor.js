@@ -0,0 +1,81 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+import Ember from 'ember';
+import NotifierMixin from '../../../mixins/notifier';
+import TooltipMixin from '../../../mixins/tooltip';
+
+export default Ember.Component.extend(NotifierMixin, TooltipMixin, {
+	sectionService: Ember.inject.service('section'),
+	waiting: false,
+	spec: '',
+	original: '',
+	result: null,
+
+	didReceiveAttrs() {
+		let spec = this.get('meta.rawBody') || '';
+		this.set('spec', spec);
+		this.set('original', spec);
+	},
+
+	willDestroyElement() {
+		this.destroyTooltips();
+	},
+
+	validate() {
+		this.set('waiting', true);
+
+		return this.get('sectionService').fetch(this.get('page'), "validate", { spec: this.get('spec') })
+			.then((response) => {
+				this.set('result', response);
+				this.set('waiting', false);
+				return response;
+			}, (reason) => {
+				this.set('waiting', false);
+				throw reason;
+			});
+	},
+
+	actions: {
+		isDirty() {
+			return this.get('spec') !== this.get('original');
+		},
+
+		onValidate() {
+			this.validate().catch(() => {
+				this.showNotification(`Something went wrong, try again!`);
+			});
+		},
+
+		onCancel() {
+			this.attrs.onCancel();
+		},
+
+		onAction(title) {
+			let page = this.get('page');
+			let meta = this.get('meta');
+
+			this.validate().then((response) => {
+				if (!response.valid) {
+					this.showNotification(`Please fix the specification problems`);
+					return;
+				}
+
+				page.set('title', title);
+				meta.set('rawBody', this.get('spec'));
+
+				this.attrs.onAction(page, meta);
+			}).catch(() => {
+				this.showNotification(`Something went wrong, try again!`);
+			});
+		}
+	}
+});
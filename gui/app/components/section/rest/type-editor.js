@@ -0,0 +1,125 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+import Ember from 'ember';
+import NotifierMixin from '../../../mixins/notifier';
+import TooltipMixin from '../../../mixins/tooltip';
+import SectionMixin from '../../../mixins/section';
+import stringUtil from '../../../utils/string';
+
+export default Ember.Component.extend(SectionMixin, NotifierMixin, TooltipMixin, {
+	sectionService: Ember.inject.service('section'),
+	isDirty: false,
+	waiting: false,
+	config: {},
+	methods: ['GET', 'POST'],
+	isPost: Ember.computed.equal('config.method', 'POST'),
+	preview: null,
+
+	didReceiveAttrs() {
+		let config = {};
+
+		try {
+			config = JSON.parse(this.get('meta.config'));
+		} catch (e) {} // eslint-disable-line no-empty
+
+		if (is.empty(config)) {
+			config = {
+				url: "",
+				method: "GET",
+				body: "",
+				headers: [],
+				query: "",
+				template: "",
+				secretKey: stringUtil.makeId(16)
+			};
+		}
+
+		this.set('config', config);
+	},
+
+	willDestroyElement() {
+		this.destroyTooltips();
+	},
+
+	// hideSecrets replaces secret header values once they have been sent to the host.
+	hideSecrets() {
+		this.get('config.headers').forEach(function (h) {
+			if (h.secret) {
+				Ember.set(h, 'value', '********');
+			}
+		});
+	},
+
+	actions: {
+		isDirty() {
+			return this.get('isDirty');
+		},
+
+		onMethodChange(method) {
+			this.set('isDirty', true);
+			this.set('config.method', method);
+		},
+
+		onAddHeader() {
+			this.set('isDirty', true);
+			this.get('config.headers').pushObject({ name: '', value: '', secret: false });
+		},
+
+		onRemoveHeader(header) {
+			this.set('isDirty', true);
+			this.get('config.headers').removeObject(header);
+		},
+
+		onPreview() {
+			let self = this;
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(this.get('page'), "preview", this.get('config'))
+				.then(function (response) {
+					self.hideSecrets();
+					self.set('preview', response);
+					self.set('waiting', false);
+				}, function (reason) { // eslint-disable-line no-unused-vars
+					self.set('waiting', false);
+					self.showNotification(`Something went wrong, try again!`);
+				});
+		},
+
+		onCancel() {
+			this.attrs.onCancel();
+		},
+
+		onAction(title) {
+			let self = this;
+			let page = this.get('page');
+			let meta = this.get('meta');
+			let config = this.get('config');
+
+			page.set('title', title);
+			meta.set('externalSource', true);
+			this.set('waiting', true);
+
+			this.get('sectionService').fetch(page, "preview", config)
+				.then(function (response) {
+					self.hideSecrets();
+					meta.set('config', JSON.stringify(config));
+					meta.set('rawBody', JSON.stringify(response.data));
+
+					self.set('waiting', false);
+					self.attrs.onAction(page, meta);
+				}, function (reason) { // eslint-disable-line no-unused-vars
+					self.set('waiting', false);
+					self.showNotification(`Something went wrong, try again!`);
+				});
+		}
+	}
+});
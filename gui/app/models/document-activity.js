@@ -55,6 +55,18 @@ export default Model.extend({
 			case constants.UserActivityType.PublishedBlock:
 				label = 'Published Block';
 				break;
+			case constants.UserActivityType.Feedback:
+				label = 'Feedback';
+				break;
+			case constants.UserActivityType.Rejected:
+				label = 'Rejected';
+				break;
+			case constants.UserActivityType.ApprovalRequested:
+				label = 'Requested Approval';
+				break;
+			case constants.UserActivityType.Commented:
+				label = 'Commented';
+				break;
 			default:
 				break;
 		}
@@ -93,6 +105,18 @@ export default Model.extend({
 			case constants.UserActivityType.PublishedBlock:
 				color = 'color-blue';
 				break;
+			case constants.UserActivityType.Feedback:
+				color = 'color-blue';
+				break;
+			case constants.UserActivityType.Rejected:
+				color = 'color-red';
+				break;
+			case constants.UserActivityType.ApprovalRequested:
+				color = 'color-blue';
+				break;
+			case constants.UserActivityType.Commented:
+				color = 'color-blue';
+				break;
 			default:
 				break;
 		}
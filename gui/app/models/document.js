@@ -26,6 +26,9 @@ export default Model.extend({
 	tags: attr('string'),
 	template: attr('boolean'),
 	layout: attr('string'),
+	lifecycle: attr('number', { defaultValue: 1 }), // 0 draft, 1 live, 2 archived
+	approvals: attr('number', { defaultValue: 0 }), // needed before going live
+	fields: attr(), // metadata field values keyed by field name
 
 	// client-side property
 	selected: attr('boolean', { defaultValue: false }),
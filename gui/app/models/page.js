@@ -22,11 +22,14 @@ export default Model.extend({
 	level: attr('number', { defaultValue: 1 }),
 	sequence: attr('number', { defaultValue: 0 }),
 	revisions: attr('number', { defaultValue: 0 }),
+	version: attr('number', { defaultValue: 0 }),
 	blockId: attr('string'),
+	blockLinked: attr('boolean', { defaultValue: false }),
 	title: attr('string'),
 	body: attr('string'),
 	rawBody: attr('string'),
 	meta: attr(),
+	lock: attr(),
 
 	tagName: Ember.computed('level', function () {
 		return "h2";
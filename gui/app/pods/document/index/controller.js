@@ -11,6 +11,7 @@
 
 import Ember from 'ember';
 import NotifierMixin from '../../../mixins/notifier';
+import netUtil from '../../../utils/net';
 
 export default Ember.Controller.extend(NotifierMixin, {
 	documentService: Ember.inject.service('document'),
@@ -69,10 +70,15 @@ export default Ember.Controller.extend(NotifierMixin, {
 
 			this.get('documentService').updatePage(documentId, page.get('id'), model).then((up) => {
 				page = up;
+				this.get('documentService').unlockPage(documentId, page.get('id'), false);
 				this.set('pageId', page.get('id'));
 				this.get('linkService').getDocumentLinks(this.get('model.document.id')).then((links) => {
 					this.set('model.links', links);
 				});
+			}).catch((error) => {
+				if (netUtil.isAjaxConflictError(error)) {
+					this.showNotification("Changed by someone else, reload to see their edits");
+				}
 			});
 		},
 
@@ -95,7 +101,8 @@ export default Ember.Controller.extend(NotifierMixin, {
 
 				pendingChanges.push({
 					pageId: pages[i].get('id'),
-					level: pages[i].get('level') - 1
+					level: pages[i].get('level') - 1,
+					version: pages[i].get('version')
 				});
 			}
 
@@ -186,14 +193,15 @@ export default Ember.Controller.extend(NotifierMixin, {
 		},
 
 		onPageSequenceChange(changes) {
-			this.get('documentService').changePageSequence(this.get('model.document.id'), changes).then(() => {
-				_.each(changes, (change) => {
+			this.get('documentService').changePageSequence(this.get('model.document.id'), changes).then((changed) => {
+				_.each(changed, (change) => {
 					let pageContent = _.findWhere(this.get('model.pages'), {
 						id: change.pageId
 					});
 
 					if (is.not.undefined(pageContent)) {
 						pageContent.set('sequence', change.sequence);
+						pageContent.set('version', change.version);
 					}
 				});
 
@@ -203,14 +211,15 @@ export default Ember.Controller.extend(NotifierMixin, {
 		},
 
 		onPageLevelChange(changes) {
-			this.get('documentService').changePageLevel(this.get('model.document.id'), changes).then(() => {
-				_.each(changes, (change) => {
+			this.get('documentService').changePageLevel(this.get('model.document.id'), changes).then((changed) => {
+				_.each(changed, (change) => {
 					let pageContent = _.findWhere(this.get('model.pages'), {
 						id: change.pageId
 					});
 
 					if (is.not.undefined(pageContent)) {
 						pageContent.set('level', change.level);
+						pageContent.set('version', change.version);
 					}
 				});
 
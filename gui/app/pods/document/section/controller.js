@@ -11,6 +11,7 @@
 
 import Ember from 'ember';
 import NotifierMixin from '../../../mixins/notifier';
+import netUtil from '../../../utils/net';
 
 export default Ember.Controller.extend(NotifierMixin, {
 	documentService: Ember.inject.service('document'),
@@ -43,6 +44,10 @@ export default Ember.Controller.extend(NotifierMixin, {
 					this.get('model.document.id'),
 					this.get('model.document.slug'), 
 					{ queryParams: { pageId: page.get('id')}});
+			}).catch((error) => {
+				if (netUtil.isAjaxConflictError(error)) {
+					this.showNotification("Changed by someone else, reload to see their edits");
+				}
 			});
 		},
 	}
@@ -19,6 +19,7 @@ export default Ember.Controller.extend({
 	matchContent: true,
 	matchFile: false,
 	matchTag: false,
+	matchArchived: false,
 
 	onKeywordChange: function () {
 		Ember.run.debounce(this, this.fetch, 750);
@@ -36,6 +37,9 @@ export default Ember.Controller.extend({
 	onMatchFile: function () {
 		Ember.run.debounce(this, this.fetch, 750);
 	}.observes('matchFile'),
+	onMatchArchived: function () {
+		Ember.run.debounce(this, this.fetch, 750);
+	}.observes('matchArchived'),
 
 	fetch() {
 		let self = this;
@@ -44,7 +48,8 @@ export default Ember.Controller.extend({
 			doc: this.get('matchDoc'),
 			attachment: this.get('matchFile'),
 			tag: this.get('matchTag'),
-			content: this.get('matchContent')
+			content: this.get('matchContent'),
+			archived: this.get('matchArchived')
 		};
 
 		payload.keywords = payload.keywords.trim();
@@ -0,0 +1,42 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+import Ember from 'ember';
+
+// Shared documents are read by people without an account, so this route
+// is not authenticated and only uses the public share endpoints.
+export default Ember.Route.extend({
+	documentService: Ember.inject.service('document'),
+	appMeta: Ember.inject.service(),
+
+	model(params) {
+		return this.get('documentService').getShared(params.secret).then((shared) => {
+			let endpoint = this.get('appMeta.endpoint');
+
+			shared.attachments = (shared.attachments || []).map((a) => {
+				a.url = `${endpoint}/public/shared/${params.secret}/attachments/${a.id}`;
+				return a;
+			});
+
+			return shared;
+		}).catch(() => {
+			return { unavailable: true };
+		});
+	},
+
+	setupController(controller, model) {
+		controller.set('model', model);
+
+		if (!model.unavailable) {
+			this.browser.setTitle(model.document.name);
+		}
+	}
+});
@@ -107,6 +107,10 @@ export default Router.map(function () {
 		path: 'widgets'
 	});
 
+	this.route('shared', {
+		path: 'shared/:secret'
+	});
+
 	this.route('not-found', {
 		path: '/*wildcard'
 	});
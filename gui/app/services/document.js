@@ -33,9 +33,15 @@ export default Ember.Service.extend({
 		});
 	},
 
-	// Returns all documents for specified folder.
-	getAllByFolder(folderId) {
-		return this.get('ajax').request(`documents?folder=${folderId}`, {
+	// Returns all documents for specified folder, optionally only those whose
+	// metadata fields match filters keyed by field name (ranges as 'from..to').
+	getAllByFolder(folderId, filters) {
+		let url = `documents?folder=${folderId}`;
+		_.each(filters || {}, (value, name) => {
+			url += `&field.${name}=${encodeURIComponent(value)}`;
+		});
+
+		return this.get('ajax').request(url, {
 			method: "GET"
 		}).then((response) => {
 			let documents = Ember.ArrayProxy.create({
@@ -79,6 +85,37 @@ export default Ember.Service.extend({
 		});
 	},
 
+	moveToFolder(documentId, folderId) {
+		return this.get('ajax').request(`documents/${documentId}/move/${folderId}`, {
+			method: 'POST'
+		}).then((response) => {
+			let data = this.get('store').normalize('document', response);
+			return this.get('store').push(data);
+		});
+	},
+
+	// Copies the document with its sections and attachments, and optionally section history.
+	duplicate(documentId, folderId, title, revisions) {
+		return this.get('ajax').request(`documents/${documentId}/duplicate/${folderId}`, {
+			method: 'POST',
+			data: JSON.stringify({ title: title || '', revisions: revisions === true })
+		}).then((response) => {
+			let data = this.get('store').normalize('document', response);
+			return this.get('store').push(data);
+		});
+	},
+
+	// Moves document between draft (0), live (1) and archived (2).
+	setLifecycle(documentId, lifecycle) {
+		return this.get('ajax').request(`documents/${documentId}/lifecycle`, {
+			method: 'PUT',
+			data: JSON.stringify({ lifecycle: lifecycle })
+		}).then((response) => {
+			let data = this.get('store').normalize('document', response);
+			return this.get('store').push(data);
+		});
+	},
+
 	getBatchedPages: function (documentId, payload) {
 		let url = `documents/${documentId}/pages/batch`;
 
@@ -166,6 +203,30 @@ export default Ember.Service.extend({
 		});
 	},
 
+	// Claims a page for editing, renewing the lock if already held.
+	lockPage(documentId, pageId) {
+		let url = `documents/${documentId}/pages/${pageId}/lock`;
+
+		return this.get('ajax').post(url);
+	},
+
+	renewPageLock(documentId, pageId) {
+		let url = `documents/${documentId}/pages/${pageId}/lock`;
+
+		return this.get('ajax').request(url, {
+			method: 'PUT'
+		});
+	},
+
+	// Releases a page lock, force lets administrators break other users' locks.
+	unlockPage(documentId, pageId, force) {
+		let url = `documents/${documentId}/pages/${pageId}/lock?force=${force ? 'true' : 'false'}`;
+
+		return this.get('ajax').request(url, {
+			method: 'DELETE'
+		});
+	},
+
 	getDocumentRevisions(documentId) {
 		let url = `documents/${documentId}/revisions`;
 
@@ -203,6 +264,264 @@ export default Ember.Service.extend({
 		});
 	},
 
+	// Turns a section linked to a reusable block into an independent copy.
+	detachPage(documentId, pageId) {
+		let url = `documents/${documentId}/pages/${pageId}/detach`;
+
+		return this.get('ajax').request(url, {
+			method: "POST"
+		});
+	},
+
+	// Sections as a nested outline, optionally with outline numbers (1, 1.1, 1.2).
+	getTree(documentId, numbered) {
+		let url = `documents/${documentId}/tree?numbered=${numbered === true}`;
+
+		return this.get('ajax').request(url, {
+			method: "GET"
+		});
+	},
+
+	// Moves a section with its subsections after another section ('' for the start,
+	// undefined to stay put) and indents or outdents them by the given levels.
+	moveSubtree(documentId, pageId, version, after, indent) {
+		let payload = {
+			pageId: pageId,
+			version: version,
+			after: _.isUndefined(after) ? null : after,
+			indent: indent || 0
+		};
+
+		return this.get('ajax').request(`documents/${documentId}/tree/move`, {
+			method: "POST",
+			data: JSON.stringify(payload)
+		});
+	},
+
+	renormalizeSections(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/tree/renormalize`, {
+			method: "POST"
+		});
+	},
+
+	// Table of contents as HTML linking to each section.
+	getContents(documentId, numbered) {
+		let url = `documents/${documentId}/contents?numbered=${numbered === true}`;
+
+		return this.get('ajax').request(url, {
+			method: "GET"
+		}).then((response) => {
+			return response.contents;
+		});
+	},
+
+	// Whole document changes between two points: 'current', 'lastvisit',
+	// a snapshot id, or a date. To defaults to the current document.
+	getDocumentDiff(documentId, from, to) {
+		let url = `documents/${documentId}/diff?from=${encodeURIComponent(from)}`;
+		if (is.not.undefined(to)) {
+			url += `&to=${encodeURIComponent(to)}`;
+		}
+
+		return this.get('ajax').request(url, {
+			method: "GET"
+		});
+	},
+
+	// Named versions of the whole document, newest first.
+	getSnapshots(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots`, {
+			method: "GET"
+		});
+	},
+
+	addSnapshot(documentId, name, note) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots`, {
+			method: "POST",
+			data: JSON.stringify({ name: name, note: note })
+		});
+	},
+
+	getSnapshot(documentId, snapshotId) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots/${snapshotId}`, {
+			method: "GET"
+		});
+	},
+
+	// otherId can be 'current' to compare against the live document.
+	getSnapshotDiff(documentId, snapshotId, otherId) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots/${snapshotId}/diff/${otherId}`, {
+			method: "GET"
+		});
+	},
+
+	restoreSnapshot(documentId, snapshotId) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots/${snapshotId}/restore`, {
+			method: "POST"
+		});
+	},
+
+	deleteSnapshot(documentId, snapshotId) {
+		return this.get('ajax').request(`documents/${documentId}/snapshots/${snapshotId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Approval requests and responses, newest first.
+	getApprovals(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/approvals`, {
+			method: "GET"
+		});
+	},
+
+	// Asks users to approve the document. Required, when given, sets how
+	// many approvals the document needs before it can go live.
+	requestApproval(documentId, users, due, note, required) {
+		let payload = { users: users, due: due, note: note };
+		if (is.not.undefined(required)) {
+			payload.required = required;
+		}
+
+		return this.get('ajax').request(`documents/${documentId}/approvals`, {
+			method: "POST",
+			data: JSON.stringify(payload)
+		});
+	},
+
+	// Actions the current user has been asked to take.
+	getPendingActions() {
+		return this.get('ajax').request(`actions`, {
+			method: "GET"
+		});
+	},
+
+	respondToAction(actionId, approved, note) {
+		return this.get('ajax').request(`actions/${actionId}`, {
+			method: "PUT",
+			data: JSON.stringify({ approved: approved, note: note })
+		});
+	},
+
+	cancelAction(actionId) {
+		return this.get('ajax').request(`actions/${actionId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Comment threads with replies, optionally for a single section.
+	getComments(documentId, pageId) {
+		let url = `documents/${documentId}/comments`;
+		if (is.not.undefined(pageId)) {
+			url += `?page=${pageId}`;
+		}
+
+		return this.get('ajax').request(url, {
+			method: "GET"
+		});
+	},
+
+	// Starts a thread on a section. Anchor is the selected text, if any,
+	// and anchorStart its character offset within the section.
+	addComment(documentId, pageId, body, mentions, anchor, anchorStart) {
+		return this.get('ajax').request(`documents/${documentId}/comments`, {
+			method: "POST",
+			data: JSON.stringify({ pageId: pageId, body: body, mentions: mentions || [], anchor: anchor || '', anchorStart: is.number(anchorStart) ? anchorStart : -1 })
+		});
+	},
+
+	replyToComment(commentId, body, mentions) {
+		return this.get('ajax').request(`comments/${commentId}/replies`, {
+			method: "POST",
+			data: JSON.stringify({ body: body, mentions: mentions || [] })
+		});
+	},
+
+	updateComment(commentId, body, mentions) {
+		return this.get('ajax').request(`comments/${commentId}`, {
+			method: "PUT",
+			data: JSON.stringify({ body: body, mentions: mentions || [] })
+		});
+	},
+
+	getCommentRevisions(commentId) {
+		return this.get('ajax').request(`comments/${commentId}/revisions`, {
+			method: "GET"
+		});
+	},
+
+	resolveComment(commentId) {
+		return this.get('ajax').request(`comments/${commentId}/resolve`, {
+			method: "POST"
+		});
+	},
+
+	reopenComment(commentId) {
+		return this.get('ajax').request(`comments/${commentId}/reopen`, {
+			method: "POST"
+		});
+	},
+
+	deleteComment(commentId) {
+		return this.get('ajax').request(`comments/${commentId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Rates the document, or one section when pageId is given.
+	addFeedback(documentId, pageId, helpful, feedback, email) {
+		return this.get('ajax').request(`documents/${documentId}/feedback`, {
+			method: "POST",
+			data: JSON.stringify({ pageId: pageId || '', helpful: helpful, feedback: feedback || '', email: email || '' })
+		});
+	},
+
+	getFeedback(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/feedback`, {
+			method: "GET"
+		});
+	},
+
+	// Emails the user when sections of the document change.
+	watch(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/watch`, {
+			method: "POST"
+		});
+	},
+
+	unwatch(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/watch`, {
+			method: "DELETE"
+		});
+	},
+
+	// Read-only links for people without an account.
+	getShares(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/shares`, {
+			method: "GET"
+		});
+	},
+
+	// Expires is optional, leave empty for links that never expire.
+	addShare(documentId, email, message, expires) {
+		return this.get('ajax').request(`documents/${documentId}/shares`, {
+			method: "POST",
+			data: JSON.stringify({ email: email || '', message: message || '', expires: expires || null })
+		});
+	},
+
+	revokeShare(shareId) {
+		return this.get('ajax').request(`shares/${shareId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Shared document with sections and attachments, fetched using the link secret.
+	getShared(secret) {
+		return this.get('ajax').request(`public/shared/${secret}`, {
+			method: "GET"
+		});
+	},
+
 	// document meta referes to number of views, edits, approvals, etc.
 	getActivity(documentId) {
 		return this.get('ajax').request(`documents/${documentId}/activity`, {
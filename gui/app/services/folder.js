@@ -165,6 +165,130 @@ export default BaseService.extend({
 		});
 	},
 
+	// Archives listed documents, or every document in the folder when none are given.
+	archive(folderId, documentIds) {
+		return this.get('ajax').post(`folders/${folderId}/archive`, {
+			contentType: 'json',
+			data: JSON.stringify({ documents: documentIds || [] })
+		});
+	},
+
+	// Moves listed documents, or all when none are given, into another space.
+	moveDocuments(folderId, targetFolderId, documentIds) {
+		return this.get('ajax').post(`folders/${folderId}/move/${targetFolderId}`, {
+			contentType: 'json',
+			data: JSON.stringify({ documents: documentIds || [] })
+		});
+	},
+
+	// Helpful and not helpful totals per document and section, least helpful first.
+	getFeedbackSummary(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/feedback`, {
+			method: "GET"
+		});
+	},
+
+	// Emails the user when documents in the space change.
+	watch(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/watch`, {
+			method: "POST"
+		});
+	},
+
+	unwatch(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/watch`, {
+			method: "DELETE"
+		});
+	},
+
+	// Metadata fields (text, number, date, user, enum) documents in the space can hold.
+	getFields(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/fields`, {
+			method: "GET"
+		});
+	},
+
+	addField(folderId, field) {
+		return this.get('ajax').request(`folders/${folderId}/fields`, {
+			method: "POST",
+			data: JSON.stringify(field)
+		});
+	},
+
+	updateField(folderId, field) {
+		return this.get('ajax').request(`folders/${folderId}/fields/${field.id}`, {
+			method: "PUT",
+			data: JSON.stringify(field)
+		});
+	},
+
+	deleteField(folderId, fieldId) {
+		return this.get('ajax').request(`folders/${folderId}/fields/${fieldId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Curated tags documents in the space can be given; empty allows any tag.
+	getTagVocabulary(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/tags`, {
+			method: "GET"
+		});
+	},
+
+	addVocabularyTag(folderId, name) {
+		return this.get('ajax').request(`folders/${folderId}/tags`, {
+			method: "POST",
+			data: JSON.stringify({ name: name })
+		});
+	},
+
+	deleteVocabularyTag(folderId, tagId) {
+		return this.get('ajax').request(`folders/${folderId}/tags/${tagId}`, {
+			method: "DELETE"
+		});
+	},
+
+	// Tags with document counts; folderId is optional and limits counts to a space.
+	getTagUsage(folderId) {
+		let url = is.empty(folderId) ? `tags` : `tags?folder=${folderId}`;
+
+		return this.get('ajax').request(url, {
+			method: "GET"
+		});
+	},
+
+	// Renaming and merging across all spaces needs an administrator when folderId is empty.
+	renameTag(from, to, folderId) {
+		return this.get('ajax').post(`tags/rename`, {
+			data: JSON.stringify({ from: from, to: to, folderId: folderId }),
+			contentType: 'json'
+		});
+	},
+
+	mergeTags(tags, into, folderId) {
+		return this.get('ajax').post(`tags/merge`, {
+			data: JSON.stringify({ tags: tags, into: into, folderId: folderId }),
+			contentType: 'json'
+		});
+	},
+
+	// Deleted documents and sections that can still be restored.
+	getTrash(folderId) {
+		return this.get('ajax').request(`folders/${folderId}/trash`, {
+			method: "GET"
+		});
+	},
+
+	restoreTrash(itemId) {
+		return this.get('ajax').post(`trash/${itemId}/restore`);
+	},
+
+	purgeTrash(itemId) {
+		return this.get('ajax').request(`trash/${itemId}`, {
+			method: "DELETE"
+		});
+	},
+
 	// Current folder caching
 	setCurrentFolder(folder) {
 		if (is.undefined(folder) || is.null(folder)) {
@@ -86,6 +86,25 @@ export default Ember.Service.extend({
 		}
 	},
 
+	getTrashConfig() {
+		if(this.get('sessionService.isGlobalAdmin')) {
+			return this.get('ajax').request(`global/trash`, {
+				method: 'GET'
+			}).then((response) => {
+				return response;
+			});
+		}
+	},
+
+	saveTrashConfig(config) {
+		if(this.get('sessionService.isGlobalAdmin')) {
+			return this.get('ajax').request(`global/trash`, {
+				method: 'PUT',
+				data: JSON.stringify(config)
+			});
+		}
+	},
+
 	syncExternalUsers() {
 		if(this.get('sessionService.isAdmin')) {
 			return this.get('ajax').request(`users/sync`, {
@@ -20,13 +20,18 @@ export default Ember.Service.extend({
 	ajax: service(),
 	store: service(),
 
-	importSavedTemplate: function (folderId, templateId, docName) {
+	// Values are only needed when the template declares variables.
+	importSavedTemplate: function (folderId, templateId, docName, values) {
 		let url = `templates/${templateId}/folder/${folderId}?type=saved`;
+		let options = { method: 'POST', data: docName };
 
-		return this.get('ajax').request(url, {
-			method: 'POST',
-			data: docName
-		}).then((doc) => {
+		// Variable values are sent as JSON; a plain title is sent as is.
+		if (is.not.undefined(values)) {
+			options.data = JSON.stringify({ title: docName, values: values });
+			options.contentType = 'application/json';
+		}
+
+		return this.get('ajax').request(url, options).then((doc) => {
 			let data = this.get('store').normalize('document', doc);
 			return this.get('store').push(data);
 		});
@@ -63,5 +68,67 @@ export default Ember.Service.extend({
 			method: 'POST',
 			data: JSON.stringify(payload)
 		}).then(() => {});
+	},
+
+	// Variables prompted for when the template is used.
+	getVariables(templateId) {
+		return this.get('ajax').request(`templates/${templateId}/variables`, {
+			method: 'GET'
+		});
+	},
+
+	saveVariables(templateId, variables) {
+		return this.get('ajax').request(`templates/${templateId}/variables`, {
+			method: 'PUT',
+			data: JSON.stringify(variables)
+		});
+	},
+
+	// Template a document was created from and the variable values given.
+	getOrigin(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/template`, {
+			method: 'GET'
+		});
+	},
+
+	getVersions(templateId) {
+		return this.get('ajax').request(`templates/${templateId}/versions`, {
+			method: 'GET'
+		});
+	},
+
+	// Releases a new version, offering its changes to documents created from earlier ones.
+	addVersion(templateId, note) {
+		return this.get('ajax').request(`templates/${templateId}/versions`, {
+			method: 'POST',
+			data: JSON.stringify({ note: note })
+		});
+	},
+
+	// Documents created from the template, optionally at one version.
+	getDerived(templateId, version) {
+		let url = `templates/${templateId}/documents`;
+		if (!_.isUndefined(version)) {
+			url += `?version=${version}`;
+		}
+
+		return this.get('ajax').request(url, {
+			method: 'GET'
+		});
+	},
+
+	// Section additions and changes proposed from the latest template version.
+	getPatches(documentId) {
+		return this.get('ajax').request(`documents/${documentId}/template/patches`, {
+			method: 'GET'
+		});
+	},
+
+	// Applies the patches for the given template sections and dismisses the rest.
+	applyPatches(documentId, templatePageIds) {
+		return this.get('ajax').request(`documents/${documentId}/template/patches`, {
+			method: 'POST',
+			data: JSON.stringify({ apply: templatePageIds })
+		});
 	}
 });
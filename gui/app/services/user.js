@@ -149,5 +149,26 @@ export default Ember.Service.extend({
 			method: "POST",
 			data: password
 		});
+	},
+
+	// Documents and spaces the current user watches.
+	getWatches() {
+		return this.get('ajax').request(`watches`, {
+			method: "GET"
+		});
+	},
+
+	// Whether change notifications arrive immediately or as a daily digest.
+	getWatchPreference() {
+		return this.get('ajax').request(`watches/preference`, {
+			method: "GET"
+		});
+	},
+
+	saveWatchPreference(digest) {
+		return this.get('ajax').request(`watches/preference`, {
+			method: "PUT",
+			data: JSON.stringify({ digest: digest })
+		});
 	}
 });
@@ -38,6 +38,12 @@ export default {
         Reverted: 7,
         PublishedTemplate: 8,
         PublishedBlock: 9,
-        Feedback: 10
+        Feedback: 10,
+        Restored: 11,
+        Draft: 12,
+        Published: 13,
+        Rejected: 14,
+        ApprovalRequested: 15,
+        Commented: 16
     }
 };
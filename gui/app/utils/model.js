@@ -166,6 +166,7 @@ let PageModel = BaseModel.extend({
 	level: 1,
 	sequence: 0,
 	revisions: 0,
+	version: 0,
 	title: "",
 	body: "",
 	rawBody: "",
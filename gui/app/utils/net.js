@@ -70,6 +70,30 @@ function isAjaxNotFoundError(reason) {
 	return false;
 }
 
+function isAjaxConflictError(reason) {
+	if (typeof reason === "undefined" || typeof reason.errors === "undefined") {
+		return false;
+	}
+
+	if (reason.errors.length > 0 && reason.errors[0].status === "409") {
+		return true;
+	}
+
+	return false;
+}
+
+function isAjaxLockedError(reason) {
+	if (typeof reason === "undefined" || typeof reason.errors === "undefined") {
+		return false;
+	}
+
+	if (reason.errors.length > 0 && reason.errors[0].status === "423") {
+		return true;
+	}
+
+	return false;
+}
+
 function isInvalidLicenseError(reason) {
 	if (typeof reason === "undefined" || typeof reason.errors === "undefined") {
 		return false;
@@ -87,5 +111,7 @@ export default {
 	getAppUrl,
 	isAjaxAccessError,
 	isAjaxNotFoundError,
+	isAjaxConflictError,
+	isAjaxLockedError,
 	isInvalidLicenseError,
 };
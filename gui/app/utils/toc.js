@@ -138,7 +138,8 @@ function moveUp(state, pages, current) {
 
 		pendingChanges.push({
 			pageId: current.get('id'),
-			sequence: sequence
+			sequence: sequence,
+			version: current.get('version')
 		});
 
 		for (var i = index + 1; i < pages.length; i++) {
@@ -150,7 +151,8 @@ function moveUp(state, pages, current) {
 
 			pendingChanges.push({
 				pageId: pages[i].get('id'),
-				sequence: sequence
+				sequence: sequence,
+				version: pages[i].get('version')
 			});
 		}
 	}
@@ -197,7 +199,8 @@ function moveDown(state, pages, current) {
 
 	pendingChanges.push({
 		pageId: current.get('id'),
-		sequence: startingSequence
+		sequence: startingSequence,
+		version: current.get('version')
 	});
 
 	var sequence = (startingSequence + upperSequence) / 2;
@@ -211,7 +214,8 @@ function moveDown(state, pages, current) {
 
 		pendingChanges.push({
 			pageId: pages[i].get('id'),
-			sequence: sequence2
+			sequence: sequence2,
+			version: pages[i].get('version')
 		});
 	}
 
@@ -225,7 +229,8 @@ function indent(state, pages, current) {
 
 	pendingChanges.push({
 		pageId: current.get('id'),
-		level: current.get('level') + state.tocTools.indentIncrement
+		level: current.get('level') + state.tocTools.indentIncrement,
+		version: current.get('version')
 	});
 
 	for (var i = pageIndex + 1; i < pages.length; i++) {
@@ -235,7 +240,8 @@ function indent(state, pages, current) {
 
 		pendingChanges.push({
 			pageId: pages[i].get('id'),
-			level: pages[i].get('level') + state.tocTools.indentIncrement
+			level: pages[i].get('level') + state.tocTools.indentIncrement,
+			version: pages[i].get('version')
 		});
 	}
 
@@ -248,7 +254,8 @@ function outdent(state, pages, current) {
 
 	pendingChanges.push({
 		pageId: current.get('id'),
-		level: current.get('level') - 1
+		level: current.get('level') - 1,
+		version: current.get('version')
 	});
 
 	for (var i = pageIndex + 1; i < pages.length; i++) {
@@ -258,7 +265,8 @@ function outdent(state, pages, current) {
 
 		pendingChanges.push({
 			pageId: pages[i].get('id'),
-			level: pages[i].get('level') - 1
+			level: pages[i].get('level') - 1,
+			version: pages[i].get('version')
 		});
 	}
 
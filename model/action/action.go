@@ -0,0 +1,76 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package action
+
+import (
+	"time"
+
+	"github.com/documize/community/model"
+)
+
+// UserAction is something a user has been asked to do with a document.
+type UserAction struct {
+	model.BaseEntity
+	OrgID         string     `json:"orgId"`
+	UserID        string     `json:"userId"` // who is asked
+	DocumentID    string     `json:"documentId"`
+	RequestorID   string     `json:"requestorId"`
+	ActionType    Type       `json:"actionType"`
+	Note          string     `json:"note"`
+	Requested     time.Time  `json:"requested"`
+	Due           *time.Time `json:"due"`
+	Completed     *time.Time `json:"completed"`
+	IsComplete    bool       `json:"isComplete"`
+	Outcome       Outcome    `json:"outcome"`
+	Reply         string     `json:"reply"`
+	DocumentTitle string     `json:"documentTitle"`
+	LabelID       string     `json:"folderId"`
+	Firstname     string     `json:"firstname"` // of the user asked
+	Lastname      string     `json:"lastname"`
+	RequestedBy   string     `json:"requestedBy"`
+}
+
+// Type determines what the user is asked to do.
+type Type int
+
+const (
+	// TypeApprove asks the user to approve or reject the document.
+	TypeApprove Type = 1
+)
+
+// Outcome records how the user responded.
+type Outcome int
+
+const (
+	// OutcomePending means the user has not responded yet.
+	OutcomePending Outcome = 0
+
+	// OutcomeApproved means the user approved the document.
+	OutcomeApproved Outcome = 1
+
+	// OutcomeRejected means the user rejected the document.
+	OutcomeRejected Outcome = 2
+)
+
+// ApprovalRequest asks users to approve a document.
+type ApprovalRequest struct {
+	Users    []string   `json:"users"`
+	Due      *time.Time `json:"due"`
+	Note     string     `json:"note"`
+	Required *int       `json:"required"` // approvals needed before the document can go live, nil keeps the current setting
+}
+
+// Response approves or rejects a requested action.
+type Response struct {
+	Approved bool   `json:"approved"`
+	Note     string `json:"note"`
+}
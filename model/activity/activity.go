@@ -70,6 +70,24 @@ const (
 
 	// TypeFeedback records user providing document feedback
 	TypeFeedback Type = 10
+
+	// TypeRestored records user restoring deleted document or section
+	TypeRestored Type = 11
+
+	// TypeDraft records user returning document to draft
+	TypeDraft Type = 12
+
+	// TypePublished records user making document live
+	TypePublished Type = 13
+
+	// TypeRejected records user rejection of document
+	TypeRejected Type = 14
+
+	// TypeApprovalRequest records user asking others to approve document
+	TypeApprovalRequest Type = 15
+
+	// TypeCommented records user commenting on document
+	TypeCommented Type = 16
 )
 
 // DocumentActivity represents an activity taken against a document.
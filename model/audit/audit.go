@@ -34,18 +34,49 @@ const (
 	EventTypeDocumentUpdate     EventType = "updated-document"
 	EventTypeDocumentDelete     EventType = "removed-document"
 	EventTypeDocumentRevisions  EventType = "viewed-document-revisions"
+	EventTypeDocumentRestore    EventType = "restored-document"
+	EventTypeDocumentDraft      EventType = "drafted-document"
+	EventTypeDocumentPublish    EventType = "published-document"
+	EventTypeDocumentArchive    EventType = "archived-document"
+	EventTypeApprovalRequest    EventType = "requested-document-approval"
+	EventTypeApprovalCancel     EventType = "cancelled-document-approval"
+	EventTypeDocumentApprove    EventType = "approved-document"
+	EventTypeDocumentReject     EventType = "rejected-document"
+	EventTypeDocumentMove       EventType = "moved-document"
+	EventTypeDocumentDuplicate  EventType = "duplicated-document"
 	EventTypeSpaceAdd           EventType = "added-space"
 	EventTypeSpaceUpdate        EventType = "updated-space"
 	EventTypeSpaceDelete        EventType = "removed-space"
 	EventTypeSpacePermission    EventType = "changed-space-permissions"
 	EventTypeSpaceJoin          EventType = "joined-space"
 	EventTypeSpaceInvite        EventType = "invited-space"
+	EventTypeSpaceFieldAdd      EventType = "added-space-field"
+	EventTypeSpaceFieldUpdate   EventType = "updated-space-field"
+	EventTypeSpaceFieldDelete   EventType = "removed-space-field"
 	EventTypeSectionAdd         EventType = "added-document-section"
 	EventTypeSectionUpdate      EventType = "updated-document-section"
 	EventTypeSectionDelete      EventType = "removed-document-section"
 	EventTypeSectionRollback    EventType = "rolled-back-document-section"
 	EventTypeSectionResequence  EventType = "resequenced-document-section"
 	EventTypeSectionCopy        EventType = "copied-document-section"
+	EventTypeSectionRestore     EventType = "restored-document-section"
+	EventTypeSectionLock        EventType = "locked-document-section"
+	EventTypeSectionLockRenew   EventType = "renewed-document-section-lock"
+	EventTypeSectionUnlock      EventType = "unlocked-document-section"
+	EventTypeSectionLockBreak   EventType = "broke-document-section-lock"
+	EventTypeCommentAdd         EventType = "added-comment"
+	EventTypeCommentUpdate      EventType = "updated-comment"
+	EventTypeCommentDelete      EventType = "removed-comment"
+	EventTypeCommentResolve     EventType = "resolved-comment"
+	EventTypeCommentReopen      EventType = "reopened-comment"
+	EventTypeShareAdd           EventType = "added-document-share"
+	EventTypeShareRevoke        EventType = "revoked-document-share"
+	EventTypeShareView          EventType = "viewed-document-share"
+	EventTypeSnapshotAdd        EventType = "added-document-snapshot"
+	EventTypeSnapshotRestore    EventType = "restored-document-snapshot"
+	EventTypeSnapshotDelete     EventType = "removed-document-snapshot"
+	EventTypeTrashPurge         EventType = "purged-trash"
+	EventTypeTrashConfig        EventType = "changed-trash-retention"
 	EventTypeAttachmentAdd      EventType = "added-attachment"
 	EventTypeAttachmentDownload EventType = "downloaded-attachment"
 	EventTypeAttachmentDelete   EventType = "removed-attachment"
@@ -55,8 +86,18 @@ const (
 	EventTypeBlockAdd           EventType = "added-reusable-block"
 	EventTypeBlockUpdate        EventType = "updated-reusable-block"
 	EventTypeBlockDelete        EventType = "removed-reusable-block"
+	EventTypeBlockDetach        EventType = "detached-reusable-block"
+	EventTypeDataSourceAdd      EventType = "added-datasource"
+	EventTypeDataSourceUpdate   EventType = "updated-datasource"
+	EventTypeDataSourceDelete   EventType = "removed-datasource"
 	EventTypeTemplateAdd        EventType = "added-document-template"
 	EventTypeTemplateUse        EventType = "used-document-template"
+	EventTypeTemplateVersion    EventType = "released-document-template-version"
+	EventTypeTemplatePatch      EventType = "applied-document-template-changes"
+	EventTypeTagRename          EventType = "renamed-tag"
+	EventTypeTagMerge           EventType = "merged-tags"
+	EventTypeTagAdd             EventType = "added-space-tag"
+	EventTypeTagDelete          EventType = "removed-space-tag"
 	EventTypeUserAdd            EventType = "added-user"
 	EventTypeUserUpdate         EventType = "updated-user"
 	EventTypeUserDelete         EventType = "removed-user"
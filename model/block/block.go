@@ -31,3 +31,16 @@ type Block struct {
 	Firstname      string `json:"firstname"`
 	Lastname       string `json:"lastname"`
 }
+
+// Skipped is a linked section a block update was not copied into.
+type Skipped struct {
+	DocumentID string `json:"documentId"`
+	PageID     string `json:"pageId"`
+	Reason     string `json:"reason"` // forbidden, archived or locked
+}
+
+// Propagation reports how a block update reached its linked sections.
+type Propagation struct {
+	Updated int       `json:"updated"`
+	Skipped []Skipped `json:"skipped"`
+}
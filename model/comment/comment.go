@@ -0,0 +1,79 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package comment
+
+import (
+	"strings"
+	"time"
+
+	"github.com/documize/community/model"
+)
+
+// Comment is a remark on a document section. The first comment on a
+// section starts a thread, replies carry the ThreadID of that comment.
+type Comment struct {
+	model.BaseEntity
+	OrgID       string `json:"orgId"`
+	DocumentID  string `json:"documentId"`
+	PageID      string `json:"pageId"`
+	ThreadID    string `json:"threadId"` // blank when the comment starts a thread
+	UserID      string `json:"userId"`
+	Body        string `json:"body"`
+	Mentions    string `json:"mentions"`    // comma separated user IDs
+	Anchor      string `json:"anchor"`      // quoted section text, blank for the whole section
+	AnchorStart int    `json:"anchorStart"` // character offset of Anchor in the section text, -1 when not anchored
+	Resolved    bool   `json:"resolved"`
+	ResolvedBy  string `json:"resolvedBy"`
+	Outdated    bool   `json:"outdated"` // anchored text no longer exists
+	Edited      bool   `json:"edited"`
+	Firstname   string `json:"firstname"`
+	Lastname    string `json:"lastname"`
+}
+
+// MentionIDs returns the users mentioned in the comment.
+func (c *Comment) MentionIDs() (ids []string) {
+	for _, id := range strings.Split(c.Mentions, ",") {
+		id = strings.TrimSpace(id)
+		if len(id) > 0 {
+			ids = append(ids, id)
+		}
+	}
+
+	return
+}
+
+// Thread is a comment together with its replies, oldest first.
+type Thread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// Revision is a previous body of an edited comment.
+type Revision struct {
+	ID        uint64    `json:"-"`
+	OrgID     string    `json:"orgId"`
+	CommentID string    `json:"commentId"`
+	UserID    string    `json:"userId"`
+	Body      string    `json:"body"`
+	Firstname string    `json:"firstname"`
+	Lastname  string    `json:"lastname"`
+	Created   time.Time `json:"created"`
+}
+
+// NewComment starts a thread or replies to one.
+type NewComment struct {
+	PageID      string   `json:"pageId"`
+	Body        string   `json:"body"`
+	Mentions    []string `json:"mentions"`
+	Anchor      string   `json:"anchor"`
+	AnchorStart int      `json:"anchorStart"`
+}
@@ -0,0 +1,42 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package datasource
+
+import "github.com/documize/community/model"
+
+// DataSource is a database that sections may query for read-only data.
+// DSN holds connection details and is encrypted at rest.
+// It is accepted from admins but never sent back to clients.
+type DataSource struct {
+	model.BaseEntity
+	OrgID   string `json:"orgId"`
+	UserID  string `json:"userId"`
+	Name    string `json:"name"`
+	Driver  string `json:"driver"`
+	DSN     string `json:"dsn,omitempty"`
+	MaxRows int    `json:"maxRows"`
+	Timeout int    `json:"timeout"` // seconds
+}
+
+// Default limits applied when none are configured.
+const (
+	DefaultMaxRows = 100
+	DefaultTimeout = 10
+)
+
+// Result holds rows returned by a data source query.
+// Values are converted to text for display.
+type Result struct {
+	Columns   []string   `json:"columns"`
+	Rows      [][]string `json:"rows"`
+	Truncated bool       `json:"truncated"` // more rows than the limit allowed
+}
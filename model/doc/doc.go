@@ -21,17 +21,41 @@ import (
 // Document represents the purpose of Documize.
 type Document struct {
 	model.BaseEntity
-	OrgID    string `json:"orgId"`
-	LabelID  string `json:"folderId"`
-	UserID   string `json:"userId"`
-	Job      string `json:"job"`
-	Location string `json:"location"`
-	Title    string `json:"name"`
-	Excerpt  string `json:"excerpt"`
-	Slug     string `json:"-"`
-	Tags     string `json:"tags"`
-	Template bool   `json:"template"`
-	Layout   string `json:"layout"`
+	OrgID     string    `json:"orgId"`
+	LabelID   string    `json:"folderId"`
+	UserID    string    `json:"userId"`
+	Job       string    `json:"job"`
+	Location  string    `json:"location"`
+	Title     string    `json:"name"`
+	Excerpt   string    `json:"excerpt"`
+	Slug      string    `json:"-"`
+	Tags      string    `json:"tags"`
+	Template  bool      `json:"template"`
+	Layout    string    `json:"layout"`
+	Lifecycle Lifecycle `json:"lifecycle"`
+	Approvals int       `json:"approvals"` // approvals needed before going live
+
+	// Fields holds values for the space's metadata fields, keyed by field name.
+	Fields map[string]string `json:"fields,omitempty" db:"-"`
+}
+
+// Lifecycle is the publication state of a document.
+type Lifecycle int
+
+const (
+	// LifecycleDraft means the document is being written.
+	LifecycleDraft Lifecycle = 0
+
+	// LifecycleLive means the document is published.
+	LifecycleLive Lifecycle = 1
+
+	// LifecycleArchived means the document is kept for reference only and cannot be changed.
+	LifecycleArchived Lifecycle = 2
+)
+
+// Valid reports whether l is a known lifecycle state.
+func (l Lifecycle) Valid() bool {
+	return l == LifecycleDraft || l == LifecycleLive || l == LifecycleArchived
 }
 
 // SetDefaults ensures on blanks and cleans.
@@ -0,0 +1,42 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package feedback
+
+import "time"
+
+// Feedback is a reader's rating of a document or one of its sections.
+type Feedback struct {
+	ID         uint64    `json:"-"`
+	RefID      string    `json:"id"`
+	OrgID      string    `json:"orgId"`
+	DocumentID string    `json:"documentId"`
+	PageID     string    `json:"pageId"` // blank when rating the whole document
+	UserID     string    `json:"userId"` // blank when anonymous
+	Email      string    `json:"email"`
+	Helpful    bool      `json:"helpful"`
+	Feedback   string    `json:"feedback"` // optional comment
+	PageTitle  string    `json:"pageTitle"`
+	Firstname  string    `json:"firstname"`
+	Lastname   string    `json:"lastname"`
+	Created    time.Time `json:"created"`
+}
+
+// Summary totals feedback for a document or section within a space.
+type Summary struct {
+	DocumentID    string `json:"documentId"`
+	DocumentTitle string `json:"documentTitle"`
+	PageID        string `json:"pageId"`
+	PageTitle     string `json:"pageTitle"`
+	Helpful       int    `json:"helpful"`
+	NotHelpful    int    `json:"notHelpful"`
+	Comments      int    `json:"comments"`
+}
@@ -0,0 +1,55 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package metadata
+
+import "github.com/documize/community/model"
+
+// FieldType determines what a metadata field holds.
+type FieldType string
+
+const (
+	// FieldText is free text.
+	FieldText FieldType = "text"
+	// FieldNumber is a decimal number.
+	FieldNumber FieldType = "number"
+	// FieldDate is a date formatted as YYYY-MM-DD.
+	FieldDate FieldType = "date"
+	// FieldUser is the ID of a user in the organization.
+	FieldUser FieldType = "user"
+	// FieldEnum is one of a fixed list of values.
+	FieldEnum FieldType = "enum"
+)
+
+// DateFormat is how date fields are entered and stored.
+const DateFormat = "2006-01-02"
+
+// Field is part of the metadata schema a space defines for its documents.
+type Field struct {
+	model.BaseEntity
+	OrgID    string    `json:"orgId"`
+	LabelID  string    `json:"folderId"`
+	Name     string    `json:"name"`  // key used in document fields and filters
+	Label    string    `json:"label"` // shown to users
+	Type     FieldType `json:"type"`
+	Required bool      `json:"required"`
+	Choices  []string  `json:"choices" db:"-"` // allowed values for enum fields
+	Sequence int       `json:"sequence"`
+}
+
+// Value is a document's value for a metadata field.
+type Value struct {
+	DocumentID string    `json:"documentId"`
+	FieldID    string    `json:"fieldId"`
+	Name       string    `json:"name"`
+	Type       FieldType `json:"type"`
+	Value      string    `json:"value"`
+}
@@ -27,11 +27,14 @@ type Page struct {
 	ContentType string  `json:"contentType"`
 	PageType    string  `json:"pageType"`
 	BlockID     string  `json:"blockId"`
+	BlockLinked bool    `json:"blockLinked"` // content follows the block rather than being a copy
 	Level       uint64  `json:"level"`
 	Sequence    float64 `json:"sequence"`
 	Title       string  `json:"title"`
 	Body        string  `json:"body"`
 	Revisions   uint64  `json:"revisions"`
+	Version     uint64  `json:"version"` // incremented on every change, clients send back what they edited
+	Lock        *Lock   `json:"lock,omitempty" db:"-"`
 }
 
 // SetDefaults ensures no blank values.
@@ -102,15 +105,83 @@ type NewPage struct {
 	Meta Meta `json:"meta"`
 }
 
-
 // PageSequenceRequest details a page ID and its sequence within the document.
 type PageSequenceRequest struct {
 	PageID   string  `json:"pageId"`
 	Sequence float64 `json:"sequence"`
+	Version  uint64  `json:"version"`
 }
 
 // PageLevelRequest details a page ID and level.
 type PageLevelRequest struct {
-	PageID string `json:"pageId"`
-	Level  int    `json:"level"`
-}
\ No newline at end of file
+	PageID  string `json:"pageId"`
+	Level   int    `json:"level"`
+	Version uint64 `json:"version"`
+}
+
+// Conflict is returned when a change is based on an out-of-date
+// version of a page.
+type Conflict struct {
+	Page Page   `json:"page"` // current version
+	Diff string `json:"diff"` // HTML diff of current body against rejected body
+}
+
+// LockTTL is how long a page lock lasts unless renewed.
+const LockTTL = 15 * time.Minute
+
+// Lock is a lease on a page held by the user editing it.
+// Other users cannot change the page until it is released or expires.
+type Lock struct {
+	ID         uint64    `json:"-"`
+	OrgID      string    `json:"orgId"`
+	DocumentID string    `json:"documentId"`
+	PageID     string    `json:"pageId"`
+	UserID     string    `json:"userId"`
+	Firstname  string    `json:"firstname"`
+	Lastname   string    `json:"lastname"`
+	Expires    time.Time `json:"expires"`
+	Created    time.Time `json:"created"`
+}
+
+// SectionDiff describes how one section differs between two versions of a document.
+type SectionDiff struct {
+	PageID        string `json:"pageId"`
+	Title         string `json:"title"`
+	PreviousTitle string `json:"previousTitle"`
+	Level         uint64 `json:"level"`
+	PreviousLevel uint64 `json:"previousLevel"`
+	Added         bool   `json:"added"`
+	Deleted       bool   `json:"deleted"`
+	Moved         bool   `json:"moved"`     // position relative to other sections changed
+	Releveled     bool   `json:"releveled"` // indent changed
+	Changed       bool   `json:"changed"`   // title or content changed
+	Diff          string `json:"diff"`      // HTML diff of content
+}
+
+// DocumentDiff lists what changed between two versions of a document.
+type DocumentDiff struct {
+	From      time.Time     `json:"from"`      // when the earlier version was current
+	To        time.Time     `json:"to"`        // when the later version was current
+	Positions bool          `json:"positions"` // moves and indent changes are known, i.e. both ends are snapshots or current
+	Sections  []SectionDiff `json:"sections"`  // changed sections in document order
+	HTML      string        `json:"html"`      // whole document with changes marked up
+}
+
+// Node is a section within the document outline and the sections nested beneath it.
+type Node struct {
+	PageID   string  `json:"pageId"`
+	Title    string  `json:"title"`
+	Level    uint64  `json:"level"`
+	Sequence float64 `json:"sequence"`
+	Version  uint64  `json:"version"`
+	Number   string  `json:"number,omitempty"` // outline number such as 1.2, when requested
+	Children []*Node `json:"children"`
+}
+
+// TreeMoveRequest moves a section together with the sections nested beneath it.
+type TreeMoveRequest struct {
+	PageID  string  `json:"pageId"`
+	Version uint64  `json:"version"` // version of the section being moved
+	After   *string `json:"after"`   // section to follow; empty moves to the start, absent keeps position
+	Indent  int     `json:"indent"`  // levels to indent (positive) or outdent (negative) the subtree
+}
@@ -18,6 +18,11 @@ type QueryOptions struct {
 	Tag        bool   `json:"tag"`
 	Attachment bool   `json:"attachment"`
 	Content    bool   `json:"content"`
+	Archived   bool   `json:"archived"` // include archived documents
+
+	// Fields restricts results to documents whose metadata fields match,
+	// keyed by field name.
+	Fields map[string]string `json:"fields"`
 }
 
 // QueryResult represents 'presentable' search results.
@@ -0,0 +1,69 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package share
+
+import (
+	"time"
+
+	"github.com/documize/community/model/attachment"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+)
+
+// Share is a read-only link to one document for someone without an account.
+type Share struct {
+	ID         uint64    `json:"-"`
+	RefID      string    `json:"id"`
+	OrgID      string    `json:"orgId"`
+	DocumentID string    `json:"documentId"`
+	UserID     string    `json:"userId"` // who created the link
+	Email      string    `json:"email"`
+	Message    string    `json:"message"`
+	Viewed     string    `json:"viewed"` // when the link was last opened
+	Views      int       `json:"views"`
+	Secret     string    `json:"secret"`
+	Expires    string    `json:"expires"` // blank when the link never expires
+	Active     bool      `json:"active"`
+	Created    time.Time `json:"created"`
+	URL        string    `json:"url"`
+}
+
+// TimeFormat is how Expires and Viewed are stored, always in UTC.
+const TimeFormat = "2006-01-02 15:04"
+
+// Expired reports if the link can no longer be used.
+func (s Share) Expired(now time.Time) bool {
+	if len(s.Expires) == 0 {
+		return false
+	}
+
+	t, err := time.Parse(TimeFormat, s.Expires)
+	if err != nil {
+		return true
+	}
+
+	return !now.UTC().Before(t)
+}
+
+// NewShare is the payload used to create a share link.
+type NewShare struct {
+	Email   string     `json:"email"`
+	Message string     `json:"message"`
+	Expires *time.Time `json:"expires"`
+}
+
+// Document is what a share link shows: the document, its sections and attachments.
+type Document struct {
+	Document    doc.Document            `json:"document"`
+	Pages       []page.Page             `json:"pages"`
+	Attachments []attachment.Attachment `json:"attachments"`
+}
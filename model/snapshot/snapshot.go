@@ -0,0 +1,51 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package snapshot
+
+import (
+	"github.com/documize/community/model"
+	"github.com/documize/community/model/attachment"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/page"
+)
+
+// Snapshot is a named version of a whole document.
+type Snapshot struct {
+	model.BaseEntity
+	OrgID      string `json:"orgId"`
+	DocumentID string `json:"documentId"`
+	UserID     string `json:"userId"`
+	Name       string `json:"name"`
+	Note       string `json:"note"`
+	Firstname  string `json:"firstname"`
+	Lastname   string `json:"lastname"`
+	Data       []byte `json:"-"` // JSON encoded Content
+}
+
+// Content is the document state captured by a snapshot.
+type Content struct {
+	Document    doc.Document   `json:"document"`
+	Pages       []page.NewPage `json:"pages"` // in document order
+	Attachments []Attachment   `json:"attachments"`
+}
+
+// Attachment is a document attachment including the file itself.
+type Attachment struct {
+	attachment.Attachment
+	Data []byte `json:"data,omitempty"`
+}
+
+// View is a snapshot with its content, less attachment files.
+type View struct {
+	Snapshot
+	Content
+}
@@ -0,0 +1,49 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package tag
+
+import "github.com/documize/community/model"
+
+// Tag is an entry in a space's curated tag vocabulary. Documents in a
+// space with a vocabulary can only be tagged from it.
+type Tag struct {
+	model.BaseEntity
+	OrgID   string `json:"orgId"`
+	LabelID string `json:"folderId"`
+	Name    string `json:"name"`
+}
+
+// Usage is a tag and how many documents carry it.
+type Usage struct {
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Curated bool   `json:"curated"` // in the space's vocabulary
+}
+
+// RenameRequest renames a tag on every document, or only those in a space.
+type RenameRequest struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	LabelID string `json:"folderId"` // empty for the whole organization
+}
+
+// MergeRequest replaces several tags with one on every document, or only those in a space.
+type MergeRequest struct {
+	Tags    []string `json:"tags"`
+	Into    string   `json:"into"`
+	LabelID string   `json:"folderId"` // empty for the whole organization
+}
+
+// Change reports how many documents a rename or merge retagged.
+type Change struct {
+	Documents int `json:"documents"`
+}
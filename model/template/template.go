@@ -52,3 +52,125 @@ func (t *Template) IsPrivate() bool {
 func (t *Template) IsRestricted() bool {
 	return t.Type == TypeRestricted
 }
+
+// VariableType determines what a template variable holds.
+type VariableType string
+
+const (
+	// VariableText is free text.
+	VariableText VariableType = "text"
+	// VariableDate is a date formatted as YYYY-MM-DD.
+	VariableDate VariableType = "date"
+	// VariableUser is the ID of a user in the organization, shown as their name.
+	VariableUser VariableType = "user"
+	// VariableChoice is one of a fixed list of values.
+	VariableChoice VariableType = "choice"
+)
+
+// DateFormat is how date variables are entered and shown.
+const DateFormat = "2006-01-02"
+
+// Variable is a value prompted for when a template is used and
+// substituted wherever {{ .Name }} appears in the template.
+type Variable struct {
+	Name     string       `json:"name"`
+	Label    string       `json:"label"` // prompt shown to the user
+	Type     VariableType `json:"type"`
+	Required bool         `json:"required"`
+	Choices  []string     `json:"choices"` // allowed values for choice variables
+	Default  string       `json:"default"`
+}
+
+// Origin records the template a document was created from,
+// the template version it matches and the variable values supplied.
+type Origin struct {
+	DocumentID string            `json:"documentId"`
+	TemplateID string            `json:"templateId"`
+	Version    int               `json:"version"`
+	Values     map[string]string `json:"values"`
+	Created    time.Time         `json:"created"`
+	Synced     time.Time         `json:"synced"` // when template changes were last taken on
+}
+
+// Version is a released revision of a template. Documents created from
+// or synced with the template record the version they match.
+type Version struct {
+	TemplateID string    `json:"templateId"`
+	Version    int       `json:"version"`
+	UserID     string    `json:"userId"`
+	Note       string    `json:"note"`
+	Documents  int       `json:"documents"` // documents currently at this version
+	Created    time.Time `json:"created"`
+	Data       []byte    `json:"-"` // JSON encoded template sections as released
+}
+
+// Derived is a document created from a template.
+type Derived struct {
+	DocumentID string    `json:"documentId"`
+	LabelID    string    `json:"folderId"`
+	Title      string    `json:"title"`
+	Version    int       `json:"version"`
+	Synced     time.Time `json:"synced"`
+}
+
+// Section links a document section to the template section it came from.
+type Section struct {
+	PageID         string `json:"pageId"`
+	TemplatePageID string `json:"templatePageId"`
+}
+
+// PatchKind says how a patch changes a document.
+type PatchKind string
+
+const (
+	// PatchAdd inserts a section added to the template.
+	PatchAdd PatchKind = "add"
+	// PatchUpdate replaces a section changed in the template.
+	PatchUpdate PatchKind = "update"
+)
+
+// Patch proposes bringing one document section in line with the template.
+type Patch struct {
+	TemplatePageID string    `json:"templatePageId"`
+	PageID         string    `json:"pageId"` // empty when adding a section
+	Kind           PatchKind `json:"kind"`
+	Title          string    `json:"title"`
+	Body           string    `json:"body"`
+	CurrentTitle   string    `json:"currentTitle"`
+	CurrentBody    string    `json:"currentBody"`
+	Edited         bool      `json:"edited"` // section changed in the document since last synced
+}
+
+// Patches lists the changes proposed for a document.
+type Patches struct {
+	DocumentID      string  `json:"documentId"`
+	TemplateID      string  `json:"templateId"`
+	Version         int     `json:"version"`         // version the document matches
+	TemplateVersion int     `json:"templateVersion"` // latest template version
+	Patches         []Patch `json:"patches"`
+}
+
+// PatchRequest lists the template sections whose patches should be
+// applied. Patches not listed are dismissed.
+type PatchRequest struct {
+	Apply []string `json:"apply"`
+}
+
+// Conflict is a patch that cannot be applied because its target is
+// locked by someone else or its document is archived.
+type Conflict struct {
+	TemplatePageID string `json:"templatePageId"`
+	PageID         string `json:"pageId"`
+	Reason         string `json:"reason"` // archived or locked
+}
+
+// VersionRequest releases a new template version.
+type VersionRequest struct {
+	Note string `json:"note"`
+}
+
+// UseRequest is the payload used to create a document from a template.
+type UseRequest struct {
+	Title  string            `json:"title"`
+	Values map[string]string `json:"values"`
+}
@@ -0,0 +1,59 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package trash
+
+import (
+	"github.com/documize/community/model"
+	"github.com/documize/community/model/comment"
+	"github.com/documize/community/model/doc"
+	"github.com/documize/community/model/link"
+	"github.com/documize/community/model/metadata"
+	"github.com/documize/community/model/page"
+	"github.com/documize/community/model/pin"
+	"github.com/documize/community/model/snapshot"
+)
+
+// DefaultRetention is how many days deleted content is kept when not configured.
+const DefaultRetention = 30
+
+// Item is a deleted document, or sections of one, that can be restored until purged.
+type Item struct {
+	model.BaseEntity
+	OrgID      string `json:"orgId"`
+	LabelID    string `json:"folderId"`
+	DocumentID string `json:"documentId"`
+	PageID     string `json:"pageId"` // empty when the whole document was deleted
+	UserID     string `json:"userId"` // who deleted it
+	Title      string `json:"title"`
+	Firstname  string `json:"firstname"`
+	Lastname   string `json:"lastname"`
+	Data       []byte `json:"-"` // JSON encoded Content
+}
+
+// Content holds everything needed to restore an item.
+type Content struct {
+	Document         *doc.Document         `json:"document,omitempty"` // nil when only sections were deleted
+	Pages            []page.NewPage        `json:"pages"`
+	Revisions        []page.Revision       `json:"revisions"`
+	Attachments      []snapshot.Attachment `json:"attachments"`
+	Links            []link.Link           `json:"links"`   // outbound links from deleted content
+	Inbound          []string              `json:"inbound"` // IDs of links orphaned by the deletion
+	Pins             []pin.Pin             `json:"pins"`
+	Comments         []comment.Comment     `json:"comments"`
+	CommentRevisions []comment.Revision    `json:"commentRevisions"`
+	Fields           []metadata.Value      `json:"fields"` // document metadata values
+}
+
+// Config holds installation-wide trash settings.
+type Config struct {
+	Retention int `json:"retention"` // days before deleted content is purged, zero keeps it forever
+}
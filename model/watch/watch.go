@@ -0,0 +1,63 @@
+// Copyright 2016 Documize Inc. <legal@documize.com>. All rights reserved.
+//
+// This software (Documize Community Edition) is licensed under
+// GNU AGPL v3 http://www.gnu.org/licenses/agpl-3.0.en.html
+//
+// You can operate outside the AGPL restrictions by purchasing
+// Documize Enterprise Edition and obtaining a commercial license
+// by contacting <sales@documize.com>.
+//
+// https://documize.com
+
+package watch
+
+import "time"
+
+// Watcher subscribes a user to changes in a document or a whole space.
+// Exactly one of LabelID and DocumentID is set.
+type Watcher struct {
+	ID           uint64     `json:"-"`
+	RefID        string     `json:"id"`
+	OrgID        string     `json:"orgId"`
+	LabelID      string     `json:"folderId"`
+	DocumentID   string     `json:"documentId"`
+	UserID       string     `json:"userId"`
+	RoleType     string     `json:"roleType"`
+	LastViewed   *time.Time `json:"lastViewed"`
+	LastNotified *time.Time `json:"lastNotified"`
+	AppURL       string     `json:"-"` // where links in notifications point
+	Title        string     `json:"title"`
+	Email        string     `json:"-"`
+	Created      time.Time  `json:"created"`
+}
+
+const (
+	// RoleWatcher is a user who chose to watch.
+	RoleWatcher = "W"
+
+	// RoleAuthor is a document author, subscribed automatically.
+	RoleAuthor = "A"
+)
+
+// Preference holds how often a user is notified.
+type Preference struct {
+	Digest bool `json:"digest"` // once a day rather than as changes happen
+}
+
+// Change is a section added, updated or deleted.
+type Change struct {
+	DocumentID    string    `json:"documentId"`
+	DocumentTitle string    `json:"documentTitle"`
+	PageID        string    `json:"pageId"` // blank when the whole document was deleted
+	PageTitle     string    `json:"pageTitle"`
+	Kind          string    `json:"kind"` // added, updated or deleted
+	UserID        string    `json:"userId"`
+	Revised       time.Time `json:"revised"`
+}
+
+// Digest lists changes to one watched document or space for notification.
+type Digest struct {
+	Title   string
+	URL     string
+	Changes []Change
+}
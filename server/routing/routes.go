@@ -16,23 +16,33 @@ import (
 
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/action"
 	"github.com/documize/community/domain/attachment"
 	"github.com/documize/community/domain/auth"
 	"github.com/documize/community/domain/auth/keycloak"
 	"github.com/documize/community/domain/block"
+	"github.com/documize/community/domain/comment"
 	"github.com/documize/community/domain/conversion"
+	"github.com/documize/community/domain/datasource"
 	"github.com/documize/community/domain/document"
+	"github.com/documize/community/domain/feedback"
 	"github.com/documize/community/domain/link"
 	"github.com/documize/community/domain/meta"
+	"github.com/documize/community/domain/metadata"
 	"github.com/documize/community/domain/organization"
 	"github.com/documize/community/domain/page"
 	"github.com/documize/community/domain/pin"
 	"github.com/documize/community/domain/search"
 	"github.com/documize/community/domain/section"
 	"github.com/documize/community/domain/setting"
+	"github.com/documize/community/domain/share"
+	"github.com/documize/community/domain/snapshot"
 	"github.com/documize/community/domain/space"
+	"github.com/documize/community/domain/tag"
 	"github.com/documize/community/domain/template"
+	"github.com/documize/community/domain/trash"
 	"github.com/documize/community/domain/user"
+	"github.com/documize/community/domain/watch"
 	"github.com/documize/community/server/web"
 )
 
@@ -50,7 +60,8 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	link := link.Handler{Runtime: rt, Store: s}
 	page := page.Handler{Runtime: rt, Store: s, Indexer: indexer}
 	space := space.Handler{Runtime: rt, Store: s}
-	block := block.Handler{Runtime: rt, Store: s}
+	block := block.Handler{Runtime: rt, Store: s, Indexer: indexer}
+	datasource := datasource.Handler{Runtime: rt, Store: s}
 	section := section.Handler{Runtime: rt, Store: s}
 	setting := setting.Handler{Runtime: rt, Store: s}
 	keycloak := keycloak.Handler{Runtime: rt, Store: s}
@@ -58,6 +69,15 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	document := document.Handler{Runtime: rt, Store: s, Indexer: indexer}
 	attachment := attachment.Handler{Runtime: rt, Store: s, Indexer: indexer}
 	conversion := conversion.Handler{Runtime: rt, Store: s, Indexer: indexer}
+	snapshot := snapshot.Handler{Runtime: rt, Store: s, Indexer: indexer}
+	trash := trash.Handler{Runtime: rt, Store: s, Indexer: indexer}
+	action := action.Handler{Runtime: rt, Store: s}
+	feedback := feedback.Handler{Runtime: rt, Store: s}
+	comment := comment.Handler{Runtime: rt, Store: s}
+	watch := watch.Handler{Runtime: rt, Store: s}
+	share := share.Handler{Runtime: rt, Store: s}
+	metadata := metadata.Handler{Runtime: rt, Store: s}
+	tag := tag.Handler{Runtime: rt, Store: s}
 	organization := organization.Handler{Runtime: rt, Store: s}
 
 	//**************************************************
@@ -71,6 +91,8 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPublic, "reset/{token}", []string{"POST", "OPTIONS"}, nil, user.ResetPassword)
 	Add(rt, RoutePrefixPublic, "share/{folderID}", []string{"POST", "OPTIONS"}, nil, space.AcceptInvitation)
 	Add(rt, RoutePrefixPublic, "attachments/{orgID}/{attachmentID}", []string{"GET", "OPTIONS"}, nil, attachment.Download)
+	Add(rt, RoutePrefixPublic, "shared/{secret}", []string{"GET", "OPTIONS"}, nil, share.View)
+	Add(rt, RoutePrefixPublic, "shared/{secret}/attachments/{attachmentID}", []string{"GET", "OPTIONS"}, nil, share.Download)
 	Add(rt, RoutePrefixPublic, "version", []string{"GET", "OPTIONS"}, nil, func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(rt.Product.Version))
 	})
@@ -87,6 +109,7 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}", []string{"PUT", "OPTIONS"}, nil, document.Update)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}", []string{"DELETE", "OPTIONS"}, nil, document.Delete)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/activity", []string{"GET", "OPTIONS"}, nil, document.Activity)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/lifecycle", []string{"PUT", "OPTIONS"}, nil, document.SetLifecycle)
 
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/level", []string{"POST", "OPTIONS"}, nil, page.ChangePageLevel)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/sequence", []string{"POST", "OPTIONS"}, nil, page.ChangePageSequence)
@@ -94,7 +117,13 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/revisions", []string{"GET", "OPTIONS"}, nil, page.GetRevisions)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/revisions/{revisionID}", []string{"GET", "OPTIONS"}, nil, page.GetDiff)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/revisions/{revisionID}", []string{"POST", "OPTIONS"}, nil, page.Rollback)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/detach", []string{"POST", "OPTIONS"}, nil, page.Detach)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/tree", []string{"GET", "OPTIONS"}, nil, page.GetTree)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/tree/move", []string{"POST", "OPTIONS"}, nil, page.MoveSubtree)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/tree/renormalize", []string{"POST", "OPTIONS"}, nil, page.Renormalize)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/contents", []string{"GET", "OPTIONS"}, nil, page.GetContents)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/revisions", []string{"GET", "OPTIONS"}, nil, page.GetDocumentRevisions)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/diff", []string{"GET", "OPTIONS"}, nil, page.GetDocumentDiff)
 
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages", []string{"GET", "OPTIONS"}, nil, page.GetPages)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}", []string{"PUT", "OPTIONS"}, nil, page.Update)
@@ -107,6 +136,60 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/attachments", []string{"POST", "OPTIONS"}, nil, attachment.Add)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/meta", []string{"GET", "OPTIONS"}, nil, page.GetMeta)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/copy/{targetID}", []string{"POST", "OPTIONS"}, nil, page.Copy)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/lock", []string{"POST", "OPTIONS"}, nil, page.Lock)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/lock", []string{"PUT", "OPTIONS"}, nil, page.RenewLock)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/pages/{pageID}/lock", []string{"DELETE", "OPTIONS"}, nil, page.Unlock)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots", []string{"GET", "OPTIONS"}, nil, snapshot.GetByDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots", []string{"POST", "OPTIONS"}, nil, snapshot.Add)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots/{snapshotID}", []string{"GET", "OPTIONS"}, nil, snapshot.Get)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots/{snapshotID}", []string{"DELETE", "OPTIONS"}, nil, snapshot.Delete)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots/{snapshotID}/diff/{otherID}", []string{"GET", "OPTIONS"}, nil, snapshot.Diff)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/snapshots/{snapshotID}/restore", []string{"POST", "OPTIONS"}, nil, snapshot.Restore)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/approvals", []string{"GET", "OPTIONS"}, nil, action.GetByDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/approvals", []string{"POST", "OPTIONS"}, nil, action.RequestApproval)
+	Add(rt, RoutePrefixPrivate, "actions", []string{"GET", "OPTIONS"}, nil, action.GetPending)
+	Add(rt, RoutePrefixPrivate, "actions/{actionID}", []string{"PUT", "OPTIONS"}, nil, action.Respond)
+	Add(rt, RoutePrefixPrivate, "actions/{actionID}", []string{"DELETE", "OPTIONS"}, nil, action.Cancel)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/feedback", []string{"GET", "OPTIONS"}, nil, feedback.GetByDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/feedback", []string{"POST", "OPTIONS"}, nil, feedback.Add)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/feedback", []string{"GET", "OPTIONS"}, nil, feedback.GetSpaceSummary)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/comments", []string{"GET", "OPTIONS"}, nil, comment.GetByDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/comments", []string{"POST", "OPTIONS"}, nil, comment.Add)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}", []string{"PUT", "OPTIONS"}, nil, comment.Update)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}", []string{"DELETE", "OPTIONS"}, nil, comment.Delete)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}/replies", []string{"POST", "OPTIONS"}, nil, comment.Reply)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}/revisions", []string{"GET", "OPTIONS"}, nil, comment.GetRevisions)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}/resolve", []string{"POST", "OPTIONS"}, nil, comment.Resolve)
+	Add(rt, RoutePrefixPrivate, "comments/{commentID}/reopen", []string{"POST", "OPTIONS"}, nil, comment.Reopen)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/watch", []string{"POST", "OPTIONS"}, nil, watch.WatchDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/watch", []string{"DELETE", "OPTIONS"}, nil, watch.UnwatchDocument)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/watch", []string{"POST", "OPTIONS"}, nil, watch.WatchSpace)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/watch", []string{"DELETE", "OPTIONS"}, nil, watch.UnwatchSpace)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/fields", []string{"GET", "OPTIONS"}, nil, metadata.GetFields)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/fields", []string{"POST", "OPTIONS"}, nil, metadata.AddField)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/fields/{fieldID}", []string{"PUT", "OPTIONS"}, nil, metadata.UpdateField)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/fields/{fieldID}", []string{"DELETE", "OPTIONS"}, nil, metadata.DeleteField)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/tags", []string{"GET", "OPTIONS"}, nil, tag.GetVocabulary)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/tags", []string{"POST", "OPTIONS"}, nil, tag.AddVocabulary)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/tags/{tagID}", []string{"DELETE", "OPTIONS"}, nil, tag.DeleteVocabulary)
+
+	Add(rt, RoutePrefixPrivate, "tags", []string{"GET", "OPTIONS"}, nil, tag.GetUsage)
+	Add(rt, RoutePrefixPrivate, "tags/rename", []string{"POST", "OPTIONS"}, nil, tag.Rename)
+	Add(rt, RoutePrefixPrivate, "tags/merge", []string{"POST", "OPTIONS"}, nil, tag.Merge)
+	Add(rt, RoutePrefixPrivate, "watches", []string{"GET", "OPTIONS"}, nil, watch.GetByUser)
+	Add(rt, RoutePrefixPrivate, "watches/preference", []string{"GET", "OPTIONS"}, nil, watch.GetPreference)
+	Add(rt, RoutePrefixPrivate, "watches/preference", []string{"PUT", "OPTIONS"}, nil, watch.SetPreference)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/move/{folderID}", []string{"POST", "OPTIONS"}, nil, document.Move)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/duplicate/{folderID}", []string{"POST", "OPTIONS"}, nil, document.Duplicate)
+
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/shares", []string{"GET", "OPTIONS"}, nil, share.GetByDocument)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/shares", []string{"POST", "OPTIONS"}, nil, share.Add)
+	Add(rt, RoutePrefixPrivate, "shares/{shareID}", []string{"DELETE", "OPTIONS"}, nil, share.Revoke)
 
 	Add(rt, RoutePrefixPrivate, "organizations/{orgID}", []string{"GET", "OPTIONS"}, nil, organization.Get)
 	Add(rt, RoutePrefixPrivate, "organizations/{orgID}", []string{"PUT", "OPTIONS"}, nil, organization.Update)
@@ -116,6 +199,11 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "folders/{folderID}/permissions", []string{"PUT", "OPTIONS"}, nil, space.SetPermissions)
 	Add(rt, RoutePrefixPrivate, "folders/{folderID}/permissions", []string{"GET", "OPTIONS"}, nil, space.GetPermissions)
 	Add(rt, RoutePrefixPrivate, "folders/{folderID}/invitation", []string{"POST", "OPTIONS"}, nil, space.Invite)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/trash", []string{"GET", "OPTIONS"}, nil, trash.GetBySpace)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/archive", []string{"POST", "OPTIONS"}, nil, document.ArchiveSpace)
+	Add(rt, RoutePrefixPrivate, "folders/{folderID}/move/{moveToId}", []string{"POST", "OPTIONS"}, nil, document.MoveSpace)
+	Add(rt, RoutePrefixPrivate, "trash/{itemID}/restore", []string{"POST", "OPTIONS"}, nil, trash.Restore)
+	Add(rt, RoutePrefixPrivate, "trash/{itemID}", []string{"DELETE", "OPTIONS"}, nil, trash.Purge)
 	Add(rt, RoutePrefixPrivate, "folders", []string{"GET", "OPTIONS"}, []string{"filter", "viewers"}, space.GetSpaceViewers)
 	Add(rt, RoutePrefixPrivate, "folders", []string{"POST", "OPTIONS"}, nil, space.Add)
 	Add(rt, RoutePrefixPrivate, "folders", []string{"GET", "OPTIONS"}, nil, space.GetAll)
@@ -137,6 +225,14 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "templates", []string{"POST", "OPTIONS"}, nil, template.SaveAs)
 	Add(rt, RoutePrefixPrivate, "templates/{templateID}/folder/{folderID}", []string{"POST", "OPTIONS"}, []string{"type", "saved"}, template.Use)
 	Add(rt, RoutePrefixPrivate, "templates/{folderID}", []string{"GET", "OPTIONS"}, nil, template.SavedList)
+	Add(rt, RoutePrefixPrivate, "templates/{templateID}/variables", []string{"GET", "OPTIONS"}, nil, template.GetVariables)
+	Add(rt, RoutePrefixPrivate, "templates/{templateID}/variables", []string{"PUT", "OPTIONS"}, nil, template.SetVariables)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/template", []string{"GET", "OPTIONS"}, nil, template.GetOrigin)
+	Add(rt, RoutePrefixPrivate, "templates/{templateID}/versions", []string{"GET", "OPTIONS"}, nil, template.GetVersions)
+	Add(rt, RoutePrefixPrivate, "templates/{templateID}/versions", []string{"POST", "OPTIONS"}, nil, template.AddVersion)
+	Add(rt, RoutePrefixPrivate, "templates/{templateID}/documents", []string{"GET", "OPTIONS"}, nil, template.GetDerived)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/template/patches", []string{"GET", "OPTIONS"}, nil, template.GetPatches)
+	Add(rt, RoutePrefixPrivate, "documents/{documentID}/template/patches", []string{"POST", "OPTIONS"}, nil, template.ApplyPatches)
 
 	Add(rt, RoutePrefixPrivate, "sections", []string{"GET", "OPTIONS"}, nil, section.GetSections)
 	Add(rt, RoutePrefixPrivate, "sections", []string{"POST", "OPTIONS"}, nil, section.RunSectionCommand)
@@ -148,6 +244,12 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "sections/blocks", []string{"POST", "OPTIONS"}, nil, block.Add)
 	Add(rt, RoutePrefixPrivate, "sections/targets", []string{"GET", "OPTIONS"}, nil, page.GetMoveCopyTargets)
 
+	Add(rt, RoutePrefixPrivate, "datasources", []string{"GET", "OPTIONS"}, nil, datasource.GetAll)
+	Add(rt, RoutePrefixPrivate, "datasources", []string{"POST", "OPTIONS"}, nil, datasource.Add)
+	Add(rt, RoutePrefixPrivate, "datasources/{dataSourceID}", []string{"GET", "OPTIONS"}, nil, datasource.Get)
+	Add(rt, RoutePrefixPrivate, "datasources/{dataSourceID}", []string{"PUT", "OPTIONS"}, nil, datasource.Update)
+	Add(rt, RoutePrefixPrivate, "datasources/{dataSourceID}", []string{"DELETE", "OPTIONS"}, nil, datasource.Delete)
+
 	Add(rt, RoutePrefixPrivate, "links/{folderID}/{documentID}/{pageID}", []string{"GET", "OPTIONS"}, nil, link.GetLinkCandidates)
 	Add(rt, RoutePrefixPrivate, "links", []string{"GET", "OPTIONS"}, nil, link.SearchLinkCandidates)
 	Add(rt, RoutePrefixPrivate, "documents/{documentID}/links", []string{"GET", "OPTIONS"}, nil, document.DocumentLinks)
@@ -158,6 +260,8 @@ func RegisterEndpoints(rt *env.Runtime, s *domain.Store) {
 	Add(rt, RoutePrefixPrivate, "global/license", []string{"PUT", "OPTIONS"}, nil, setting.SetLicense)
 	Add(rt, RoutePrefixPrivate, "global/auth", []string{"GET", "OPTIONS"}, nil, setting.AuthConfig)
 	Add(rt, RoutePrefixPrivate, "global/auth", []string{"PUT", "OPTIONS"}, nil, setting.SetAuthConfig)
+	Add(rt, RoutePrefixPrivate, "global/trash", []string{"GET", "OPTIONS"}, nil, trash.Config)
+	Add(rt, RoutePrefixPrivate, "global/trash", []string{"PUT", "OPTIONS"}, nil, trash.SetConfig)
 
 	Add(rt, RoutePrefixPrivate, "pin/{userID}", []string{"POST", "OPTIONS"}, nil, pin.Add)
 	Add(rt, RoutePrefixPrivate, "pin/{userID}", []string{"GET", "OPTIONS"}, nil, pin.GetUserPins)
@@ -21,6 +21,8 @@ import (
 	"github.com/documize/community/core/database"
 	"github.com/documize/community/core/env"
 	"github.com/documize/community/domain"
+	"github.com/documize/community/domain/trash"
+	"github.com/documize/community/domain/watch"
 	"github.com/documize/community/server/routing"
 	"github.com/documize/community/server/web"
 	"github.com/gorilla/mux"
@@ -48,6 +50,8 @@ func Start(rt *env.Runtime, s *domain.Store, ready chan struct{}) {
 			rt.Log.Error("plugin setup failed", err)
 		}
 		rt.Log.Info("Starting web server")
+		trash.Schedule(rt, s)
+		watch.Schedule(rt, s)
 	}
 
 	// define middleware